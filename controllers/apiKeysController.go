@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"encoding/json"
+	"eventom-backend/dtos"
+	"eventom-backend/services"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type ApiKeysController struct {
+	apiKeysService services.ApiKeysServiceInterface
+	validator      *validator.Validate
+	logger         *utils.Logger
+}
+
+func NewApiKeysController(apiKeysService services.ApiKeysServiceInterface, logger *utils.Logger) *ApiKeysController {
+	return &ApiKeysController{
+		apiKeysService: apiKeysService,
+		validator:      validator.New(),
+		logger:         logger,
+	}
+}
+
+func (akc ApiKeysController) HandleCreateApiKey(w http.ResponseWriter, r *http.Request) {
+	var apiKeyCreate dtos.ApiKeyCreateDto
+	err := json.NewDecoder(r.Body).Decode(&apiKeyCreate)
+
+	if err != nil {
+		akc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = akc.validator.Struct(&apiKeyCreate)
+
+	if err != nil {
+		akc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	createdApiKey, responseErr := akc.apiKeysService.CreateApiKey(userId, &apiKeyCreate)
+
+	if responseErr != nil {
+		akc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	akc.logger.Log(utils.LevelInfo, fmt.Sprintf("Api key with ID %s created for user with ID %s", createdApiKey.ID, userId), nil)
+
+	responseJson, err := json.Marshal(createdApiKey)
+
+	if err != nil {
+		akc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJson)
+}
+
+func (akc ApiKeysController) HandleGetApiKeys(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	apiKeysList, responseErr := akc.apiKeysService.GetApiKeys(userId)
+
+	if responseErr != nil {
+		akc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(&apiKeysList)
+
+	if err != nil {
+		akc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (akc ApiKeysController) HandleRenameApiKey(w http.ResponseWriter, r *http.Request) {
+	var apiKeyUpdate dtos.ApiKeyUpdateDto
+	err := json.NewDecoder(r.Body).Decode(&apiKeyUpdate)
+
+	if err != nil {
+		akc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = akc.validator.Struct(&apiKeyUpdate)
+
+	if err != nil {
+		akc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	apiKey, responseErr := akc.apiKeysService.RenameApiKey(userId, r.PathValue("id"), apiKeyUpdate.Name)
+
+	if responseErr != nil {
+		akc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(apiKey)
+
+	if err != nil {
+		akc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (akc ApiKeysController) HandleRevokeApiKey(w http.ResponseWriter, r *http.Request) {
+	apiKeyId := r.PathValue("id")
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	responseErr := akc.apiKeysService.RevokeApiKey(userId, apiKeyId)
+
+	if responseErr != nil {
+		akc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	akc.logger.Log(utils.LevelInfo, fmt.Sprintf("Api key with ID %s revoked", apiKeyId), nil)
+
+	w.WriteHeader(http.StatusOK)
+}
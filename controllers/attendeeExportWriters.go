@@ -0,0 +1,102 @@
+package controllers
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/services"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// csvAttendeeWriter streams attendees as csv with a header row
+type csvAttendeeWriter struct {
+	w         http.ResponseWriter
+	csvWriter *csv.Writer
+	columns   []string
+	started   bool
+}
+
+func newCsvAttendeeWriter(w http.ResponseWriter, columns []string) *csvAttendeeWriter {
+	return &csvAttendeeWriter{
+		w:         w,
+		csvWriter: csv.NewWriter(w),
+		columns:   columns,
+	}
+}
+
+func (caw *csvAttendeeWriter) Begin(event *models.Event) error {
+	caw.started = true
+	caw.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	caw.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="attendees-%s.csv"`, event.ID))
+	caw.w.WriteHeader(http.StatusOK)
+
+	return caw.csvWriter.Write(caw.columns)
+}
+
+func (caw *csvAttendeeWriter) Write(attendee *dtos.Attendee) error {
+	record := make([]string, len(caw.columns))
+	for i, column := range caw.columns {
+		record[i] = escapeCsvFormula(attendee.ExportValue(column))
+	}
+
+	return caw.csvWriter.Write(record)
+}
+
+func (caw *csvAttendeeWriter) End() error {
+	caw.csvWriter.Flush()
+
+	return caw.csvWriter.Error()
+}
+
+// escapeCsvFormula keeps spreadsheet applications from evaluating user provided values like display names as formulas
+func escapeCsvFormula(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+
+	return value
+}
+
+// jsonLinesAttendeeWriter streams attendees as json lines, one object with the selected columns per line
+type jsonLinesAttendeeWriter struct {
+	w       http.ResponseWriter
+	encoder *json.Encoder
+	columns []string
+	started bool
+}
+
+func newJsonLinesAttendeeWriter(w http.ResponseWriter, columns []string) *jsonLinesAttendeeWriter {
+	return &jsonLinesAttendeeWriter{
+		w:       w,
+		encoder: json.NewEncoder(w),
+		columns: columns,
+	}
+}
+
+func (jlaw *jsonLinesAttendeeWriter) Begin(event *models.Event) error {
+	jlaw.started = true
+	jlaw.w.Header().Set("Content-Type", "application/jsonl; charset=utf-8")
+	jlaw.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="attendees-%s.jsonl"`, event.ID))
+	jlaw.w.WriteHeader(http.StatusOK)
+
+	return nil
+}
+
+func (jlaw *jsonLinesAttendeeWriter) Write(attendee *dtos.Attendee) error {
+	line := make(map[string]string, len(jlaw.columns))
+	for _, column := range jlaw.columns {
+		line[column] = attendee.ExportValue(column)
+	}
+
+	return jlaw.encoder.Encode(line)
+}
+
+func (jlaw *jsonLinesAttendeeWriter) End() error {
+	return nil
+}
+
+var _ services.AttendeeExportWriter = (*csvAttendeeWriter)(nil)
+var _ services.AttendeeExportWriter = (*jsonLinesAttendeeWriter)(nil)
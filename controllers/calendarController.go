@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"encoding/json"
+	"eventom-backend/services"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+const calendarFileSuffix = ".ics"
+
+type CalendarController struct {
+	calendarService services.CalendarServiceInterface
+	logger          *utils.Logger
+}
+
+func NewCalendarController(calendarService services.CalendarServiceInterface, logger *utils.Logger) *CalendarController {
+	return &CalendarController{
+		calendarService: calendarService,
+		logger:          logger,
+	}
+}
+
+// HandleCalendarFile serves requests for the path value name that end with .ics with calendarHandler and all others with next.
+// The suffix is removed from the path value. This is needed because the mux only supports wildcards that fill a whole segment
+func HandleCalendarFile(name string, calendarHandler http.HandlerFunc, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		value, found := strings.CutSuffix(r.PathValue(name), calendarFileSuffix)
+
+		if !found {
+			next(w, r)
+			return
+		}
+
+		r.SetPathValue(name, value)
+		calendarHandler(w, r)
+	}
+}
+
+func (cc CalendarController) HandleGetEventCalendar(w http.ResponseWriter, r *http.Request) {
+	eventId := r.PathValue("id")
+
+	calendar, responseErr := cc.calendarService.GetEventCalendar(eventId)
+
+	if responseErr != nil {
+		cc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	writeCalendar(w, fmt.Sprintf("event-%s.ics", eventId), calendar)
+}
+
+// HandleGetCalendarFeed serves the calendar feed of a user. The route is public, the token in the url is the credential
+func (cc CalendarController) HandleGetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	token := r.PathValue("token")
+
+	calendar, responseErr := cc.calendarService.GetCalendarFeed(token)
+
+	if responseErr != nil {
+		cc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	// the feed is personal, so it must not be stored by shared caches
+	w.Header().Set("Cache-Control", "private, no-cache")
+	writeCalendar(w, "eventom.ics", calendar)
+}
+
+func (cc CalendarController) HandleCreateCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	calendarFeed, responseErr := cc.calendarService.CreateCalendarFeed(userId)
+
+	if responseErr != nil {
+		cc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	cc.logger.Log(utils.LevelInfo, fmt.Sprintf("Calendar feed of user %s created", userId), nil)
+
+	responseJson, err := json.Marshal(calendarFeed)
+
+	if err != nil {
+		cc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJson)
+}
+
+func (cc CalendarController) HandleDeleteCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	responseErr := cc.calendarService.DeleteCalendarFeed(userId)
+
+	if responseErr != nil {
+		cc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	cc.logger.Log(utils.LevelInfo, fmt.Sprintf("Calendar feed of user %s deleted", userId), nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeCalendar(w http.ResponseWriter, fileName string, calendar []byte) {
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", fileName))
+	w.WriteHeader(http.StatusOK)
+	w.Write(calendar)
+}
@@ -12,10 +12,14 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
 
+// defaultRadiusKm is used for searches around a point without radius_km
+const defaultRadiusKm = 25
+
 type EventsController struct {
 	eventsService services.EventsServiceInterface
 	validator     *validator.Validate
@@ -129,6 +133,14 @@ func (ec EventsController) HandleGetAllEvents(w http.ResponseWriter, r *http.Req
 		return
 	}
 
+	eventFacets, responseErr := ec.eventsService.GetEventFacets(eventFilters)
+
+	if responseErr != nil {
+		ec.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
 	eventListMetadata := &dtos.EventListMetadata{
 		CurrentPage:  eventFilters.Page,
 		PageSize:     eventFilters.PageSize,
@@ -139,6 +151,7 @@ func (ec EventsController) HandleGetAllEvents(w http.ResponseWriter, r *http.Req
 	responseData := &dtos.EventListResponse{
 		Events:   eventsList,
 		Metadata: eventListMetadata,
+		Facets:   eventFacets,
 	}
 
 	responseJson, err := json.Marshal(responseData)
@@ -154,6 +167,73 @@ func (ec EventsController) HandleGetAllEvents(w http.ResponseWriter, r *http.Req
 	w.Write(responseJson)
 }
 
+func (ec EventsController) HandleCreateEventSeries(w http.ResponseWriter, r *http.Request) {
+	var seriesDto dtos.CreateEventSeriesDto
+	err := json.NewDecoder(r.Body).Decode(&seriesDto)
+
+	if err != nil {
+		ec.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = ec.validator.Struct(&seriesDto)
+
+	if err != nil {
+		ec.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	createdSeries, responseErr := ec.eventsService.CreateEventSeries(userId, &seriesDto)
+
+	if responseErr != nil {
+		ec.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	ec.logger.Log(utils.LevelInfo, fmt.Sprintf("Event series with ID %s created with %d occurrences", createdSeries.ID, len(createdSeries.Occurrences)), nil)
+
+	responseJson, err := json.Marshal(createdSeries)
+
+	if err != nil {
+		ec.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJson)
+}
+
+func (ec EventsController) HandleGetEventSeries(w http.ResponseWriter, r *http.Request) {
+	seriesId := r.PathValue("id")
+
+	series, responseErr := ec.eventsService.GetEventSeries(seriesId)
+
+	if responseErr != nil {
+		ec.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(series)
+
+	if err != nil {
+		ec.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
 func (ec EventsController) HandleUpdateEvent(w http.ResponseWriter, r *http.Request) {
 	var event models.Event
 	bodyDecorder := json.NewDecoder(r.Body)
@@ -168,8 +248,15 @@ func (ec EventsController) HandleUpdateEvent(w http.ResponseWriter, r *http.Requ
 	}
 
 	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+	manageAny := utils.HasPermission(r.Context(), models.PermissionEventsManageAny)
+	allowOverbooking := r.URL.Query().Get("overbook") == "true"
+	scope := r.URL.Query().Get("scope")
+
+	if scope == "" {
+		scope = models.EventEditScopeSingle
+	}
 
-	updatedEvent, responseErr := ec.eventsService.UpdateEvent(userId, &event)
+	updatedEvent, responseErr := ec.eventsService.UpdateEvent(userId, manageAny, &event, scope, allowOverbooking)
 
 	if responseErr != nil {
 		ec.logger.Log(utils.LevelError, responseErr.Message, nil)
@@ -195,8 +282,11 @@ func (ec EventsController) HandleUpdateEvent(w http.ResponseWriter, r *http.Requ
 func (ec EventsController) HandleDeleteEvent(w http.ResponseWriter, r *http.Request) {
 	eventId := r.PathValue("id")
 	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+	manageAny := utils.HasPermission(r.Context(), models.PermissionEventsManageAny)
+	reason := r.URL.Query().Get("reason")
+	purge := r.URL.Query().Get("purge") == "true"
 
-	responseErr := ec.eventsService.DeleteEvent(userId, eventId)
+	responseErr := ec.eventsService.DeleteEvent(userId, manageAny, eventId, reason, purge)
 
 	if responseErr != nil {
 		ec.logger.Log(utils.LevelError, responseErr.Message, nil)
@@ -235,9 +325,13 @@ func setEventFilters(r *http.Request) (*dtos.EventFilterDto, error) {
 	var eventFilters dtos.EventFilterDto
 	pageParam := r.URL.Query().Get("page")
 	pageSizeParam := r.URL.Query().Get("page_size")
-	eventNameParam := r.URL.Query().Get("name")
+	searchParam := r.URL.Query().Get("search")
 	locationParam := r.URL.Query().Get("location")
 	freeCapacityParam := r.URL.Query().Get("capacity")
+	fromParam := r.URL.Query().Get("from")
+	toParam := r.URL.Query().Get("to")
+	nearParam := r.URL.Query().Get("near")
+	radiusParam := r.URL.Query().Get("radius_km")
 	sortColumnParam := r.URL.Query().Get("column")
 	sortOrderParam := r.URL.Query().Get("order")
 
@@ -271,17 +365,87 @@ func setEventFilters(r *http.Request) (*dtos.EventFilterDto, error) {
 		}
 	}
 
+	if !strings.EqualFold(fromParam, "") {
+		from, err := time.Parse(time.RFC3339, fromParam)
+		if err != nil {
+			return nil, errors.New("from must be a RFC 3339 timestamp")
+		}
+		eventFilters.From = &from
+	}
+
+	if !strings.EqualFold(toParam, "") {
+		to, err := time.Parse(time.RFC3339, toParam)
+		if err != nil {
+			return nil, errors.New("to must be a RFC 3339 timestamp")
+		}
+		eventFilters.To = &to
+	}
+
+	if eventFilters.From != nil && eventFilters.To != nil && !eventFilters.To.After(*eventFilters.From) {
+		return nil, errors.New("to must be after from")
+	}
+
+	// name is the former parameter of the search and is still accepted
+	if strings.EqualFold(searchParam, "") {
+		searchParam = r.URL.Query().Get("name")
+	}
+
+	// search results are sorted by relevance unless another column is requested
+	if !strings.EqualFold(strings.TrimSpace(searchParam), "") && strings.EqualFold(sortColumnParam, "") {
+		sortColumnParam = "relevance"
+	}
+
+	if sortColumnParam == "relevance" && strings.EqualFold(strings.TrimSpace(searchParam), "") {
+		return nil, errors.New("sorting by relevance requires search")
+	}
+
+	if !strings.EqualFold(nearParam, "") {
+		latitudeParam, longitudeParam, found := strings.Cut(nearParam, ",")
+		latitude, latitudeErr := strconv.ParseFloat(strings.TrimSpace(latitudeParam), 64)
+		longitude, longitudeErr := strconv.ParseFloat(strings.TrimSpace(longitudeParam), 64)
+		if !found || latitudeErr != nil || longitudeErr != nil {
+			return nil, errors.New("near must be a latitude and a longitude separated by a comma")
+		}
+		eventFilters.NearLatitude = &latitude
+		eventFilters.NearLongitude = &longitude
+
+		eventFilters.RadiusKm = defaultRadiusKm
+		if !strings.EqualFold(radiusParam, "") {
+			radius, err := strconv.ParseFloat(radiusParam, 64)
+			if err != nil {
+				return nil, errors.New("radius_km must be a number")
+			}
+			eventFilters.RadiusKm = radius
+		}
+
+		// events around a point are sorted by distance unless another column is requested
+		if strings.EqualFold(sortColumnParam, "") {
+			sortColumnParam = "distance"
+		}
+	}
+
+	if sortColumnParam == "distance" && eventFilters.NearLatitude == nil {
+		return nil, errors.New("sorting by distance requires near")
+	}
+
 	if strings.EqualFold(sortColumnParam, "") {
 		sortColumnParam = "id"
 	}
 
+	// the most relevant results come first
+	if strings.EqualFold(sortOrderParam, "") && sortColumnParam == "relevance" {
+		sortOrderParam = "DESC"
+	}
+
 	if strings.EqualFold(sortOrderParam, "") {
 		sortOrderParam = "ASC"
 	}
 
-	eventFilters.Name = eventNameParam
+	eventFilters.Search = searchParam
 	eventFilters.Location = locationParam
 	eventFilters.FreeCapacity = freeCapacity
+	eventFilters.Categories = queryValues(r, "category")
+	eventFilters.Tags = queryValues(r, "tag")
 	eventFilters.SortColumn = sortColumnParam
 	eventFilters.SortOrder = sortOrderParam
 	eventFilters.Page = page
@@ -289,3 +453,18 @@ func setEventFilters(r *http.Request) (*dtos.EventFilterDto, error) {
 
 	return &eventFilters, nil
 }
+
+// queryValues returns the values of a query parameter that can be repeated or contain a comma separated list, e.g. ?tag=go,rust&tag=web
+func queryValues(r *http.Request, name string) []string {
+	values := make([]string, 0)
+
+	for _, param := range r.URL.Query()[name] {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+
+	return values
+}
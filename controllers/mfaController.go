@@ -0,0 +1,143 @@
+package controllers
+
+import (
+	"encoding/json"
+	"eventom-backend/dtos"
+	"eventom-backend/services"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type MfaController struct {
+	mfaService services.MfaServiceInterface
+	validator  *validator.Validate
+	logger     *utils.Logger
+}
+
+func NewMfaController(mfaService services.MfaServiceInterface, logger *utils.Logger) *MfaController {
+	return &MfaController{
+		mfaService: mfaService,
+		validator:  validator.New(),
+		logger:     logger,
+	}
+}
+
+func (mc MfaController) HandleEnrollTotp(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	if !ok {
+		mc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	enrollment, responseErr := mc.mfaService.EnrollTotp(userId)
+
+	if responseErr != nil {
+		mc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(enrollment)
+
+	if err != nil {
+		mc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (mc MfaController) HandleConfirmTotp(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	if !ok {
+		mc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	mfaCode, err := mc.parseMfaCode(r)
+
+	if err != nil {
+		mc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recoveryCodes, responseErr := mc.mfaService.ConfirmTotp(userId, mfaCode.Code)
+
+	if responseErr != nil {
+		mc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	mc.logger.Log(utils.LevelInfo, fmt.Sprintf("User with ID %s enabled two-factor authentication", userId), nil)
+
+	responseJson, err := json.Marshal(&dtos.RecoveryCodes{RecoveryCodes: recoveryCodes})
+
+	if err != nil {
+		mc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (mc MfaController) HandleDisableTotp(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	if !ok {
+		mc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	mfaCode, err := mc.parseMfaCode(r)
+
+	if err != nil {
+		mc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseErr := mc.mfaService.DisableTotp(userId, mfaCode.Code)
+
+	if responseErr != nil {
+		mc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	mc.logger.Log(utils.LevelInfo, fmt.Sprintf("User with ID %s disabled two-factor authentication", userId), nil)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (mc MfaController) parseMfaCode(r *http.Request) (*dtos.MfaCodeDto, error) {
+	var mfaCode dtos.MfaCodeDto
+	err := json.NewDecoder(r.Body).Decode(&mfaCode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = mc.validator.Struct(&mfaCode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &mfaCode, nil
+}
@@ -0,0 +1,95 @@
+package controllers
+
+import (
+	"encoding/json"
+	"eventom-backend/services"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+)
+
+type OidcController struct {
+	oidcService services.OidcServiceInterface
+	logger      *utils.Logger
+}
+
+func NewOidcController(oidcService services.OidcServiceInterface, logger *utils.Logger) *OidcController {
+	return &OidcController{
+		oidcService: oidcService,
+		logger:      logger,
+	}
+}
+
+func (oc OidcController) HandleOidcLogin(w http.ResponseWriter, r *http.Request) {
+	authUrl, state, responseErr := oc.oidcService.StartLogin()
+
+	if responseErr != nil {
+		oc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	utils.SetOidcStateCookie(w, state)
+
+	http.Redirect(w, r, authUrl, http.StatusFound)
+}
+
+func (oc OidcController) HandleOidcCallback(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	// the identity provider reports failed or cancelled logins with an error code
+	if providerErr := query.Get("error"); providerErr != "" {
+		message := fmt.Sprintf("Login at identity provider failed: %s %s", providerErr, query.Get("error_description"))
+		oc.logger.Log(utils.LevelError, message, nil)
+		http.Error(w, message, http.StatusUnauthorized)
+		return
+	}
+
+	state := query.Get("state")
+	code := query.Get("code")
+
+	if state == "" || code == "" {
+		oc.logger.Log(utils.LevelError, "State or code missing", nil)
+		http.Error(w, "State or code missing", http.StatusBadRequest)
+		return
+	}
+
+	// the login has to be completed by the browser that started it, see utils.OidcStateCookieName
+	if !utils.VerifyOidcStateCookie(r, state) {
+		oc.logger.Log(utils.LevelError, "State does not match the login of this browser", nil)
+		http.Error(w, "State does not match the login of this browser", http.StatusUnauthorized)
+		return
+	}
+
+	utils.ClearOidcStateCookie(w)
+
+	authTokens, mfaChallenge, responseErr := oc.oidcService.CompleteLogin(state, code, utils.GetClientInfo(r))
+
+	if responseErr != nil {
+		oc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	// the cookies are only set after the second factor has been verified via POST /login/mfa
+	if mfaChallenge != nil {
+		responseJson, err := json.Marshal(mfaChallenge)
+
+		if err != nil {
+			oc.logger.Log(utils.LevelFatal, err.Error(), nil)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(responseJson)
+		return
+	}
+
+	oc.logger.Log(utils.LevelInfo, "User logged in with openid connect", nil)
+
+	utils.SetAuthCookies(w, authTokens)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,45 @@
+package controllers
+
+import (
+	"html/template"
+	"net/http"
+)
+
+// passwordResetPage is opened with the link from the password reset mail. It posts the token from the link together with the new
+// password to POST /password/reset as form
+var passwordResetPage = template.Must(template.New("passwordReset").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Reset your eventom password</title>
+</head>
+<body>
+<h1>Reset your eventom password</h1>
+{{if .Done}}<p>Your password has been changed, you can log in with the new password now.</p>
+{{else}}{{if .Error}}<p role="alert">{{.Error}}</p>
+{{end}}<form method="post" action="/password/reset">
+<input type="hidden" name="token" value="{{.Token}}">
+<label for="password">New password</label>
+<input type="password" id="password" name="password" autocomplete="new-password" required>
+<button type="submit">Change password</button>
+</form>
+{{end}}</body>
+</html>
+`))
+
+type passwordResetPageData struct {
+	Token string
+	Error string
+	Done  bool
+}
+
+// writePasswordResetPage renders the page. The token is part of the url, so it must neither be cached nor sent to other sites
+func writePasswordResetPage(w http.ResponseWriter, status int, data passwordResetPageData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Referrer-Policy", "no-referrer")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; form-action 'self'; frame-ancestors 'none'")
+	w.WriteHeader(status)
+	passwordResetPage.Execute(w, data)
+}
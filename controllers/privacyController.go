@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/services"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type PrivacyController struct {
+	privacyService services.PrivacyServiceInterface
+	validator      *validator.Validate
+	logger         *utils.Logger
+}
+
+func NewPrivacyController(privacyService services.PrivacyServiceInterface, logger *utils.Logger) *PrivacyController {
+	return &PrivacyController{
+		privacyService: privacyService,
+		validator:      validator.New(),
+		logger:         logger,
+	}
+}
+
+func (pc PrivacyController) HandleExportMe(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	pc.writeExport(w, userId)
+}
+
+func (pc PrivacyController) HandleExportUser(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+
+	pc.writeExport(w, userId)
+}
+
+func (pc PrivacyController) HandleRequestErasureMe(w http.ResponseWriter, r *http.Request) {
+	var passwordConfirmation dtos.PasswordConfirmationDto
+	err := json.NewDecoder(r.Body).Decode(&passwordConfirmation)
+
+	if err != nil {
+		pc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = pc.validator.Struct(&passwordConfirmation)
+
+	if err != nil {
+		pc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+	claims := r.Context().Value(utils.ContextClaimsKey).(*dtos.AccessTokenClaims)
+
+	erasureRequest, responseErr := pc.privacyService.RequestOwnErasure(userId, claims.SessionId, passwordConfirmation.Password)
+
+	if responseErr != nil {
+		pc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	pc.logger.Log(utils.LevelInfo, fmt.Sprintf("Erasure of user with ID %s requested", userId), nil)
+
+	utils.ClearAuthCookies(w)
+
+	pc.writeErasureRequest(w, erasureRequest)
+}
+
+func (pc PrivacyController) HandleRequestErasureUser(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+	requestedBy := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	erasureRequest, responseErr := pc.privacyService.RequestErasure(userId, requestedBy)
+
+	if responseErr != nil {
+		pc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	pc.logger.Log(utils.LevelInfo, fmt.Sprintf("Erasure of user with ID %s requested by user with ID %s", userId, requestedBy), nil)
+
+	pc.writeErasureRequest(w, erasureRequest)
+}
+
+// unexportedUserTables are linked to a user but only hold secrets and revocations of tokens, they mean nothing outside of the app
+var unexportedUserTables = []string{
+	"refresh_tokens",
+	"revoked_tokens",
+	"user_token_revocations",
+	"email_verification_tokens",
+	"password_reset_tokens",
+	"account_unlock_tokens",
+	"user_totp",
+	"totp_recovery_codes",
+}
+
+// exportArchiveFile is a json file of the export archive together with the tables its content is read from
+type exportArchiveFile struct {
+	name    string
+	tables  []string
+	content any
+}
+
+// exportArchiveFiles splits the data of the user into the files of the export archive. Every table that is linked to a user
+// has to be listed by one of the files or in unexportedUserTables
+func exportArchiveFiles(userDataExport *dtos.UserDataExport) []exportArchiveFile {
+	account := map[string]any{
+		"exported_at":      userDataExport.ExportedAt,
+		"account":          userDataExport.Account,
+		"roles":            userDataExport.Roles,
+		"api_keys":         userDataExport.ApiKeys,
+		"identities":       userDataExport.Identities,
+		"calendar_feed":    userDataExport.CalendarFeed,
+		"erasure_requests": userDataExport.ErasureRequests,
+	}
+
+	return []exportArchiveFile{
+		{"account.json", []string{"users", "user_roles", "api_keys", "user_identities", "calendar_feed_tokens", "erasure_requests"}, account},
+		{"sessions.json", []string{"sessions"}, userDataExport.Sessions},
+		{"events.json", []string{"events"}, userDataExport.Events},
+		{"event_series.json", []string{"event_series"}, userDataExport.EventSeries},
+		{"venues.json", []string{"venues"}, userDataExport.Venues},
+		{"registrations.json", []string{"registrations"}, userDataExport.Registrations},
+		{"cancelled_registrations.json", []string{"cancelled_registrations"}, userDataExport.CancelledRegistrations},
+		{"waitlist_entries.json", []string{"waitlist_entries"}, userDataExport.WaitlistEntries},
+	}
+}
+
+// writeExport sends the data of the user as zip archive with one json file per part, see exportArchiveFiles
+func (pc PrivacyController) writeExport(w http.ResponseWriter, userId string) {
+	userDataExport, responseErr := pc.privacyService.ExportUserData(userId)
+
+	if responseErr != nil {
+		pc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	archiveFiles := exportArchiveFiles(userDataExport)
+
+	// the archive is built in memory, so errors can still be answered with a proper status code
+	var buffer bytes.Buffer
+	archive := zip.NewWriter(&buffer)
+
+	for _, archiveFile := range archiveFiles {
+		fileWriter, err := archive.Create(archiveFile.name)
+
+		if err != nil {
+			pc.logger.Log(utils.LevelError, err.Error(), nil)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		encoder := json.NewEncoder(fileWriter)
+		encoder.SetIndent("", "  ")
+
+		err = encoder.Encode(archiveFile.content)
+
+		if err != nil {
+			pc.logger.Log(utils.LevelError, err.Error(), nil)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	err := archive.Close()
+
+	if err != nil {
+		pc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	pc.logger.Log(utils.LevelInfo, fmt.Sprintf("Data of user with ID %s exported", userId), nil)
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="eventom-export-%s.zip"`, userId))
+	w.WriteHeader(http.StatusOK)
+	w.Write(buffer.Bytes())
+}
+
+func (pc PrivacyController) writeErasureRequest(w http.ResponseWriter, erasureRequest *models.ErasureRequest) {
+	responseJson, err := json.Marshal(erasureRequest)
+
+	if err != nil {
+		pc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(responseJson)
+}
@@ -0,0 +1,274 @@
+package controllers
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"eventom-backend/dtos"
+	"eventom-backend/mailers"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/services"
+	"eventom-backend/testutils"
+	"eventom-backend/utils"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type PrivacyControllerTestSuite struct {
+	suite.Suite
+	ctx                          context.Context
+	router                       *http.ServeMux
+	usersRepository              *repositories.UsersRepository
+	eventsRepository             *repositories.EventsRepository
+	registrationsRepository      *repositories.RegistrationsRepository
+	sessionsRepository           *repositories.SessionsRepository
+	userIdentitiesRepository     *repositories.UserIdentitiesRepository
+	waitlistRepository           *repositories.WaitlistRepository
+	calendarFeedTokensRepository *repositories.CalendarFeedTokensRepository
+	eventSeriesRepository        *repositories.EventSeriesRepository
+	venuesRepository             *repositories.VenuesRepository
+	erasureRequestsRepository    *repositories.ErasureRequestsRepository
+	transactionHandler           *repositories.TransactionHandler
+}
+
+func TestPrivacyControllerSuite(t *testing.T) {
+	suite.Run(t, &PrivacyControllerTestSuite{})
+}
+
+func (suite *PrivacyControllerTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	logger := utils.NewLogger(os.Stdout)
+
+	suite.usersRepository = repositories.NewUsersRepository(testutils.TestContainer.DB)
+	suite.eventsRepository = repositories.NewEventsRepository(testutils.TestContainer.DB)
+	suite.registrationsRepository = repositories.NewRegistrationsRepository(testutils.TestContainer.DB)
+	suite.sessionsRepository = repositories.NewSessionsRepository(testutils.TestContainer.DB)
+	suite.userIdentitiesRepository = repositories.NewUserIdentitiesRepository(testutils.TestContainer.DB)
+	suite.waitlistRepository = repositories.NewWaitlistRepository(testutils.TestContainer.DB)
+	suite.calendarFeedTokensRepository = repositories.NewCalendarFeedTokensRepository(testutils.TestContainer.DB)
+	suite.eventSeriesRepository = repositories.NewEventSeriesRepository(testutils.TestContainer.DB)
+	suite.venuesRepository = repositories.NewVenuesRepository(testutils.TestContainer.DB)
+	suite.erasureRequestsRepository = repositories.NewErasureRequestsRepository(testutils.TestContainer.DB)
+	suite.transactionHandler = repositories.NewTxHandler(testutils.TestContainer.DB)
+	refreshTokensRepository := repositories.NewRefreshTokensRepository(testutils.TestContainer.DB)
+	revokedTokensRepository := repositories.NewRevokedTokensRepository(testutils.TestContainer.DB)
+	rolesRepository := repositories.NewRolesRepository(testutils.TestContainer.DB)
+	apiKeysRepository := repositories.NewApiKeysRepository(testutils.TestContainer.DB)
+	mfaRepository := repositories.NewMfaRepository(testutils.TestContainer.DB)
+	tokensService := services.NewTokensService(refreshTokensRepository, revokedTokensRepository, suite.sessionsRepository, rolesRepository, *suite.transactionHandler)
+	mfaService := services.NewMfaService(mfaRepository, suite.usersRepository, *suite.transactionHandler)
+	registrationNotifier := services.NewMailRegistrationNotifier(suite.usersRepository, suite.eventsRepository, mailers.NewInMemoryMailer(), logger)
+	privacyService := services.NewPrivacyService(
+		suite.usersRepository,
+		rolesRepository,
+		apiKeysRepository,
+		suite.eventsRepository,
+		suite.registrationsRepository,
+		suite.erasureRequestsRepository,
+		suite.sessionsRepository,
+		suite.userIdentitiesRepository,
+		suite.waitlistRepository,
+		suite.calendarFeedTokensRepository,
+		suite.eventSeriesRepository,
+		suite.venuesRepository,
+		mfaService,
+		tokensService,
+		*suite.transactionHandler,
+		registrationNotifier,
+	)
+	privacyController := NewPrivacyController(privacyService, logger)
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /users/{id}/export", privacyController.HandleExportUser)
+
+	suite.router = router
+}
+
+func (suite *PrivacyControllerTestSuite) BeforeTest(suiteName, testName string) {
+	// registrations block deleting their users, events of deleted users are kept as tombstones
+	queries := []string{
+		`DELETE FROM registrations`,
+		`DELETE FROM events`,
+		`DELETE FROM venues`,
+		`DELETE FROM users`,
+	}
+
+	for _, query := range queries {
+		_, err := testutils.TestContainer.DB.Exec(query)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func (suite *PrivacyControllerTestSuite) TestExportContainsAllUserTables() {
+	organizer := suite.createUser("organizer@test.com")
+	user := suite.createUser("user@test.com")
+
+	fullEvent := suite.createEvent("Full event", organizer.ID, 1)
+	_, err := suite.transactionHandler.ExecTx(fullEvent.ID, organizer.ID)
+	require.Nil(suite.T(), err)
+	openEvent := suite.createEvent("Open event", organizer.ID, 10)
+	cancelledEvent := suite.createEvent("Cancelled event", organizer.ID, 10)
+	_ = suite.createEvent("Own event", user.ID, 10)
+
+	// fill every table that is linked to the user
+	_, err = suite.transactionHandler.ExecTx(openEvent.ID, user.ID)
+	require.Nil(suite.T(), err)
+	_, err = suite.waitlistRepository.QueryCreateWaitlistEntry(fullEvent.ID, user.ID)
+	require.Nil(suite.T(), err)
+	_, err = suite.registrationsRepository.QueryCreateCancelledRegistration(&models.Registration{
+		ID:      uuid.NewString(),
+		EventId: cancelledEvent.ID,
+		UserId:  user.ID,
+	}, models.CancellationReasonEventDeleted)
+	require.Nil(suite.T(), err)
+	err = suite.sessionsRepository.QueryCreateSession(&models.Session{
+		ID:        uuid.NewString(),
+		UserId:    user.ID,
+		UserAgent: "Test agent",
+		IpAddress: "192.0.2.1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	require.Nil(suite.T(), err)
+	err = suite.userIdentitiesRepository.QueryCreateUserIdentity(user.ID, "https://issuer.test", "subject", "user@test.com")
+	require.Nil(suite.T(), err)
+	err = suite.calendarFeedTokensRepository.QuerySaveCalendarFeedToken(user.ID, "token-hash")
+	require.Nil(suite.T(), err)
+	_, err = suite.eventSeriesRepository.QueryCreateEventSeries(&models.EventSeries{
+		UserId:         user.ID,
+		RecurrenceRule: "FREQ=WEEKLY;COUNT=2",
+		StartDate:      time.Now(),
+		Timezone:       "UTC",
+	})
+	require.Nil(suite.T(), err)
+	_, err = suite.venuesRepository.QueryCreateVenue(&models.Venue{
+		Name:      "Test venue",
+		Address:   "Test street 1",
+		Latitude:  50.94,
+		Longitude: 6.96,
+		UserId:    &user.ID,
+	})
+	require.Nil(suite.T(), err)
+	_, err = suite.erasureRequestsRepository.QueryCreateErasureRequest(user.ID, organizer.ID)
+	require.Nil(suite.T(), err)
+
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest("GET", "/users/"+user.ID+"/export", nil))
+	require.Equal(suite.T(), 200, recorder.Result().StatusCode)
+
+	archive, zipErr := zip.NewReader(bytes.NewReader(recorder.Body.Bytes()), int64(recorder.Body.Len()))
+	require.NoError(suite.T(), zipErr)
+
+	archiveContents := make(map[string][]byte)
+	for _, archiveFile := range archive.File {
+		fileReader, err := archiveFile.Open()
+		require.NoError(suite.T(), err)
+		content, err := io.ReadAll(fileReader)
+		require.NoError(suite.T(), err)
+		archiveContents[archiveFile.Name] = content
+	}
+
+	// every table with a user_id column has to end up in a file of the archive unless it only holds secrets
+	rows, queryErr := testutils.TestContainer.DB.Query(`
+		SELECT DISTINCT
+			table_name
+		FROM
+			information_schema.columns
+		WHERE
+			table_schema = 'public'
+			AND
+			column_name = 'user_id'`)
+	require.NoError(suite.T(), queryErr)
+	defer rows.Close()
+
+	archiveFiles := exportArchiveFiles(&dtos.UserDataExport{})
+	userTables := make([]string, 0)
+
+	for rows.Next() {
+		var table string
+		require.NoError(suite.T(), rows.Scan(&table))
+		userTables = append(userTables, table)
+	}
+	require.NoError(suite.T(), rows.Err())
+	require.NotEmpty(suite.T(), userTables)
+
+	for _, table := range userTables {
+		if slices.Contains(unexportedUserTables, table) {
+			continue
+		}
+
+		index := slices.IndexFunc(archiveFiles, func(archiveFile exportArchiveFile) bool {
+			return slices.Contains(archiveFile.tables, table)
+		})
+		if assert.NotEqual(suite.T(), -1, index, "table %s is not exported", table) {
+			assert.Contains(suite.T(), archiveContents, archiveFiles[index].name, "table %s is not exported", table)
+		}
+	}
+
+	// every file holds the rows of the user
+	for _, name := range []string{"sessions.json", "events.json", "event_series.json", "venues.json", "registrations.json", "cancelled_registrations.json", "waitlist_entries.json"} {
+		var entries []map[string]any
+		require.NoError(suite.T(), json.Unmarshal(archiveContents[name], &entries), name)
+		assert.Len(suite.T(), entries, 1, name)
+	}
+
+	var account map[string]any
+	require.NoError(suite.T(), json.Unmarshal(archiveContents["account.json"], &account))
+	assert.Len(suite.T(), account["identities"], 1)
+	assert.Len(suite.T(), account["erasure_requests"], 1)
+	assert.NotNil(suite.T(), account["calendar_feed"])
+	assert.Contains(suite.T(), string(archiveContents["sessions.json"]), "192.0.2.1")
+	assert.NotContains(suite.T(), string(archiveContents["account.json"]), "token-hash")
+}
+
+func (suite *PrivacyControllerTestSuite) createUser(email string) *models.User {
+	err := suite.usersRepository.QuerySignupUser(email, "Test123")
+	require.Nil(suite.T(), err)
+
+	user, err := suite.usersRepository.QueryGetUser(email)
+	require.Nil(suite.T(), err)
+
+	return user
+}
+
+func (suite *PrivacyControllerTestSuite) createEvent(name string, userId string, maxCapacity int) *models.Event {
+	event, err := suite.eventsRepository.QueryCreateEvent(&models.Event{
+		Name:        name,
+		Location:    "Köln",
+		StartsAt:    time.Now().Add(24 * time.Hour),
+		EndsAt:      time.Now().Add(26 * time.Hour),
+		MaxCapacity: maxCapacity,
+		UserId:      userId,
+	})
+	require.Nil(suite.T(), err)
+
+	return event
+}
@@ -2,11 +2,17 @@ package controllers
 
 import (
 	"encoding/json"
+	"errors"
+	"eventom-backend/dtos"
 	"eventom-backend/models"
 	"eventom-backend/services"
 	"eventom-backend/utils"
 	"fmt"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -75,6 +81,23 @@ func (rc RegistrationsController) HandleRegisterUserForEvent(w http.ResponseWrit
 }
 
 func (rc RegistrationsController) HandleCancleRegistration(w http.ResponseWriter, r *http.Request) {
+	registrationId := r.PathValue("id")
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	if !ok {
+		rc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	manageAny := utils.HasPermission(r.Context(), models.PermissionRegistrationsManageAny)
+
+	cancelledRegistration, responseErr := rc.registrationsService.CancelRegistration(userId, manageAny, registrationId)
+
+	rc.writeCancelledRegistration(w, cancelledRegistration, responseErr)
+}
+
+func (rc RegistrationsController) HandleCancelEventRegistration(w http.ResponseWriter, r *http.Request) {
 	eventId := r.PathValue("id")
 	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
 
@@ -84,8 +107,20 @@ func (rc RegistrationsController) HandleCancleRegistration(w http.ResponseWriter
 		return
 	}
 
-	cancelledRegistration, responseErr := rc.registrationsService.CancelRegistration(eventId, userId)
+	// admins may cancel registrations of other users by providing their user id
+	attendeeId := userId
+	if targetUserId := r.URL.Query().Get("user_id"); targetUserId != "" {
+		attendeeId = targetUserId
+	}
+
+	manageAny := utils.HasPermission(r.Context(), models.PermissionRegistrationsManageAny)
+
+	cancelledRegistration, responseErr := rc.registrationsService.CancelEventRegistration(userId, manageAny, eventId, attendeeId)
+
+	rc.writeCancelledRegistration(w, cancelledRegistration, responseErr)
+}
 
+func (rc RegistrationsController) writeCancelledRegistration(w http.ResponseWriter, cancelledRegistration *models.Registration, responseErr *models.ResponseError) {
 	if responseErr != nil {
 		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
 		http.Error(w, responseErr.Message, responseErr.Status)
@@ -94,7 +129,17 @@ func (rc RegistrationsController) HandleCancleRegistration(w http.ResponseWriter
 
 	rc.logger.Log(utils.LevelInfo, fmt.Sprintf("Registration with ID %s cancelled", cancelledRegistration.ID), nil)
 
+	responseJson, err := json.Marshal(cancelledRegistration)
+
+	if err != nil {
+		rc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
 }
 
 func (rc RegistrationsController) HandleGetAllRegistrations(w http.ResponseWriter, r *http.Request) {
@@ -118,3 +163,372 @@ func (rc RegistrationsController) HandleGetAllRegistrations(w http.ResponseWrite
 	w.WriteHeader(http.StatusOK)
 	w.Write(responseJson)
 }
+
+func (rc RegistrationsController) HandleGetMyRegistrations(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+	if !ok {
+		rc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	filters, err := setUserRegistrationFilters(r)
+
+	if err == nil {
+		err = rc.validator.Struct(filters)
+	}
+
+	if err != nil {
+		rc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	registrationsList, totalCount, responseErr := rc.registrationsService.GetUserRegistrations(userId, filters)
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(&dtos.RegistrationListResponse{
+		Registrations: registrationsList,
+		Metadata:      buildListMetadata(filters.Page, filters.PageSize, totalCount),
+	})
+
+	if err != nil {
+		rc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (rc RegistrationsController) HandleGetEventAttendees(w http.ResponseWriter, r *http.Request) {
+	eventId := r.PathValue("id")
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	if !ok {
+		rc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	filters, err := setAttendeeFilters(r)
+
+	if err == nil {
+		err = rc.validator.Struct(filters)
+	}
+
+	if err != nil {
+		rc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	manageAny := utils.HasPermission(r.Context(), models.PermissionEventsManageAny)
+
+	attendeesList, totalCount, responseErr := rc.registrationsService.GetEventAttendees(userId, manageAny, eventId, filters)
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(&dtos.AttendeeListResponse{
+		Attendees: attendeesList,
+		Metadata:  buildListMetadata(filters.Page, filters.PageSize, totalCount),
+	})
+
+	if err != nil {
+		rc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (rc RegistrationsController) HandleExportAttendeesCsv(w http.ResponseWriter, r *http.Request) {
+	columns, err := parseAttendeeExportColumns(r)
+
+	if err != nil {
+		rc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer := newCsvAttendeeWriter(w, columns)
+	responseErr := rc.exportAttendees(r, writer)
+
+	if responseErr != nil && !writer.started {
+		http.Error(w, responseErr.Message, responseErr.Status)
+	}
+}
+
+func (rc RegistrationsController) HandleExportAttendeesJsonLines(w http.ResponseWriter, r *http.Request) {
+	columns, err := parseAttendeeExportColumns(r)
+
+	if err != nil {
+		rc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	writer := newJsonLinesAttendeeWriter(w, columns)
+	responseErr := rc.exportAttendees(r, writer)
+
+	if responseErr != nil && !writer.started {
+		http.Error(w, responseErr.Message, responseErr.Status)
+	}
+}
+
+// exportAttendees streams the attendees into the writer. Once the writer has started the response, errors can only be logged
+func (rc RegistrationsController) exportAttendees(r *http.Request, writer services.AttendeeExportWriter) *models.ResponseError {
+	eventId := r.PathValue("id")
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	if !ok {
+		rc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		return &models.ResponseError{
+			Message: "Could not convert user id from token to a string",
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	manageAny := utils.HasPermission(r.Context(), models.PermissionEventsManageAny)
+
+	responseErr := rc.registrationsService.ExportEventAttendees(userId, manageAny, eventId, writer)
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		return responseErr
+	}
+
+	rc.logger.Log(utils.LevelInfo, fmt.Sprintf("Attendees of event with ID %s exported", eventId), nil)
+
+	return nil
+}
+
+func (rc RegistrationsController) HandleJoinWaitlist(w http.ResponseWriter, r *http.Request) {
+	var waitlistEntry models.WaitlistEntry
+	err := json.NewDecoder(r.Body).Decode(&waitlistEntry)
+
+	if err != nil {
+		rc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+	if !ok {
+		rc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	err = rc.validator.Struct(&waitlistEntry)
+
+	if err != nil {
+		rc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	createdEntry, responseErr := rc.registrationsService.JoinWaitlist(waitlistEntry.EventId, userId)
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	rc.logger.Log(utils.LevelInfo, fmt.Sprintf("User %s joined the waitlist of event %s at position %d", userId, createdEntry.EventId, createdEntry.Position), nil)
+
+	responseJson, err := json.Marshal(&createdEntry)
+
+	if err != nil {
+		rc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJson)
+}
+
+func (rc RegistrationsController) HandleGetWaitlistEntries(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+	if !ok {
+		rc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	entriesList, responseErr := rc.registrationsService.GetWaitlistEntries(userId)
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(&entriesList)
+
+	if err != nil {
+		rc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (rc RegistrationsController) HandleLeaveWaitlist(w http.ResponseWriter, r *http.Request) {
+	eventId := r.PathValue("id")
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	if !ok {
+		rc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	responseErr := rc.registrationsService.LeaveWaitlist(eventId, userId)
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	rc.logger.Log(utils.LevelInfo, fmt.Sprintf("User %s left the waitlist of event %s", userId, eventId), nil)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func setUserRegistrationFilters(r *http.Request) (*dtos.UserRegistrationFilterDto, error) {
+	var filters dtos.UserRegistrationFilterDto
+	page, pageSize, err := parsePagination(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	upcoming := false
+	if upcomingParam := r.URL.Query().Get("upcoming"); upcomingParam != "" {
+		upcoming, err = strconv.ParseBool(upcomingParam)
+		if err != nil {
+			return nil, errors.New("upcoming must be true or false")
+		}
+	}
+
+	sortColumnParam := r.URL.Query().Get("column")
+	if sortColumnParam == "" {
+		sortColumnParam = "event_start"
+	}
+
+	sortOrderParam := r.URL.Query().Get("order")
+	if sortOrderParam == "" {
+		sortOrderParam = "ASC"
+	}
+
+	filters.Page = page
+	filters.PageSize = pageSize
+	filters.Name = r.URL.Query().Get("name")
+	filters.Location = r.URL.Query().Get("location")
+	filters.Upcoming = upcoming
+	filters.SortColumn = sortColumnParam
+	filters.SortOrder = sortOrderParam
+
+	return &filters, nil
+}
+
+func setAttendeeFilters(r *http.Request) (*dtos.AttendeeFilterDto, error) {
+	var filters dtos.AttendeeFilterDto
+	page, pageSize, err := parsePagination(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sortColumnParam := r.URL.Query().Get("column")
+	if sortColumnParam == "" {
+		sortColumnParam = "email"
+	}
+
+	sortOrderParam := r.URL.Query().Get("order")
+	if sortOrderParam == "" {
+		sortOrderParam = "ASC"
+	}
+
+	filters.Page = page
+	filters.PageSize = pageSize
+	filters.Search = r.URL.Query().Get("search")
+	filters.SortColumn = sortColumnParam
+	filters.SortOrder = sortOrderParam
+
+	return &filters, nil
+}
+
+// parseAttendeeExportColumns reads the comma separated query parameter columns, all columns are exported by default
+func parseAttendeeExportColumns(r *http.Request) ([]string, error) {
+	columnsParam := r.URL.Query().Get("columns")
+	if columnsParam == "" {
+		return dtos.AttendeeExportColumns, nil
+	}
+
+	columns := strings.Split(columnsParam, ",")
+	for _, column := range columns {
+		if !slices.Contains(dtos.AttendeeExportColumns, column) {
+			return nil, fmt.Errorf("columns must be a comma separated list of %s", strings.Join(dtos.AttendeeExportColumns, ", "))
+		}
+	}
+
+	return columns, nil
+}
+
+// parsePagination reads the query parameters page and page_size, which default to the first page with 10 entries
+func parsePagination(r *http.Request) (int, int, error) {
+	page := 1
+	if pageParam := r.URL.Query().Get("page"); pageParam != "" {
+		var err error
+		page, err = strconv.Atoi(pageParam)
+		if err != nil {
+			return 0, 0, errors.New("page must be a number")
+		}
+		if page < 1 {
+			return 0, 0, errors.New("page must be greater or equal 1")
+		}
+	}
+
+	pageSize := 10
+	if pageSizeParam := r.URL.Query().Get("page_size"); pageSizeParam != "" {
+		var err error
+		pageSize, err = strconv.Atoi(pageSizeParam)
+		if err != nil {
+			return 0, 0, errors.New("page size must be a number")
+		}
+	}
+
+	return page, pageSize, nil
+}
+
+func buildListMetadata(page int, pageSize int, totalCount int) *dtos.EventListMetadata {
+	return &dtos.EventListMetadata{
+		CurrentPage:  page,
+		PageSize:     pageSize,
+		LastPage:     int(math.Ceil(float64(totalCount) / float64(pageSize))),
+		TotalRecords: totalCount,
+	}
+}
@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"encoding/json"
+	"eventom-backend/services"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+)
+
+type RolesController struct {
+	rolesService services.RolesServiceInterface
+	logger       *utils.Logger
+}
+
+func NewRolesController(rolesService services.RolesServiceInterface, logger *utils.Logger) *RolesController {
+	return &RolesController{
+		rolesService: rolesService,
+		logger:       logger,
+	}
+}
+
+func (rc RolesController) HandleGetRoles(w http.ResponseWriter, r *http.Request) {
+	rolesList, responseErr := rc.rolesService.GetRoles()
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(&rolesList)
+
+	if err != nil {
+		rc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (rc RolesController) HandleGetUserRoles(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+
+	rolesList, responseErr := rc.rolesService.GetUserRoles(userId)
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(&rolesList)
+
+	if err != nil {
+		rc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (rc RolesController) HandleAssignRole(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+	roleName := r.PathValue("role")
+
+	responseErr := rc.rolesService.AssignRole(userId, roleName)
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	rc.logger.Log(utils.LevelInfo, fmt.Sprintf("Role %s assigned to user with ID %s", roleName, userId), nil)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (rc RolesController) HandleRemoveRole(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+	roleName := r.PathValue("role")
+
+	actingUserId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	if !ok {
+		rc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	responseErr := rc.rolesService.RemoveRole(actingUserId, userId, roleName)
+
+	if responseErr != nil {
+		rc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	rc.logger.Log(utils.LevelInfo, fmt.Sprintf("Role %s removed from user with ID %s", roleName, userId), nil)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,124 @@
+package controllers
+
+import (
+	"encoding/json"
+	"eventom-backend/dtos"
+	"eventom-backend/services"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+)
+
+type TokensController struct {
+	tokensService services.TokensServiceInterface
+	logger        *utils.Logger
+}
+
+func NewTokensController(tokensService services.TokensServiceInterface, logger *utils.Logger) *TokensController {
+	return &TokensController{
+		tokensService: tokensService,
+		logger:        logger,
+	}
+}
+
+func (tc TokensController) HandleRefreshToken(w http.ResponseWriter, r *http.Request) {
+	refreshToken, err := r.Cookie(utils.RefreshTokenCookieName)
+
+	if err != nil {
+		tc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	authTokens, responseErr := tc.tokensService.RefreshTokens(refreshToken.Value, utils.GetClientInfo(r))
+
+	if responseErr != nil {
+		tc.logger.Log(utils.LevelError, responseErr.Message, map[string]string{
+			"Request IP Address: ": r.RemoteAddr,
+		})
+		// a conflict means a parallel request of the same client has already rotated the token and set the new cookies
+		if responseErr.Status != http.StatusConflict {
+			utils.ClearAuthCookies(w)
+		}
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	tc.logger.Log(utils.LevelInfo, fmt.Sprintf("Tokens of user with ID %s refreshed", authTokens.UserId), nil)
+
+	utils.SetAuthCookies(w, authTokens)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleGetJwks publishes the public keys that verify our tokens, so other services can verify them without calling us
+func (tc TokensController) HandleGetJwks(w http.ResponseWriter, r *http.Request) {
+	responseJson, err := json.Marshal(tc.tokensService.GetJwks())
+
+	if err != nil {
+		tc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// new keys are published before they sign tokens, a short cache keeps the rotation working for clients
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (tc TokensController) HandleGetSessions(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(utils.ContextClaimsKey).(*dtos.AccessTokenClaims)
+
+	sessions, responseErr := tc.tokensService.GetSessions(claims.UserId)
+
+	if responseErr != nil {
+		tc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	sessionsList := make([]*dtos.SessionInfo, 0, len(sessions))
+
+	for _, session := range sessions {
+		sessionsList = append(sessionsList, &dtos.SessionInfo{
+			Session: session,
+			Current: session.ID == claims.SessionId,
+		})
+	}
+
+	responseJson, err := json.Marshal(sessionsList)
+
+	if err != nil {
+		tc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (tc TokensController) HandleRevokeSession(w http.ResponseWriter, r *http.Request) {
+	claims := r.Context().Value(utils.ContextClaimsKey).(*dtos.AccessTokenClaims)
+	sessionId := r.PathValue("id")
+
+	responseErr := tc.tokensService.RevokeSession(claims.UserId, sessionId)
+
+	if responseErr != nil {
+		tc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	tc.logger.Log(utils.LevelInfo, fmt.Sprintf("Session with ID %s of user with ID %s revoked", sessionId, claims.UserId), nil)
+
+	// revoking the current session is a logout
+	if sessionId == claims.SessionId {
+		utils.ClearAuthCookies(w)
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
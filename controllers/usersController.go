@@ -2,12 +2,13 @@ package controllers
 
 import (
 	"encoding/json"
+	"eventom-backend/dtos"
 	"eventom-backend/models"
 	"eventom-backend/services"
 	"eventom-backend/utils"
 	"fmt"
 	"net/http"
-	"time"
+	"strings"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -51,6 +52,205 @@ func (uc UsersController) HandleSignupUser(w http.ResponseWriter, r *http.Reques
 	w.WriteHeader(http.StatusOK)
 }
 
+func (uc UsersController) HandleVerifyEmail(w http.ResponseWriter, r *http.Request) {
+	verificationToken := r.URL.Query().Get("token")
+
+	if verificationToken == "" {
+		uc.logger.Log(utils.LevelError, "Verification token missing", nil)
+		http.Error(w, "Verification token missing", http.StatusBadRequest)
+		return
+	}
+
+	responseErr := uc.usersService.VerifyEmail(verificationToken)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (uc UsersController) HandleResendVerificationEmail(w http.ResponseWriter, r *http.Request) {
+	var emailRequest dtos.EmailRequestDto
+	err := json.NewDecoder(r.Body).Decode(&emailRequest)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = uc.validator.Struct(&emailRequest)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// the mail is sent in the background and errors are only logged. Answering before the address is looked up keeps the response
+	// time the same for registered and unknown addresses
+	go func() {
+		responseErr := uc.usersService.ResendVerificationEmail(emailRequest.Email)
+
+		if responseErr != nil {
+			uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		}
+	}()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (uc UsersController) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var emailRequest dtos.EmailRequestDto
+	err := json.NewDecoder(r.Body).Decode(&emailRequest)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = uc.validator.Struct(&emailRequest)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// errors are only logged, the response must not reveal whether the address is registered. The mail is sent in the background,
+	// so the response time does not reveal it either
+	go func() {
+		responseErr := uc.usersService.ForgotPassword(emailRequest.Email)
+
+		if responseErr != nil {
+			uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		}
+	}()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleShowPasswordResetForm serves the page the link in the password reset mail points to
+func (uc UsersController) HandleShowPasswordResetForm(w http.ResponseWriter, r *http.Request) {
+	resetToken := r.URL.Query().Get("token")
+
+	if resetToken == "" {
+		uc.logger.Log(utils.LevelError, "Password reset token missing", nil)
+		http.Error(w, "Password reset token missing", http.StatusBadRequest)
+		return
+	}
+
+	writePasswordResetPage(w, http.StatusOK, passwordResetPageData{Token: resetToken})
+}
+
+// HandleResetPassword accepts json and the form of the password reset page, the page is rendered again with the result
+func (uc UsersController) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		uc.handleResetPasswordForm(w, r)
+		return
+	}
+
+	var passwordReset dtos.PasswordResetDto
+	err := json.NewDecoder(r.Body).Decode(&passwordReset)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = uc.validator.Struct(&passwordReset)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	responseErr := uc.usersService.ResetPassword(passwordReset.Token, passwordReset.Password)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	uc.logger.Log(utils.LevelInfo, "Password reset", nil)
+
+	utils.ClearAuthCookies(w)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (uc UsersController) handleResetPasswordForm(w http.ResponseWriter, r *http.Request) {
+	passwordReset := dtos.PasswordResetDto{
+		Token:    r.PostFormValue("token"),
+		Password: r.PostFormValue("password"),
+	}
+
+	err := uc.validator.Struct(&passwordReset)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		writePasswordResetPage(w, http.StatusBadRequest, passwordResetPageData{Token: passwordReset.Token, Error: "Please enter a new password"})
+		return
+	}
+
+	responseErr := uc.usersService.ResetPassword(passwordReset.Token, passwordReset.Password)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		writePasswordResetPage(w, responseErr.Status, passwordResetPageData{Token: passwordReset.Token, Error: responseErr.Message})
+		return
+	}
+
+	uc.logger.Log(utils.LevelInfo, "Password reset", nil)
+
+	utils.ClearAuthCookies(w)
+
+	writePasswordResetPage(w, http.StatusOK, passwordResetPageData{Done: true})
+}
+
+func (uc UsersController) HandleUnlockAccount(w http.ResponseWriter, r *http.Request) {
+	unlockToken := r.URL.Query().Get("token")
+
+	if unlockToken == "" {
+		uc.logger.Log(utils.LevelError, "Unlock token missing", nil)
+		http.Error(w, "Unlock token missing", http.StatusBadRequest)
+		return
+	}
+
+	responseErr := uc.usersService.UnlockAccount(unlockToken)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (uc UsersController) HandleUnlockUser(w http.ResponseWriter, r *http.Request) {
+	userId := r.PathValue("id")
+
+	responseErr := uc.usersService.UnlockUser(userId)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	uc.logger.Log(utils.LevelInfo, fmt.Sprintf("User with ID %s unlocked", userId), nil)
+
+	w.WriteHeader(http.StatusOK)
+}
+
 func (uc UsersController) HandleLoginUser(w http.ResponseWriter, r *http.Request) {
 	var user models.User
 	bodyDecorder := json.NewDecoder(r.Body)
@@ -63,7 +263,7 @@ func (uc UsersController) HandleLoginUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	jwtToken, responseErr := uc.usersService.LoginUser(&user)
+	authTokens, mfaChallenge, responseErr := uc.usersService.LoginUser(&user, utils.GetClientInfo(r))
 
 	if responseErr != nil {
 		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
@@ -71,25 +271,107 @@ func (uc UsersController) HandleLoginUser(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// the cookies are only set after the second factor has been verified via POST /login/mfa
+	if mfaChallenge != nil {
+		responseJson, err := json.Marshal(mfaChallenge)
+
+		if err != nil {
+			uc.logger.Log(utils.LevelFatal, err.Error(), nil)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(responseJson)
+		return
+	}
+
 	uc.logger.Log(utils.LevelInfo, fmt.Sprintf("User with ID %s logged in", user.ID), nil)
 
-	http.SetCookie(w, &http.Cookie{
-		Name:     "jwt",
-		Value:    jwtToken,
-		Secure:   true,
-		HttpOnly: true,
-		Expires:  time.Now().Add(time.Hour),
-	})
+	utils.SetAuthCookies(w, authTokens)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (uc UsersController) HandleLoginUserMfa(w http.ResponseWriter, r *http.Request) {
+	var mfaLogin dtos.MfaLoginDto
+	err := json.NewDecoder(r.Body).Decode(&mfaLogin)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = uc.validator.Struct(&mfaLogin)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	authTokens, responseErr := uc.usersService.LoginUserWithMfa(mfaLogin.MfaToken, mfaLogin.Code, utils.GetClientInfo(r))
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	uc.logger.Log(utils.LevelInfo, fmt.Sprintf("User with ID %s logged in with two-factor authentication", authTokens.UserId), nil)
+
+	utils.SetAuthCookies(w, authTokens)
 
 	w.WriteHeader(http.StatusOK)
 }
 
 func (uc UsersController) HandleLogoutUser(w http.ResponseWriter, r *http.Request) {
-	http.SetCookie(w, &http.Cookie{
-		Name:    "jwt",
-		Value:   "",
-		Expires: time.Now(),
-	})
+	var refreshToken, accessToken string
+
+	if cookie, err := r.Cookie(utils.RefreshTokenCookieName); err == nil {
+		refreshToken = cookie.Value
+	}
+
+	if cookie, err := r.Cookie(utils.AccessTokenCookieName); err == nil {
+		accessToken = cookie.Value
+	}
+
+	// end the session server side as well, otherwise copies of the tokens would stay valid
+	responseErr := uc.usersService.LogoutUser(refreshToken, accessToken)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	utils.ClearAuthCookies(w)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (uc UsersController) HandleLogoutUserEverywhere(w http.ResponseWriter, r *http.Request) {
+	userId, ok := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	if !ok {
+		uc.logger.Log(utils.LevelFatal, "Could not convert user id from token to a string", nil)
+		http.Error(w, "Could not convert user id from token to a string", http.StatusInternalServerError)
+		return
+	}
+
+	responseErr := uc.usersService.LogoutUserEverywhere(userId)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	uc.logger.Log(utils.LevelInfo, fmt.Sprintf("User with ID %s logged out everywhere", userId), nil)
+
+	utils.ClearAuthCookies(w)
 
 	w.WriteHeader(http.StatusOK)
 }
@@ -115,3 +397,167 @@ func (uc UsersController) parseUser(user *models.User, bodyDecoder *json.Decoder
 
 	return nil
 }
+
+func (uc UsersController) HandleGetMe(w http.ResponseWriter, r *http.Request) {
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	userProfile, responseErr := uc.usersService.GetProfile(userId)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	uc.writeProfile(w, userProfile)
+}
+
+func (uc UsersController) HandleUpdateMe(w http.ResponseWriter, r *http.Request) {
+	var profileUpdate dtos.UserProfileUpdateDto
+	err := json.NewDecoder(r.Body).Decode(&profileUpdate)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = uc.validator.Struct(&profileUpdate)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	userProfile, responseErr := uc.usersService.UpdateProfile(userId, &profileUpdate)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	uc.writeProfile(w, userProfile)
+}
+
+func (uc UsersController) HandleChangePassword(w http.ResponseWriter, r *http.Request) {
+	var passwordChange dtos.PasswordChangeDto
+	err := json.NewDecoder(r.Body).Decode(&passwordChange)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = uc.validator.Struct(&passwordChange)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+	claims := r.Context().Value(utils.ContextClaimsKey).(*dtos.AccessTokenClaims)
+
+	authTokens, responseErr := uc.usersService.ChangePassword(userId, claims.SessionId, passwordChange.CurrentPassword, passwordChange.NewPassword, utils.GetClientInfo(r))
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	uc.logger.Log(utils.LevelInfo, fmt.Sprintf("User with ID %s changed the password", userId), nil)
+
+	// all other sessions have been ended, the current one continues with new tokens
+	utils.SetAuthCookies(w, authTokens)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (uc UsersController) HandleChangeEmail(w http.ResponseWriter, r *http.Request) {
+	var emailChange dtos.EmailChangeDto
+	err := json.NewDecoder(r.Body).Decode(&emailChange)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = uc.validator.Struct(&emailChange)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+	claims := r.Context().Value(utils.ContextClaimsKey).(*dtos.AccessTokenClaims)
+
+	responseErr := uc.usersService.ChangeEmail(userId, claims.SessionId, emailChange.Email, emailChange.Password)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (uc UsersController) HandleDeleteMe(w http.ResponseWriter, r *http.Request) {
+	var passwordConfirmation dtos.PasswordConfirmationDto
+	err := json.NewDecoder(r.Body).Decode(&passwordConfirmation)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = uc.validator.Struct(&passwordConfirmation)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+	claims := r.Context().Value(utils.ContextClaimsKey).(*dtos.AccessTokenClaims)
+
+	responseErr := uc.usersService.DeleteUser(userId, claims.SessionId, passwordConfirmation.Password)
+
+	if responseErr != nil {
+		uc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	uc.logger.Log(utils.LevelInfo, fmt.Sprintf("User with ID %s deleted", userId), nil)
+
+	utils.ClearAuthCookies(w)
+
+	w.WriteHeader(http.StatusOK)
+}
+
+func (uc UsersController) writeProfile(w http.ResponseWriter, userProfile *dtos.UserProfile) {
+	responseJson, err := json.Marshal(userProfile)
+
+	if err != nil {
+		uc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"eventom-backend/mailers"
 	"eventom-backend/models"
 	"eventom-backend/repositories"
 	"eventom-backend/services"
@@ -12,10 +13,15 @@ import (
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -23,6 +29,7 @@ type UsersControllerTestSuite struct {
 	suite.Suite
 	ctx    context.Context
 	router *http.ServeMux
+	mailer *mailers.InMemoryMailer
 }
 
 func TestUsersControllerSuite(t *testing.T) {
@@ -45,12 +52,32 @@ func (suite *UsersControllerTestSuite) SetupSuite() {
 	logger := utils.NewLogger(os.Stdout)
 
 	usersRepository := repositories.NewUsersRepository(testutils.TestContainer.DB)
-	usersService := services.NewUsersService(usersRepository)
+	eventsRepository := repositories.NewEventsRepository(testutils.TestContainer.DB)
+	refreshTokensRepository := repositories.NewRefreshTokensRepository(testutils.TestContainer.DB)
+	revokedTokensRepository := repositories.NewRevokedTokensRepository(testutils.TestContainer.DB)
+	sessionsRepository := repositories.NewSessionsRepository(testutils.TestContainer.DB)
+	rolesRepository := repositories.NewRolesRepository(testutils.TestContainer.DB)
+	emailVerificationsRepository := repositories.NewEmailVerificationsRepository(testutils.TestContainer.DB)
+	passwordResetsRepository := repositories.NewPasswordResetsRepository(testutils.TestContainer.DB)
+	loginAttemptsRepository := repositories.NewLoginAttemptsRepository(testutils.TestContainer.DB)
+	mfaRepository := repositories.NewMfaRepository(testutils.TestContainer.DB)
+	transactionHandler := repositories.NewTxHandler(testutils.TestContainer.DB)
+	tokensService := services.NewTokensService(refreshTokensRepository, revokedTokensRepository, sessionsRepository, rolesRepository, *transactionHandler)
+	mailer := mailers.NewInMemoryMailer()
+	suite.mailer = mailer
+	mfaService := services.NewMfaService(mfaRepository, usersRepository, *transactionHandler)
+	loginAttemptsService := services.NewLoginAttemptsService(loginAttemptsRepository, usersRepository, *transactionHandler, mailer)
+	registrationNotifier := services.NewMailRegistrationNotifier(usersRepository, eventsRepository, mailer, logger)
+	usersService := services.NewUsersService(usersRepository, emailVerificationsRepository, passwordResetsRepository, tokensService, loginAttemptsService, mfaService, *transactionHandler, mailer, registrationNotifier)
 	usersController := NewUsersController(usersService, logger)
 
 	router := http.NewServeMux()
 	router.HandleFunc("POST /signup", usersController.HandleSignupUser)
 	router.HandleFunc("POST /login", usersController.HandleLoginUser)
+	router.HandleFunc("GET /verify-email", usersController.HandleVerifyEmail)
+	router.HandleFunc("POST /password/forgot", usersController.HandleForgotPassword)
+	router.HandleFunc("GET /password/reset", usersController.HandleShowPasswordResetForm)
+	router.HandleFunc("POST /password/reset", usersController.HandleResetPassword)
 
 	suite.router = router
 }
@@ -65,6 +92,16 @@ func (suite *UsersControllerTestSuite) BeforeTest(suiteName, testName string) {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	// failed logins of previous tests would delay or lock the next login attempts
+	query = `
+		DELETE FROM
+			login_attempts`
+	_, err = testutils.TestContainer.DB.Exec(query)
+
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func (suite *UsersControllerTestSuite) TestSignupFailNoEmail() {
@@ -164,6 +201,81 @@ func (suite *UsersControllerTestSuite) TestSignupFailUniqueEmail() {
 	assert.Equal(suite.T(), 409, recorderFail.Result().StatusCode)
 }
 
+func (suite *UsersControllerTestSuite) TestResetPasswordWithMailedLink() {
+	recorder := httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/signup", strings.NewReader(`{"email":"test@test.com","password":"Test123"}`)))
+	require.Equal(suite.T(), 200, recorder.Result().StatusCode)
+
+	recorder = httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest("GET", suite.lastMailedLink("test@test.com"), nil))
+	require.Equal(suite.T(), 200, recorder.Result().StatusCode)
+
+	sentMessages := len(suite.mailer.Messages())
+	recorder = httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/password/forgot", strings.NewReader(`{"email":"test@test.com"}`)))
+	require.Equal(suite.T(), 200, recorder.Result().StatusCode)
+
+	// the reset mail is sent in the background
+	require.Eventually(suite.T(), func() bool {
+		return len(suite.mailer.Messages()) > sentMessages
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// the link from the mail is opened in the browser and shows the form
+	resetLink := suite.lastMailedLink("test@test.com")
+	recorder = httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest("GET", resetLink, nil))
+	require.Equal(suite.T(), 200, recorder.Result().StatusCode)
+	assert.Contains(suite.T(), recorder.Header().Get("Content-Type"), "text/html")
+	assert.Equal(suite.T(), "no-referrer", recorder.Header().Get("Referrer-Policy"))
+
+	parsedLink, err := url.Parse(resetLink)
+	require.Nil(suite.T(), err)
+	resetToken := parsedLink.Query().Get("token")
+	assert.Contains(suite.T(), recorder.Body.String(), `name="token" value="`+resetToken+`"`)
+
+	// submitting the form sets the new password
+	form := url.Values{"token": {resetToken}, "password": {"NewPassword123"}}
+	request := httptest.NewRequest("POST", "/password/reset", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder = httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, request)
+	require.Equal(suite.T(), 200, recorder.Result().StatusCode)
+	assert.Contains(suite.T(), recorder.Body.String(), "Your password has been changed")
+
+	recorder = httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, httptest.NewRequest("POST", "/login", strings.NewReader(`{"email":"test@test.com","password":"NewPassword123"}`)))
+	assert.Equal(suite.T(), 200, recorder.Result().StatusCode)
+
+	// the token can only be used once
+	request = httptest.NewRequest("POST", "/password/reset", strings.NewReader(form.Encode()))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder = httptest.NewRecorder()
+	suite.router.ServeHTTP(recorder, request)
+	assert.NotEqual(suite.T(), 200, recorder.Result().StatusCode)
+}
+
+// lastMailedLink returns the path and query of the link in the last mail to the given address
+func (suite *UsersControllerTestSuite) lastMailedLink(email string) string {
+	messages := suite.mailer.Messages()
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != email {
+			continue
+		}
+
+		link := regexp.MustCompile(`https?://\S+`).FindString(messages[i].Body)
+		require.NotEmpty(suite.T(), link)
+
+		parsedLink, err := url.Parse(link)
+		require.Nil(suite.T(), err)
+
+		return parsedLink.RequestURI()
+	}
+
+	suite.T().Fatalf("no mail sent to %s", email)
+	return ""
+}
+
 func (suite *UsersControllerTestSuite) TestLoginFailNoUser() {
 	user := &models.User{
 		Email:    "test@test.com",
@@ -0,0 +1,136 @@
+package controllers
+
+import (
+	"encoding/json"
+	"eventom-backend/dtos"
+	"eventom-backend/services"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type VenuesController struct {
+	venuesService services.VenuesServiceInterface
+	validator     *validator.Validate
+	logger        *utils.Logger
+}
+
+func NewVenuesController(venuesService services.VenuesServiceInterface, logger *utils.Logger) *VenuesController {
+	return &VenuesController{
+		venuesService: venuesService,
+		validator:     validator.New(),
+		logger:        logger,
+	}
+}
+
+func (vc VenuesController) HandleCreateVenue(w http.ResponseWriter, r *http.Request) {
+	var venueDto dtos.CreateVenueDto
+	err := json.NewDecoder(r.Body).Decode(&venueDto)
+
+	if err != nil {
+		vc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	err = vc.validator.Struct(&venueDto)
+
+	if err != nil {
+		vc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userId := r.Context().Value(utils.ContextUserIdKey).(string)
+
+	createdVenue, responseErr := vc.venuesService.CreateVenue(userId, &venueDto)
+
+	if responseErr != nil {
+		vc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	vc.logger.Log(utils.LevelInfo, fmt.Sprintf("Venue with ID %s created", createdVenue.ID), nil)
+
+	responseJson, err := json.Marshal(createdVenue)
+
+	if err != nil {
+		vc.logger.Log(utils.LevelFatal, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	w.Write(responseJson)
+}
+
+func (vc VenuesController) HandleGetVenue(w http.ResponseWriter, r *http.Request) {
+	venueId := r.PathValue("id")
+
+	venue, responseErr := vc.venuesService.GetVenue(venueId)
+
+	if responseErr != nil {
+		vc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(venue)
+
+	if err != nil {
+		vc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
+
+func (vc VenuesController) HandleGetVenues(w http.ResponseWriter, r *http.Request) {
+	page, pageSize, err := parsePagination(r)
+
+	filters := &dtos.VenueFilterDto{
+		Page:     page,
+		PageSize: pageSize,
+		Search:   r.URL.Query().Get("search"),
+	}
+
+	if err == nil {
+		err = vc.validator.Struct(filters)
+	}
+
+	if err != nil {
+		vc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	venues, totalCount, responseErr := vc.venuesService.GetVenues(filters)
+
+	if responseErr != nil {
+		vc.logger.Log(utils.LevelError, responseErr.Message, nil)
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return
+	}
+
+	responseJson, err := json.Marshal(&dtos.VenueListResponse{
+		Venues:   venues,
+		Metadata: buildListMetadata(filters.Page, filters.PageSize, totalCount),
+	})
+
+	if err != nil {
+		vc.logger.Log(utils.LevelError, err.Error(), nil)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(responseJson)
+}
@@ -1,3 +1,5 @@
+-- this script creates the database of a new installation and is run again by `make migrate` to update existing ones,
+-- so every statement has to be idempotent. Columns added to existing tables get an ALTER TABLE below their table
 CREATE EXTENSION IF NOT EXISTS plpgsql WITH SCHEMA pg_catalog;
 CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA pg_catalog;
 
@@ -5,22 +7,180 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA pg_catalog;
 CREATE TABLE IF NOT EXISTS users (
   id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
   email TEXT NOT NULL UNIQUE,
-  password TEXT NOT NULL
+  password TEXT NOT NULL,
+  verified boolean NOT NULL DEFAULT false,
+  display_name text NOT NULL DEFAULT '',
+  timezone text NOT NULL DEFAULT 'UTC',
+  locale text NOT NULL DEFAULT 'en',
+  erased_at timestamptz
 );
 
--- events
+ALTER TABLE users
+  ADD COLUMN IF NOT EXISTS display_name text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'UTC',
+  ADD COLUMN IF NOT EXISTS locale text NOT NULL DEFAULT 'en';
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at timestamptz;
+
+-- users that signed up before email verification was required have been able to log in already, so they count as verified
+DO $$
+BEGIN
+  IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = 'public' AND table_name = 'users' AND column_name = 'verified') THEN
+    ALTER TABLE users ADD COLUMN verified boolean NOT NULL DEFAULT false;
+    UPDATE users SET verified = true;
+  END IF;
+END
+$$;
+
+-- venues events take place at, the coordinates are used to search for events around a point
+CREATE TABLE IF NOT EXISTS venues (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  venue_name text NOT NULL,
+  address text NOT NULL,
+  latitude double precision NOT NULL CHECK(latitude BETWEEN -90 AND 90),
+  longitude double precision NOT NULL CHECK(longitude BETWEEN -180 AND 180),
+  user_id uuid,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- recurring events, every occurrence is an event of its own with its own capacity and registrations
+CREATE TABLE IF NOT EXISTS event_series (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  recurrence_rule text NOT NULL,
+  start_date date NOT NULL,
+  timezone text NOT NULL DEFAULT 'UTC',
+  exception_dates date[] NOT NULL DEFAULT '{}',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+ALTER TABLE event_series ADD COLUMN IF NOT EXISTS timezone text NOT NULL DEFAULT 'UTC';
+
+-- text search configuration that stems the words of a language given as BCP 47 tag, words of unknown languages are not stemmed
+CREATE OR REPLACE FUNCTION text_search_configuration(language text) RETURNS regconfig AS $$
+  SELECT (CASE split_part(lower(language), '-', 1)
+    WHEN 'da' THEN 'danish'
+    WHEN 'de' THEN 'german'
+    WHEN 'en' THEN 'english'
+    WHEN 'es' THEN 'spanish'
+    WHEN 'fi' THEN 'finnish'
+    WHEN 'fr' THEN 'french'
+    WHEN 'hu' THEN 'hungarian'
+    WHEN 'it' THEN 'italian'
+    WHEN 'nb' THEN 'norwegian'
+    WHEN 'nl' THEN 'dutch'
+    WHEN 'nn' THEN 'norwegian'
+    WHEN 'no' THEN 'norwegian'
+    WHEN 'pt' THEN 'portuguese'
+    WHEN 'ro' THEN 'romanian'
+    WHEN 'ru' THEN 'russian'
+    WHEN 'sv' THEN 'swedish'
+    WHEN 'tr' THEN 'turkish'
+    ELSE 'simple'
+  END)::regconfig
+$$ LANGUAGE sql IMMUTABLE;
+
+-- events, the events of deleted users are kept as tombstones without an owner
 CREATE TABLE IF NOT EXISTS events (
   id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
   event_name text NOT NULL,
   event_description text NOT NULL,
   event_location text NOT NULL,
-  event_date date NOT NULL,
+  category text NOT NULL DEFAULT '',
+  tags text[] NOT NULL DEFAULT '{}',
+  event_start timestamptz NOT NULL,
+  event_end timestamptz NOT NULL,
+  event_timezone text NOT NULL DEFAULT 'UTC',
   max_capacity integer NOT NULL,
   amount_registrations integer DEFAULT 0,
-  user_id uuid NOT NULL,
-  FOREIGN KEY(user_id) REFERENCES users(id)
+  user_id uuid,
+  deleted_at timestamptz,
+  deletion_reason text NOT NULL DEFAULT '',
+  series_id uuid,
+  venue_id uuid,
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  revision integer NOT NULL DEFAULT 0,
+  event_language text NOT NULL DEFAULT 'en',
+  search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector(text_search_configuration(event_language), event_name), 'A') ||
+    setweight(to_tsvector(text_search_configuration(event_language), event_description), 'B') ||
+    setweight(to_tsvector(text_search_configuration(event_language), event_location), 'C')
+  ) STORED,
+  prefix_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', event_name || ' ' || event_description || ' ' || event_location)
+  ) STORED,
+  CHECK(event_end > event_start),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL,
+  FOREIGN KEY(series_id) REFERENCES event_series(id) ON DELETE SET NULL,
+  FOREIGN KEY(venue_id) REFERENCES venues(id) ON DELETE SET NULL
 );
 
+-- events of deleted users are kept without an owner, the constraint is replaced to update databases created with a required owner
+ALTER TABLE events
+  ALTER COLUMN user_id DROP NOT NULL,
+  DROP CONSTRAINT IF EXISTS events_user_id_fkey,
+  ADD CONSTRAINT events_user_id_fkey FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL;
+
+ALTER TABLE events
+  ADD COLUMN IF NOT EXISTS deleted_at timestamptz,
+  ADD COLUMN IF NOT EXISTS deletion_reason text NOT NULL DEFAULT '';
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS series_id uuid REFERENCES event_series(id) ON DELETE SET NULL;
+
+-- events used to last a whole day without a time zone, existing events take the whole day in UTC
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = 'public' AND table_name = 'events' AND column_name = 'event_date') THEN
+    ALTER TABLE events
+      ADD COLUMN IF NOT EXISTS event_start timestamptz,
+      ADD COLUMN IF NOT EXISTS event_end timestamptz,
+      ADD COLUMN IF NOT EXISTS event_timezone text NOT NULL DEFAULT 'UTC';
+    UPDATE events SET event_start = event_date::timestamp AT TIME ZONE 'UTC', event_end = (event_date + 1)::timestamp AT TIME ZONE 'UTC';
+    ALTER TABLE events
+      ALTER COLUMN event_start SET NOT NULL,
+      ALTER COLUMN event_end SET NOT NULL,
+      ADD CONSTRAINT events_check CHECK(event_end > event_start),
+      DROP COLUMN event_date;
+  END IF;
+END
+$$;
+
+ALTER TABLE events
+  ADD COLUMN IF NOT EXISTS updated_at timestamptz NOT NULL DEFAULT now(),
+  ADD COLUMN IF NOT EXISTS revision integer NOT NULL DEFAULT 0;
+
+ALTER TABLE events
+  ADD COLUMN IF NOT EXISTS category text NOT NULL DEFAULT '',
+  ADD COLUMN IF NOT EXISTS tags text[] NOT NULL DEFAULT '{}';
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS venue_id uuid REFERENCES venues(id) ON DELETE SET NULL;
+
+ALTER TABLE events ADD COLUMN IF NOT EXISTS event_language text NOT NULL DEFAULT 'en';
+
+-- the search vector used to stem every event in english, it is generated again in the language of the event
+DO $$
+BEGIN
+  IF EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = 'public' AND table_name = 'events' AND column_name = 'search_vector' AND generation_expression NOT LIKE '%text_search_configuration%') THEN
+    ALTER TABLE events DROP COLUMN search_vector;
+  END IF;
+END
+$$;
+
+ALTER TABLE events
+  ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector(text_search_configuration(event_language), event_name), 'A') ||
+    setweight(to_tsvector(text_search_configuration(event_language), event_description), 'B') ||
+    setweight(to_tsvector(text_search_configuration(event_language), event_location), 'C')
+  ) STORED,
+  ADD COLUMN IF NOT EXISTS prefix_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', event_name || ' ' || event_description || ' ' || event_location)
+  ) STORED;
+
+-- replaced by events_search_index
+DROP INDEX IF EXISTS events_name_search_index;
+
 -- registrations
 CREATE TABLE IF NOT EXISTS registrations (
   id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
@@ -31,5 +191,268 @@ CREATE TABLE IF NOT EXISTS registrations (
   UNIQUE(event_id, user_id)
 );
 
--- full text search index on event names
-CREATE INDEX IF NOT EXISTS events_name_search_index ON events USING GIN(to_tsvector('simple', event_name));
\ No newline at end of file
+-- refresh tokens, every login starts a new token family that is rotated on each refresh
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  family_id uuid NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  revoked_at timestamptz,
+  replaced_by uuid,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_family_index ON refresh_tokens(family_id);
+
+-- revoked access tokens, rows can be removed once the token would have expired anyway
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti uuid PRIMARY KEY,
+  user_id uuid NOT NULL,
+  expires_at timestamptz NOT NULL,
+  revoked_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- all access tokens of a user issued before revoked_before are invalid
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+  user_id uuid PRIMARY KEY,
+  revoked_before timestamptz NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- roles and permissions for role based access control
+CREATE TABLE IF NOT EXISTS roles (
+  role_name text PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+  permission_name text PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role_name text NOT NULL,
+  permission_name text NOT NULL,
+  PRIMARY KEY(role_name, permission_name),
+  FOREIGN KEY(role_name) REFERENCES roles(role_name) ON DELETE CASCADE,
+  FOREIGN KEY(permission_name) REFERENCES permissions(permission_name) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id uuid NOT NULL,
+  role_name text NOT NULL,
+  PRIMARY KEY(user_id, role_name),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY(role_name) REFERENCES roles(role_name) ON DELETE CASCADE
+);
+
+INSERT INTO roles(role_name) VALUES
+  ('admin'),
+  ('organizer'),
+  ('attendee')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO permissions(permission_name) VALUES
+  ('events:write'),
+  ('events:manage_any'),
+  ('registrations:read'),
+  ('registrations:write'),
+  ('registrations:manage_any'),
+  ('users:manage')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions(role_name, permission_name) VALUES
+  ('admin', 'events:write'),
+  ('admin', 'events:manage_any'),
+  ('admin', 'registrations:read'),
+  ('admin', 'registrations:write'),
+  ('admin', 'registrations:manage_any'),
+  ('admin', 'users:manage'),
+  ('organizer', 'events:write'),
+  ('attendee', 'registrations:read'),
+  ('attendee', 'registrations:write')
+ON CONFLICT DO NOTHING;
+
+-- users that existed before roles were introduced get the roles of a new signup, users whose roles have been managed already are left alone
+INSERT INTO user_roles(user_id, role_name)
+SELECT
+  users.id, default_roles.role_name
+FROM
+  users
+CROSS JOIN
+  (VALUES ('attendee'), ('organizer')) AS default_roles(role_name)
+WHERE
+  users.erased_at IS NULL
+  AND
+  NOT EXISTS (SELECT 1 FROM user_roles WHERE user_roles.user_id = users.id)
+ON CONFLICT DO NOTHING;
+
+-- email verification tokens, the email column holds the address that gets verified by the token
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  email text NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  used_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- password reset tokens
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  used_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- failed login attempts per account (email) and per ip address, tracked for addresses that are not registered as well
+CREATE TABLE IF NOT EXISTS login_attempts (
+  scope text NOT NULL,
+  subject text NOT NULL,
+  failed_attempts integer NOT NULL DEFAULT 0,
+  last_failed_at timestamptz NOT NULL DEFAULT now(),
+  locked_until timestamptz,
+  PRIMARY KEY(scope, subject)
+);
+
+-- account unlock tokens that are mailed when an account gets locked
+CREATE TABLE IF NOT EXISTS account_unlock_tokens (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  used_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- totp secrets for two-factor authentication, the secret is only used for login after it has been confirmed with a valid code
+CREATE TABLE IF NOT EXISTS user_totp (
+  user_id uuid PRIMARY KEY,
+  secret text NOT NULL,
+  confirmed_at timestamptz,
+  last_used_step bigint NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- hashed one-time recovery codes that replace a totp code if the authenticator got lost
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  code_hash text NOT NULL,
+  used_at timestamptz,
+  UNIQUE(user_id, code_hash),
+  FOREIGN KEY(user_id) REFERENCES user_totp(user_id) ON DELETE CASCADE
+);
+
+-- personal api keys for machine clients, the scopes are permission names that limit what the key can do on behalf of its user
+CREATE TABLE IF NOT EXISTS api_keys (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  key_name text NOT NULL,
+  key_prefix text NOT NULL,
+  key_hash text NOT NULL UNIQUE,
+  scopes text[] NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  expires_at timestamptz,
+  last_used_at timestamptz,
+  revoked_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- erasure requests of users, pending requests are processed by the erasure job that anonymizes the user
+CREATE TABLE IF NOT EXISTS erasure_requests (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  requested_by uuid NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  completed_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS erasure_requests_pending_index ON erasure_requests(user_id) WHERE completed_at IS NULL;
+
+-- external identities of users that log in with openid connect, the subject is unique per issuer
+CREATE TABLE IF NOT EXISTS user_identities (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  issuer text NOT NULL,
+  subject text NOT NULL,
+  email text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  last_login_at timestamptz,
+  UNIQUE(issuer, subject),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- pending openid connect logins, the state is sent to the identity provider and identifies the login on the callback
+CREATE TABLE IF NOT EXISTS oidc_auth_requests (
+  state_hash text PRIMARY KEY,
+  nonce text NOT NULL,
+  code_verifier text NOT NULL,
+  expires_at timestamptz NOT NULL
+);
+
+-- sessions of logged in users, the id of a session is the family id of its refresh tokens and the sid claim of its access tokens
+CREATE TABLE IF NOT EXISTS sessions (
+  id uuid PRIMARY KEY,
+  user_id uuid NOT NULL,
+  user_agent text NOT NULL DEFAULT '',
+  ip_address text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  last_seen_at timestamptz NOT NULL DEFAULT now(),
+  expires_at timestamptz NOT NULL,
+  revoked_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- users waiting for a seat of a full event, the first in line gets the next free seat
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  event_id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT clock_timestamp(),
+  UNIQUE(event_id, user_id),
+  FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS waitlist_entries_order_index ON waitlist_entries(event_id, created_at, id);
+
+-- registrations that have been cancelled by the system, the id is the id of the cancelled registration
+CREATE TABLE IF NOT EXISTS cancelled_registrations (
+  id uuid PRIMARY KEY,
+  event_id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  reason text NOT NULL,
+  cancelled_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- secret tokens of the calendar feeds, calendar apps can not log in so the token in the feed url is the only credential
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+  user_id uuid PRIMARY KEY,
+  token_hash text NOT NULL UNIQUE,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+-- index for filtering events by time range
+CREATE INDEX IF NOT EXISTS events_time_range_index ON events(event_start, event_end);
+
+-- index for filtering events by tags
+CREATE INDEX IF NOT EXISTS events_tags_index ON events USING GIN(tags);
+
+-- index for joining events with their venues
+CREATE INDEX IF NOT EXISTS events_venue_index ON events(venue_id);
+
+-- full text search index on event names, descriptions and locations
+CREATE INDEX IF NOT EXISTS events_search_index ON events USING GIN(search_vector);
\ No newline at end of file
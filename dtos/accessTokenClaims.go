@@ -0,0 +1,15 @@
+package dtos
+
+import "time"
+
+type AccessTokenClaims struct {
+	UserId      string
+	TokenId     string
+	SessionId   string
+	Roles       []string
+	Permissions []string
+	IssuedAt    time.Time
+	ExpiresAt   time.Time
+	// ApiKeyId is set if the request has been authenticated with an api key instead of an access token
+	ApiKeyId string
+}
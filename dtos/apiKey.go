@@ -0,0 +1,22 @@
+package dtos
+
+import (
+	"eventom-backend/models"
+	"time"
+)
+
+type ApiKeyCreateDto struct {
+	Name      string     `json:"name" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+type ApiKeyUpdateDto struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// CreatedApiKey carries the plain key, it is only returned once on creation
+type CreatedApiKey struct {
+	*models.ApiKey
+	Key string `json:"key"`
+}
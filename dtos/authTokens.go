@@ -0,0 +1,11 @@
+package dtos
+
+import "time"
+
+type AuthTokens struct {
+	UserId                string
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
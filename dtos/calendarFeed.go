@@ -0,0 +1,6 @@
+package dtos
+
+// CalendarFeed carries the url of the calendar feed of a user. The url contains the token, it is only returned once on creation
+type CalendarFeed struct {
+	Url string `json:"url"`
+}
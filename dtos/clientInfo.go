@@ -0,0 +1,7 @@
+package dtos
+
+// ClientInfo describes the device a request has been sent from, it is recorded for the sessions of a user
+type ClientInfo struct {
+	IpAddress string
+	UserAgent string
+}
@@ -0,0 +1,5 @@
+package dtos
+
+type EmailRequestDto struct {
+	Email string `json:"email" validate:"required,email"`
+}
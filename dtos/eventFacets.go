@@ -0,0 +1,12 @@
+package dtos
+
+// EventFacets count the events that match the current filters per category and per tag, most frequent values first
+type EventFacets struct {
+	Categories []*FacetCount `json:"categories"`
+	Tags       []*FacetCount `json:"tags"`
+}
+
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
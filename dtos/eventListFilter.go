@@ -1,11 +1,24 @@
 package dtos
 
+import "time"
+
 type EventFilterDto struct {
-	Page         int `validate:"required,gte=1"`
-	PageSize     int `validate:"required,oneof=10 15 20 25"`
-	Name         string
+	Page     int `validate:"required,gte=1"`
+	PageSize int `validate:"required,oneof=10 15 20 25"`
+	// Search is matched against the name, description and location of the events, the last word also matches as prefix
+	Search       string
 	Location     string
-	FreeCapacity int    `validate:"omitempty,number,gte=0"`
-	SortColumn   string `validate:"omitempty,oneof=id event_name event_description event_date max_capacity amount_registrations"`
-	SortOrder    string `validate:"omitempty,oneof=DESC ASC"`
+	FreeCapacity int `validate:"omitempty,number,gte=0"`
+	// From and To limit the list to events that take place at least partially in the time range
+	From *time.Time
+	To   *time.Time
+	// Categories and Tags are lists of values, events have to match any of the categories and all of the tags
+	Categories []string `validate:"max=10,dive,oneof=conference workshop meetup concert festival sports exhibition other"`
+	Tags       []string `validate:"max=10,dive,required,max=30"`
+	// NearLatitude and NearLongitude limit the list to events at venues within RadiusKm around the point
+	NearLatitude  *float64 `validate:"required_with=NearLongitude,omitempty,latitude"`
+	NearLongitude *float64 `validate:"required_with=NearLatitude,omitempty,longitude"`
+	RadiusKm      float64  `validate:"omitempty,gt=0,lte=500"`
+	SortColumn    string   `validate:"omitempty,oneof=id event_name event_description event_start event_end max_capacity amount_registrations distance relevance"`
+	SortOrder     string   `validate:"omitempty,oneof=DESC ASC"`
 }
@@ -5,4 +5,5 @@ import "eventom-backend/models"
 type EventListResponse struct {
 	Events   []*models.Event
 	Metadata *EventListMetadata
+	Facets   *EventFacets
 }
@@ -0,0 +1,21 @@
+package dtos
+
+import "time"
+
+// CreateEventSeriesDto describes a recurring event, every occurrence gets the name, description, location, venue, category, tags, language and capacity.
+// StartsAt and EndsAt are the first occurrence, the following ones start at the same local time of day in the time zone
+type CreateEventSeriesDto struct {
+	Name           string      `json:"name" validate:"required,max=100"`
+	Description    string      `json:"description,omitempty" validate:"omitempty,max=255"`
+	Location       string      `json:"location" validate:"required"`
+	VenueId        *string     `json:"venue_id,omitempty" validate:"omitempty,uuid"`
+	Category       string      `json:"category,omitempty" validate:"omitempty,oneof=conference workshop meetup concert festival sports exhibition other"`
+	Tags           []string    `json:"tags" validate:"max=10,dive,required,max=30"`
+	MaxCapacity    int         `json:"max_capacity" validate:"required,gte=1"`
+	StartsAt       time.Time   `json:"starts_at" validate:"required"`
+	EndsAt         time.Time   `json:"ends_at" validate:"required,gtfield=StartsAt"`
+	Timezone       string      `json:"timezone" validate:"omitempty,timezone"`
+	Language       string      `json:"language" validate:"omitempty,bcp47_language_tag"`
+	RecurrenceRule string      `json:"rrule" validate:"required,max=255"`
+	ExceptionDates []time.Time `json:"exdates"`
+}
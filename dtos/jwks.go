@@ -0,0 +1,14 @@
+package dtos
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+type JwkSet struct {
+	Keys []Jwk `json:"keys"`
+}
@@ -0,0 +1,27 @@
+package dtos
+
+import "time"
+
+type TotpEnrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthUri string `json:"otpauth_uri"`
+}
+
+type MfaCodeDto struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type MfaLoginDto struct {
+	MfaToken string `json:"mfa_token" validate:"required"`
+	Code     string `json:"code" validate:"required"`
+}
+
+type MfaChallenge struct {
+	MfaRequired bool      `json:"mfa_required"`
+	MfaToken    string    `json:"mfa_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type RecoveryCodes struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,27 @@
+package dtos
+
+import "github.com/golang-jwt/jwt/v5"
+
+// OidcProviderMetadata is the part of the discovery document of an openid provider that the login flow needs
+type OidcProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JwksUri               string `json:"jwks_uri"`
+}
+
+// OidcTokenResponse is the answer of the token endpoint to the code exchange
+type OidcTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IdToken     string `json:"id_token"`
+}
+
+// OidcIdTokenClaims are the claims of a verified id token that are used to find or create the user
+type OidcIdTokenClaims struct {
+	jwt.RegisteredClaims
+	Nonce         string `json:"nonce"`
+	Email         string `json:"email"`
+	EmailVerified bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
@@ -0,0 +1,6 @@
+package dtos
+
+type PasswordResetDto struct {
+	Token    string `json:"token" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
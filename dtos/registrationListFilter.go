@@ -0,0 +1,22 @@
+package dtos
+
+// UserRegistrationFilterDto filters and sorts the registrations of a user by their events
+type UserRegistrationFilterDto struct {
+	Page       int `validate:"required,gte=1"`
+	PageSize   int `validate:"required,oneof=10 15 20 25"`
+	Name       string
+	Location   string
+	Upcoming   bool
+	SortColumn string `validate:"omitempty,oneof=event_name event_location event_start"`
+	SortOrder  string `validate:"omitempty,oneof=DESC ASC"`
+}
+
+// AttendeeFilterDto filters and sorts the attendees of an event
+type AttendeeFilterDto struct {
+	Page     int `validate:"required,gte=1"`
+	PageSize int `validate:"required,oneof=10 15 20 25"`
+	// Search matches the email address or display name of the attendees
+	Search     string `validate:"omitempty,max=100"`
+	SortColumn string `validate:"omitempty,oneof=email display_name"`
+	SortOrder  string `validate:"omitempty,oneof=DESC ASC"`
+}
@@ -0,0 +1,39 @@
+package dtos
+
+import "eventom-backend/models"
+
+type RegistrationListResponse struct {
+	Registrations []*models.Registration
+	Metadata      *EventListMetadata
+}
+
+// Attendee is a registration as the organizer of the event sees it
+type Attendee struct {
+	RegistrationId string `json:"registration_id"`
+	UserId         string `json:"user_id"`
+	Email          string `json:"email"`
+	DisplayName    string `json:"display_name"`
+}
+
+// AttendeeExportColumns are the columns that can be selected for attendee exports
+var AttendeeExportColumns = []string{"registration_id", "user_id", "email", "display_name"}
+
+// ExportValue returns the value of the given export column
+func (a *Attendee) ExportValue(column string) string {
+	switch column {
+	case "registration_id":
+		return a.RegistrationId
+	case "user_id":
+		return a.UserId
+	case "email":
+		return a.Email
+	case "display_name":
+		return a.DisplayName
+	}
+	return ""
+}
+
+type AttendeeListResponse struct {
+	Attendees []*Attendee
+	Metadata  *EventListMetadata
+}
@@ -0,0 +1,9 @@
+package dtos
+
+import "eventom-backend/models"
+
+// SessionInfo marks the session the listing has been requested from
+type SessionInfo struct {
+	*models.Session
+	Current bool `json:"current"`
+}
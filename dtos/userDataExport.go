@@ -0,0 +1,24 @@
+package dtos
+
+import (
+	"eventom-backend/models"
+	"time"
+)
+
+// UserDataExport holds all data that is tied to a user, it is delivered as zip archive with one json file per part
+type UserDataExport struct {
+	ExportedAt             time.Time                       `json:"exported_at"`
+	Account                *UserProfile                    `json:"account"`
+	Roles                  []string                        `json:"roles"`
+	ApiKeys                []*models.ApiKey                `json:"api_keys"`
+	Identities             []*models.UserIdentity          `json:"identities"`
+	CalendarFeed           *models.CalendarFeedToken       `json:"calendar_feed"`
+	ErasureRequests        []*models.ErasureRequest        `json:"erasure_requests"`
+	Sessions               []*models.Session               `json:"sessions"`
+	Events                 []*models.Event                 `json:"events"`
+	EventSeries            []*models.EventSeries           `json:"event_series"`
+	Venues                 []*models.Venue                 `json:"venues"`
+	Registrations          []*models.Registration          `json:"registrations"`
+	CancelledRegistrations []*models.CancelledRegistration `json:"cancelled_registrations"`
+	WaitlistEntries        []*models.WaitlistEntry         `json:"waitlist_entries"`
+}
@@ -0,0 +1,33 @@
+package dtos
+
+type UserProfile struct {
+	ID          string `json:"id"`
+	Email       string `json:"email"`
+	DisplayName string `json:"display_name"`
+	Timezone    string `json:"timezone"`
+	Locale      string `json:"locale"`
+	Verified    bool   `json:"verified"`
+	MfaEnabled  bool   `json:"mfa_enabled"`
+}
+
+// UserProfileUpdateDto only changes the fields that are present in the request
+type UserProfileUpdateDto struct {
+	DisplayName *string `json:"display_name" validate:"omitempty,max=100"`
+	Timezone    *string `json:"timezone" validate:"omitempty,timezone"`
+	Locale      *string `json:"locale" validate:"omitempty,bcp47_language_tag"`
+}
+
+// the current password is left empty by users without a password, they confirm with a recent login instead
+type PasswordChangeDto struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password" validate:"required"`
+}
+
+type EmailChangeDto struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password"`
+}
+
+type PasswordConfirmationDto struct {
+	Password string `json:"password"`
+}
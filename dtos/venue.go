@@ -0,0 +1,24 @@
+package dtos
+
+import "eventom-backend/models"
+
+// CreateVenueDto uses pointers for the coordinates, so 0 can be told apart from a missing value
+type CreateVenueDto struct {
+	Name      string   `json:"name" validate:"required,max=100"`
+	Address   string   `json:"address" validate:"required,max=255"`
+	Latitude  *float64 `json:"latitude" validate:"required,latitude"`
+	Longitude *float64 `json:"longitude" validate:"required,longitude"`
+}
+
+// VenueFilterDto searches the venues page by page
+type VenueFilterDto struct {
+	Page     int `validate:"required,gte=1"`
+	PageSize int `validate:"required,oneof=10 15 20 25"`
+	// Search matches the name or address of the venues
+	Search string `validate:"omitempty,max=100"`
+}
+
+type VenueListResponse struct {
+	Venues   []*models.Venue
+	Metadata *EventListMetadata
+}
@@ -0,0 +1,37 @@
+package mailers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FileMailer writes every mail as .eml file into a directory instead of sending it, which is handy for local runs
+type FileMailer struct {
+	directory string
+	from      string
+}
+
+func NewFileMailer(directory string, from string) (*FileMailer, error) {
+	err := os.MkdirAll(directory, 0755)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileMailer{
+		directory: directory,
+		from:      from,
+	}, nil
+}
+
+func (fm *FileMailer) Send(message *Message) error {
+	filename := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405"), uuid.NewString())
+
+	return os.WriteFile(filepath.Join(fm.directory, filename), formatMessage(fm.from, message), 0644)
+}
+
+var _ Mailer = (*FileMailer)(nil)
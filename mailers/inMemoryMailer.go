@@ -0,0 +1,30 @@
+package mailers
+
+import "sync"
+
+// InMemoryMailer keeps all mails in memory so tests can inspect them
+type InMemoryMailer struct {
+	mutex    sync.Mutex
+	messages []*Message
+}
+
+func NewInMemoryMailer() *InMemoryMailer {
+	return &InMemoryMailer{
+		messages: make([]*Message, 0),
+	}
+}
+
+func (im *InMemoryMailer) Send(message *Message) error {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	im.messages = append(im.messages, message)
+	return nil
+}
+
+func (im *InMemoryMailer) Messages() []*Message {
+	im.mutex.Lock()
+	defer im.mutex.Unlock()
+	return append([]*Message(nil), im.messages...)
+}
+
+var _ Mailer = (*InMemoryMailer)(nil)
@@ -0,0 +1,12 @@
+package mailers
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer sends outgoing mails, the implementation is chosen on start up depending on the environment
+type Mailer interface {
+	Send(message *Message) error
+}
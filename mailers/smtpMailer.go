@@ -0,0 +1,53 @@
+package mailers
+
+import (
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+type SmtpMailer struct {
+	host     string
+	port     string
+	username string
+	password string
+	from     string
+}
+
+func NewSmtpMailer(host string, port string, username string, password string, from string) *SmtpMailer {
+	return &SmtpMailer{
+		host:     host,
+		port:     port,
+		username: username,
+		password: password,
+		from:     from,
+	}
+}
+
+func (sm *SmtpMailer) Send(message *Message) error {
+	var auth smtp.Auth
+	if sm.username != "" {
+		auth = smtp.PlainAuth("", sm.username, sm.password, sm.host)
+	}
+
+	return smtp.SendMail(net.JoinHostPort(sm.host, sm.port), auth, sm.from, []string{message.To}, formatMessage(sm.from, message))
+}
+
+func formatMessage(from string, message *Message) []byte {
+	var builder strings.Builder
+
+	builder.WriteString(fmt.Sprintf("From: %s\r\n", from))
+	builder.WriteString(fmt.Sprintf("To: %s\r\n", message.To))
+	builder.WriteString(fmt.Sprintf("Subject: %s\r\n", message.Subject))
+	builder.WriteString(fmt.Sprintf("Date: %s\r\n", time.Now().Format(time.RFC1123Z)))
+	builder.WriteString("MIME-Version: 1.0\r\n")
+	builder.WriteString("Content-Type: text/plain; charset=\"utf-8\"\r\n")
+	builder.WriteString("\r\n")
+	builder.WriteString(strings.ReplaceAll(message.Body, "\n", "\r\n"))
+
+	return []byte(builder.String())
+}
+
+var _ Mailer = (*SmtpMailer)(nil)
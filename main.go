@@ -6,6 +6,9 @@ import (
 	"os"
 
 	_ "github.com/lib/pq"
+
+	// the alpine image comes without time zone database, it is needed to validate the time zones of users
+	_ "time/tzdata"
 )
 
 func main() {
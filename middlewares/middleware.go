@@ -2,6 +2,10 @@ package middlewares
 
 import (
 	"context"
+	"errors"
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/services"
 	"eventom-backend/utils"
 	"fmt"
 	"net"
@@ -27,50 +31,105 @@ func CreateStack(mws ...Middleware) Middleware {
 	}
 }
 
-func AuthMiddleware(next http.Handler, logger *utils.Logger) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		requestTarget := r.Method + " " + strings.Split(r.URL.Path, "/")[1]
+// NewAuthMiddleware authenticates requests that carry an api key in the Authorization header or an access token cookie and
+// stores the claims in the request context. Requests without valid credentials are passed on unauthenticated, the route
+// policies decide whether that is sufficient
+func NewAuthMiddleware(tokensService services.TokensServiceInterface, apiKeysService services.ApiKeysServiceInterface) Middleware {
+	return func(next http.Handler, logger *utils.Logger) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var claims *dtos.AccessTokenClaims
+			var responseErr *models.ResponseError
 
-		if !utils.ProtectedRoutes[requestTarget] {
-			next.ServeHTTP(w, r)
-			return
-		}
+			if apiKey, ok := bearerToken(r); ok {
+				claims, responseErr = apiKeysService.AuthenticateApiKey(apiKey)
 
-		jwtToken, err := r.Cookie("jwt")
+				if responseErr != nil {
+					logger.Log(utils.LevelError, fmt.Sprintf("Failed to verify api key: %s", responseErr.Message), map[string]string{
+						"Request IP Address: ": r.RemoteAddr,
+					})
+				}
+			} else {
+				claims, responseErr = authenticateAccessToken(r, tokensService)
 
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-			return
-		}
+				if responseErr != nil {
+					logger.Log(utils.LevelError, fmt.Sprintf("Failed to verify jwt: %s", responseErr.Message), map[string]string{
+						"Request IP Address: ": r.RemoteAddr,
+					})
+				}
+			}
 
-		verifiedToken, err := utils.VerifyJwt(jwtToken.Value)
+			if claims == nil {
+				next.ServeHTTP(w, r)
+				return
+			}
 
-		if err != nil {
-			logger.Log(utils.LevelError, fmt.Sprintf("Failed to verify jwt: %s", err.Error()), map[string]string{
-				"Request IP Address: ": r.RemoteAddr,
-			})
-			http.Error(w, err.Error(), http.StatusUnauthorized)
-			return
-		}
+			next.ServeHTTP(w, r.WithContext(withClaims(r.Context(), claims)))
+		})
+	}
+}
 
-		// extract user id from token for further usage
-		claims, ok := verifiedToken.Claims.(jwt.MapClaims)
+func withClaims(ctx context.Context, claims *dtos.AccessTokenClaims) context.Context {
+	ctx = context.WithValue(ctx, utils.ContextUserIdKey, claims.UserId)
+	return context.WithValue(ctx, utils.ContextClaimsKey, claims)
+}
 
-		if !ok {
-			http.Error(w, "Could not convert jwt claims", http.StatusInternalServerError)
-			return
+// bearerToken returns the credentials of an "Authorization: Bearer" header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+
+	return strings.TrimSpace(token), true
+}
+
+// authenticateAccessToken returns the claims of a valid access token and nil claims if the access token is missing or expired
+func authenticateAccessToken(r *http.Request, tokensService services.TokensServiceInterface) (*dtos.AccessTokenClaims, *models.ResponseError) {
+	jwtToken, err := r.Cookie(utils.AccessTokenCookieName)
+
+	if err != nil {
+		return nil, nil
+	}
+
+	verifiedToken, err := utils.VerifyJwt(jwtToken.Value)
+
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, nil
 		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusUnauthorized,
+		}
+	}
 
-		userId, ok := claims["user_id"].(string)
+	claims, err := utils.ParseAccessTokenClaims(verifiedToken)
 
-		if !ok {
-			http.Error(w, "Could not convert user id from jwt claims to string", http.StatusInternalServerError)
-			return
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusUnauthorized,
 		}
+	}
 
-		ctx := context.WithValue(r.Context(), utils.ContextUserIdKey, userId)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
+	if tokensService.IsAccessTokenRevoked(claims) {
+		return nil, &models.ResponseError{
+			Message: "Token has been revoked",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	return claims, nil
+}
+
+// redactedPath hides the token of calendar feed urls before the path is logged, the token is the only credential of the feed
+func redactedPath(path string) string {
+	if strings.HasPrefix(path, "/calendar/") {
+		return "/calendar/[redacted]"
+	}
+
+	return path
 }
 
 func RateLimiterMiddleware(next http.Handler, logger *utils.Logger) http.Handler {
@@ -100,7 +159,7 @@ func RateLimiterMiddleware(next http.Handler, logger *utils.Logger) http.Handler
 		if err != nil {
 			logger.Log(utils.LevelError, err.Error(), map[string]string{
 				"Request IP Address: ": ip,
-				"Request URL: ":        r.URL.Path,
+				"Request URL: ":        redactedPath(r.URL.Path),
 			})
 			http.Error(w, err.Error(), http.StatusBadRequest)
 			return
@@ -121,7 +180,7 @@ func RateLimiterMiddleware(next http.Handler, logger *utils.Logger) http.Handler
 		if !clients[ip].limiter.Allow() {
 			logger.Log(utils.LevelError, "Too many requests", map[string]string{
 				"Request IP Address: ": ip,
-				"Request URL: ":        r.URL.Path,
+				"Request URL: ":        redactedPath(r.URL.Path),
 			})
 			http.Error(w, "Too many requests", http.StatusTooManyRequests)
 			return
@@ -0,0 +1,116 @@
+package middlewares
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/services"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+)
+
+// Policy guards single routes and is declared per route in server.InitHttpServer
+type Policy struct {
+	tokensService services.TokensServiceInterface
+	logger        *utils.Logger
+}
+
+func NewPolicy(tokensService services.TokensServiceInterface, logger *utils.Logger) *Policy {
+	return &Policy{
+		tokensService: tokensService,
+		logger:        logger,
+	}
+}
+
+// Authenticated only lets requests of logged in users pass
+func (p *Policy) Authenticated(handler http.HandlerFunc) http.HandlerFunc {
+	return p.RequirePermissions(handler)
+}
+
+// RequireSession only lets requests of users that logged in themselves pass. Routes that manage credentials use it, so a leaked api key
+// cannot be used to create further keys or to change the login of its owner
+func (p *Policy) RequireSession(handler http.HandlerFunc) http.HandlerFunc {
+	return p.RequirePermissions(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(utils.ContextClaimsKey).(*dtos.AccessTokenClaims)
+
+		if !ok || claims.ApiKeyId != "" {
+			p.logger.Log(utils.LevelError, "Api key used for a route that requires a session", map[string]string{
+				"Request IP Address: ": r.RemoteAddr,
+				"Request URL: ":        redactedPath(r.URL.Path),
+			})
+			http.Error(w, "Api keys cannot be used for this route", http.StatusForbidden)
+			return
+		}
+
+		handler(w, r)
+	})
+}
+
+// RequirePermissions only lets requests pass whose user has been granted all of the given permissions
+func (p *Policy) RequirePermissions(handler http.HandlerFunc, permissions ...string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(utils.ContextUserIdKey).(string); !ok {
+			authenticatedRequest, ok := p.refreshSession(w, r)
+
+			if !ok {
+				return
+			}
+
+			r = authenticatedRequest
+		}
+
+		for _, permission := range permissions {
+			if !utils.HasPermission(r.Context(), permission) {
+				p.logger.Log(utils.LevelError, fmt.Sprintf("Missing permission %s", permission), map[string]string{
+					"Request IP Address: ": r.RemoteAddr,
+					"Request URL: ":        redactedPath(r.URL.Path),
+				})
+				http.Error(w, "Access denied", http.StatusForbidden)
+				return
+			}
+		}
+
+		handler(w, r)
+	}
+}
+
+// refreshSession renews an expired or missing access token transparently with the refresh token
+func (p *Policy) refreshSession(w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	refreshToken, err := r.Cookie(utils.RefreshTokenCookieName)
+
+	if err != nil {
+		http.Error(w, "Authentication required", http.StatusUnauthorized)
+		return nil, false
+	}
+
+	authTokens, responseErr := p.tokensService.RefreshTokens(refreshToken.Value, utils.GetClientInfo(r))
+
+	if responseErr != nil {
+		p.logger.Log(utils.LevelError, fmt.Sprintf("Failed to refresh tokens: %s", responseErr.Message), map[string]string{
+			"Request IP Address: ": r.RemoteAddr,
+		})
+		// a conflict means a parallel request of the same client has already rotated the token and set the new cookies
+		if responseErr.Status != http.StatusConflict {
+			utils.ClearAuthCookies(w)
+		}
+		http.Error(w, responseErr.Message, responseErr.Status)
+		return nil, false
+	}
+
+	verifiedToken, err := utils.VerifyJwt(authTokens.AccessToken)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	claims, err := utils.ParseAccessTokenClaims(verifiedToken)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return nil, false
+	}
+
+	utils.SetAuthCookies(w, authTokens)
+
+	return r.WithContext(withClaims(r.Context(), claims)), true
+}
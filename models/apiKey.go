@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ApiKeyPrefix marks api keys, so they can be told apart from other tokens e.g. by secret scanners
+const ApiKeyPrefix = "evk_"
+
+type ApiKey struct {
+	ID         string     `json:"id"`
+	UserId     string     `json:"user_id"`
+	Name       string     `json:"name"`
+	KeyPrefix  string     `json:"key_prefix"`
+	KeyHash    string     `json:"-"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
@@ -0,0 +1,10 @@
+package models
+
+import "time"
+
+// CalendarFeedToken is the secret in the url of the calendar feed of a user, only its hash is stored
+type CalendarFeedToken struct {
+	UserId    string    `json:"user_id"`
+	TokenHash string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
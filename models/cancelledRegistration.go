@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+const (
+	CancellationReasonEventDeleted = "event_deleted"
+)
+
+// CancelledRegistration is kept when the system cancels a registration, so attendees and reports can tell why it is gone
+type CancelledRegistration struct {
+	ID          string    `json:"id"`
+	EventId     string    `json:"event_id"`
+	UserId      string    `json:"user_id"`
+	Reason      string    `json:"reason"`
+	CancelledAt time.Time `json:"cancelled_at"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+type EmailVerificationToken struct {
+	ID        string
+	UserId    string
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
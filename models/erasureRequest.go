@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type ErasureRequest struct {
+	ID          string     `json:"id"`
+	UserId      string     `json:"user_id"`
+	RequestedBy string     `json:"requested_by"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at"`
+}
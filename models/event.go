@@ -1,16 +1,110 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
 type Event struct {
-	ID                 string    `json:"id" validate:"omitempty,uuid"`
-	Name               string    `json:"name" validate:"required,max=100"`
-	Description        string    `json:"description,omitempty" validate:"omitempty,max=255"`
-	Location           string    `json:"location" validate:"required"`
-	Date               time.Time `json:"date" validate:"required"`
+	ID          string `json:"id" validate:"omitempty,uuid"`
+	Name        string `json:"name" validate:"required,max=100"`
+	Description string `json:"description,omitempty" validate:"omitempty,max=255"`
+	Location    string `json:"location" validate:"required"`
+	// Category is one of EventCategories, Tags are free-form and stored in lower case
+	Category string   `json:"category,omitempty" validate:"omitempty,oneof=conference workshop meetup concert festival sports exhibition other"`
+	Tags     []string `json:"tags" validate:"max=10,dive,required,max=30"`
+	// StartsAt and EndsAt are points in time, Timezone is the IANA time zone the event takes place in, e.g. Europe/Berlin.
+	// Language is the BCP 47 tag of the language the event is described in, its words are searched by their stem in this language
+	StartsAt           time.Time `json:"starts_at" validate:"required"`
+	EndsAt             time.Time `json:"ends_at" validate:"required,gtfield=StartsAt"`
+	Timezone           string    `json:"timezone" validate:"omitempty,timezone"`
+	Language           string    `json:"language" validate:"omitempty,bcp47_language_tag"`
 	MaxCapacity        int       `json:"max_capacity" validate:"required,gte=1"`
 	AmountRegistration int       `json:"amount_registrations"`
 	UserId             string    `json:"user_id"`
+	// DeletedAt is set for deleted events that are kept as tombstone for the registration history
+	DeletedAt      *time.Time `json:"deleted_at,omitempty"`
+	DeletionReason string     `json:"deletion_reason,omitempty"`
+	// SeriesId is set for occurrences of recurring events
+	SeriesId *string `json:"series_id,omitempty"`
+	VenueId  *string `json:"venue_id,omitempty" validate:"omitempty,uuid"`
+	// DistanceKm is only set when events are searched around a point, it is the distance to the venue of the event
+	DistanceKm *float64 `json:"distance_km,omitempty"`
+	// Highlights is only set when events are searched by a search term
+	Highlights *EventHighlights `json:"highlights,omitempty"`
+	UpdatedAt  time.Time        `json:"updated_at"`
+	// Revision is increased on every change, calendar apps use it to pick up updates of events they already know
+	Revision int `json:"revision"`
+}
+
+// EventHighlights are html snippets of an event that mark the words matching a search term with <mark>, everything else is escaped
+type EventHighlights struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+// EventCategories are the categories an event can be assigned to, they have to match the validation of Event.Category
+var EventCategories = []string{"conference", "workshop", "meetup", "concert", "festival", "sports", "exhibition", "other"}
+
+// DefaultEventTimezone is used for events that are created without a time zone
+const DefaultEventTimezone = "UTC"
+
+// DefaultEventLanguage is used for events that are created without a language
+const DefaultEventLanguage = "en"
+
+// Scopes of changes to an occurrence of a recurring event
+const (
+	EventEditScopeSingle    = "single"
+	EventEditScopeFollowing = "following"
+	EventEditScopeAll       = "all"
+)
+
+// TimeLocation returns the time zone of the event, events with an unknown time zone are shown in UTC
+func (e *Event) TimeLocation() *time.Location {
+	location, err := time.LoadLocation(e.Timezone)
+
+	if err != nil {
+		return time.UTC
+	}
+
+	return location
+}
+
+// LocalDate returns the date the event starts on in its time zone. The date is returned as midnight UTC so dates can be compared
+func (e *Event) LocalDate() time.Time {
+	start := e.StartsAt.In(e.TimeLocation())
+	return time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// AtDate returns a copy of the event that starts on the given date at the same local time of day and lasts as long,
+// so occurrences of a series keep their wall clock time across daylight saving time changes
+func (e *Event) AtDate(date time.Time) *Event {
+	location := e.TimeLocation()
+	start := e.StartsAt.In(location)
+
+	event := *e
+	event.StartsAt = time.Date(date.Year(), date.Month(), date.Day(), start.Hour(), start.Minute(), start.Second(), start.Nanosecond(), location)
+	event.EndsAt = event.StartsAt.Add(e.EndsAt.Sub(e.StartsAt))
+
+	return &event
+}
+
+// MarshalJSON renders the start and end in UTC and additionally in the local time of the event
+func (e Event) MarshalJSON() ([]byte, error) {
+	type event Event
+	location := e.TimeLocation()
+
+	return json.Marshal(&struct {
+		event
+		StartsAt      time.Time `json:"starts_at"`
+		EndsAt        time.Time `json:"ends_at"`
+		StartsAtLocal time.Time `json:"starts_at_local"`
+		EndsAtLocal   time.Time `json:"ends_at_local"`
+	}{
+		event:         event(e),
+		StartsAt:      e.StartsAt.UTC(),
+		EndsAt:        e.EndsAt.UTC(),
+		StartsAtLocal: e.StartsAt.In(location),
+		EndsAtLocal:   e.EndsAt.In(location),
+	})
 }
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// EventSeries repeats an event by an iCalendar RRULE. Its occurrences are created up front as events
+type EventSeries struct {
+	ID             string      `json:"id"`
+	UserId         string      `json:"user_id"`
+	RecurrenceRule string      `json:"rrule"`
+	StartDate      time.Time   `json:"start_date"`
+	Timezone       string      `json:"timezone"`
+	ExceptionDates []time.Time `json:"exdates"`
+	CreatedAt      time.Time   `json:"created_at"`
+	Occurrences    []*Event    `json:"occurrences,omitempty"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+const (
+	LoginAttemptScopeAccount   = "account"
+	LoginAttemptScopeIpAddress = "ip"
+)
+
+type LoginAttempt struct {
+	Scope          string
+	Subject        string
+	FailedAttempts int
+	LastFailedAt   time.Time
+	LockedUntil    *time.Time
+}
+
+type AccountUnlockToken struct {
+	ID        string
+	UserId    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type PasswordResetToken struct {
+	ID        string
+	UserId    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+	UsedAt    *time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type RefreshToken struct {
+	ID         string
+	UserId     string
+	FamilyId   string
+	TokenHash  string
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+	ReplacedBy *string
+}
@@ -4,4 +4,6 @@ type Registration struct {
 	ID      string `json:"id" validate:"omitempty,uuid"`
 	EventId string `json:"event_id" validate:"uuid"`
 	UserId  string `json:"user_id" validate:"uuid"`
+	// Event is only loaded by queries that list the registrations of a user
+	Event *Event `json:"event,omitempty"`
 }
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+type RevokedToken struct {
+	TokenId   string
+	UserId    string
+	ExpiresAt time.Time
+}
+
+type UserTokenRevocation struct {
+	UserId        string
+	RevokedBefore time.Time
+}
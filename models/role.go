@@ -0,0 +1,24 @@
+package models
+
+const (
+	RoleAdmin     = "admin"
+	RoleOrganizer = "organizer"
+	RoleAttendee  = "attendee"
+)
+
+const (
+	PermissionEventsWrite            = "events:write"
+	PermissionEventsManageAny        = "events:manage_any"
+	PermissionRegistrationsRead      = "registrations:read"
+	PermissionRegistrationsWrite     = "registrations:write"
+	PermissionRegistrationsManageAny = "registrations:manage_any"
+	PermissionUsersManage            = "users:manage"
+)
+
+// DefaultUserRoles are assigned to every user on signup
+var DefaultUserRoles = []string{RoleAttendee, RoleOrganizer}
+
+type Role struct {
+	Name        string   `json:"name"`
+	Permissions []string `json:"permissions"`
+}
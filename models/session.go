@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// Session is a login of a user on a device. It lives as long as its refresh tokens are rotated
+type Session struct {
+	ID         string     `json:"id"`
+	UserId     string     `json:"user_id"`
+	UserAgent  string     `json:"user_agent"`
+	IpAddress  string     `json:"ip_address"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+	RevokedAt  *time.Time `json:"-"`
+}
@@ -1,7 +1,19 @@
 package models
 
+// NoPassword is stored as password of users that signed up with openid connect. It is no bcrypt hash, so no password matches it
+const NoPassword = "!"
+
 type User struct {
-	ID       string `json:"id" validate:"omitempty,uuid"`
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	ID          string `json:"id" validate:"omitempty,uuid"`
+	Email       string `json:"email" validate:"required,email"`
+	Password    string `json:"password" validate:"required"`
+	Verified    bool   `json:"verified"`
+	DisplayName string `json:"display_name"`
+	Timezone    string `json:"timezone"`
+	Locale      string `json:"locale"`
+}
+
+// HasPassword tells whether the user can log in with a password
+func (u *User) HasPassword() bool {
+	return u.Password != NoPassword
 }
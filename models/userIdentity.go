@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to the subject of an external identity provider
+type UserIdentity struct {
+	ID          string     `json:"id"`
+	UserId      string     `json:"user_id"`
+	Issuer      string     `json:"issuer"`
+	Subject     string     `json:"subject"`
+	Email       string     `json:"email"`
+	CreatedAt   time.Time  `json:"created_at"`
+	LastLoginAt *time.Time `json:"last_login_at"`
+}
+
+// OidcAuthRequest is a login that has been started but not completed at the identity provider yet
+type OidcAuthRequest struct {
+	StateHash    string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
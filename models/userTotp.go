@@ -0,0 +1,11 @@
+package models
+
+import "time"
+
+type UserTotp struct {
+	UserId       string
+	Secret       string
+	ConfirmedAt  *time.Time
+	LastUsedStep int64
+	CreatedAt    time.Time
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// Venue is a place events take place at. Venues are shared, every organizer can link events to any venue
+type Venue struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	UserId    *string   `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
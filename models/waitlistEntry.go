@@ -0,0 +1,12 @@
+package models
+
+import "time"
+
+type WaitlistEntry struct {
+	ID        string    `json:"id"`
+	EventId   string    `json:"event_id" validate:"uuid"`
+	UserId    string    `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	// Position is the place in line, 1 gets the next free seat
+	Position int `json:"position"`
+}
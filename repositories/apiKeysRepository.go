@@ -0,0 +1,231 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+const apiKeyColumns = `id, user_id, key_name, key_prefix, key_hash, scopes, created_at, expires_at, last_used_at, revoked_at`
+
+type ApiKeysRepository struct {
+	db DBTX
+}
+
+func NewApiKeysRepository(db DBTX) *ApiKeysRepository {
+	return &ApiKeysRepository{
+		db: db,
+	}
+}
+
+func (akr *ApiKeysRepository) QueryCreateApiKey(apiKey *models.ApiKey) *models.ResponseError {
+	query := `
+		INSERT INTO
+			api_keys(user_id, key_name, key_prefix, key_hash, scopes, expires_at)
+		VALUES
+			($1, $2, $3, $4, $5, $6)
+		RETURNING
+			id, created_at`
+	row := akr.db.QueryRow(query, apiKey.UserId, apiKey.Name, apiKey.KeyPrefix, apiKey.KeyHash, pq.Array(apiKey.Scopes), apiKey.ExpiresAt)
+
+	err := row.Scan(&apiKey.ID, &apiKey.CreatedAt)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (akr *ApiKeysRepository) QueryGetUserApiKeys(userId string) ([]*models.ApiKey, *models.ResponseError) {
+	query := `
+		SELECT
+			` + apiKeyColumns + `
+		FROM
+			api_keys
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at DESC`
+	rows, err := akr.db.Query(query, userId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	apiKeysList := make([]*models.ApiKey, 0)
+
+	for rows.Next() {
+		apiKey, err := scanApiKey(rows)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		apiKeysList = append(apiKeysList, apiKey)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return apiKeysList, nil
+}
+
+func (akr *ApiKeysRepository) QueryRenameApiKey(userId string, apiKeyId string, name string) (*models.ApiKey, *models.ResponseError) {
+	query := `
+		UPDATE
+			api_keys
+		SET
+			key_name = $3
+		WHERE
+			id = $1
+			AND
+			user_id = $2
+		RETURNING
+			` + apiKeyColumns
+	row := akr.db.QueryRow(query, apiKeyId, userId, name)
+
+	return akr.scanSingleApiKey(row)
+}
+
+func (akr *ApiKeysRepository) QueryRevokeApiKey(userId string, apiKeyId string) *models.ResponseError {
+	query := `
+		UPDATE
+			api_keys
+		SET
+			revoked_at = now()
+		WHERE
+			id = $1
+			AND
+			user_id = $2
+			AND
+			revoked_at IS NULL`
+	result, err := akr.db.Exec(query, apiKeyId, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Api key not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+// QueryUseApiKey returns the active api key with the given hash and records the usage
+// QueryRevokeUserApiKeys revokes all api keys of the user that are not revoked yet
+func (akr *ApiKeysRepository) QueryRevokeUserApiKeys(userId string) *models.ResponseError {
+	query := `
+		UPDATE
+			api_keys
+		SET
+			revoked_at = now()
+		WHERE
+			user_id = $1
+			AND
+			revoked_at IS NULL`
+	_, err := akr.db.Exec(query, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (akr *ApiKeysRepository) QueryUseApiKey(keyHash string) (*models.ApiKey, *models.ResponseError) {
+	query := `
+		UPDATE
+			api_keys
+		SET
+			last_used_at = now()
+		WHERE
+			key_hash = $1
+			AND
+			revoked_at IS NULL
+			AND
+			(expires_at IS NULL OR expires_at > now())
+		RETURNING
+			` + apiKeyColumns
+	row := akr.db.QueryRow(query, keyHash)
+
+	return akr.scanSingleApiKey(row)
+}
+
+func (akr *ApiKeysRepository) scanSingleApiKey(row *sql.Row) (*models.ApiKey, *models.ResponseError) {
+	apiKey, err := scanApiKey(row)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Api key not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return apiKey, nil
+}
+
+func scanApiKey(scanner interface{ Scan(dest ...any) error }) (*models.ApiKey, error) {
+	var apiKey models.ApiKey
+	err := scanner.Scan(
+		&apiKey.ID,
+		&apiKey.UserId,
+		&apiKey.Name,
+		&apiKey.KeyPrefix,
+		&apiKey.KeyHash,
+		pq.Array(&apiKey.Scopes),
+		&apiKey.CreatedAt,
+		&apiKey.ExpiresAt,
+		&apiKey.LastUsedAt,
+		&apiKey.RevokedAt,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &apiKey, nil
+}
+
+var _ ApiKeysRepositoryInterface = (*ApiKeysRepository)(nil)
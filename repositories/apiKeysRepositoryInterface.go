@@ -0,0 +1,17 @@
+package repositories
+
+import "eventom-backend/models"
+
+type ApiKeysRepositoryInterface interface {
+	QueryCreateApiKey(apiKey *models.ApiKey) *models.ResponseError
+
+	QueryGetUserApiKeys(userId string) ([]*models.ApiKey, *models.ResponseError)
+
+	QueryRenameApiKey(userId string, apiKeyId string, name string) (*models.ApiKey, *models.ResponseError)
+
+	QueryRevokeApiKey(userId string, apiKeyId string) *models.ResponseError
+
+	QueryRevokeUserApiKeys(userId string) *models.ResponseError
+
+	QueryUseApiKey(keyHash string) (*models.ApiKey, *models.ResponseError)
+}
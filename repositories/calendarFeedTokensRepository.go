@@ -0,0 +1,131 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+)
+
+type CalendarFeedTokensRepository struct {
+	db DBTX
+}
+
+func NewCalendarFeedTokensRepository(db DBTX) *CalendarFeedTokensRepository {
+	return &CalendarFeedTokensRepository{
+		db: db,
+	}
+}
+
+// QuerySaveCalendarFeedToken stores the token of the calendar feed of the user. Every user has one feed, a new token replaces the old one
+func (cftr *CalendarFeedTokensRepository) QuerySaveCalendarFeedToken(userId string, tokenHash string) *models.ResponseError {
+	query := `
+		INSERT INTO
+			calendar_feed_tokens(user_id, token_hash)
+		VALUES
+			($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			token_hash = EXCLUDED.token_hash,
+			created_at = now()`
+	_, err := cftr.db.Exec(query, userId, tokenHash)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (cftr *CalendarFeedTokensRepository) QueryGetCalendarFeedUserId(tokenHash string) (string, *models.ResponseError) {
+	query := `
+		SELECT
+			user_id
+		FROM
+			calendar_feed_tokens
+		WHERE
+			token_hash = $1`
+	row := cftr.db.QueryRow(query, tokenHash)
+
+	var userId string
+	err := row.Scan(&userId)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", &models.ResponseError{
+				Message: "Calendar not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return userId, nil
+}
+
+// QueryGetUserCalendarFeedToken returns the token of the calendar feed of the user, nil if the user has no feed
+func (cftr *CalendarFeedTokensRepository) QueryGetUserCalendarFeedToken(userId string) (*models.CalendarFeedToken, *models.ResponseError) {
+	query := `
+		SELECT
+			user_id, token_hash, created_at
+		FROM
+			calendar_feed_tokens
+		WHERE
+			user_id = $1`
+	row := cftr.db.QueryRow(query, userId)
+
+	var calendarFeedToken models.CalendarFeedToken
+	err := row.Scan(&calendarFeedToken.UserId, &calendarFeedToken.TokenHash, &calendarFeedToken.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &calendarFeedToken, nil
+}
+
+func (cftr *CalendarFeedTokensRepository) QueryDeleteCalendarFeedToken(userId string) *models.ResponseError {
+	query := `
+		DELETE FROM
+			calendar_feed_tokens
+		WHERE
+			user_id = $1`
+	result, err := cftr.db.Exec(query, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Calendar not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+var _ CalendarFeedTokensRepositoryInterface = (*CalendarFeedTokensRepository)(nil)
@@ -0,0 +1,13 @@
+package repositories
+
+import "eventom-backend/models"
+
+type CalendarFeedTokensRepositoryInterface interface {
+	QuerySaveCalendarFeedToken(userId string, tokenHash string) *models.ResponseError
+
+	QueryGetCalendarFeedUserId(tokenHash string) (string, *models.ResponseError)
+
+	QueryGetUserCalendarFeedToken(userId string) (*models.CalendarFeedToken, *models.ResponseError)
+
+	QueryDeleteCalendarFeedToken(userId string) *models.ResponseError
+}
@@ -0,0 +1,95 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+)
+
+type EmailVerificationsRepository struct {
+	db DBTX
+}
+
+func NewEmailVerificationsRepository(db DBTX) *EmailVerificationsRepository {
+	return &EmailVerificationsRepository{
+		db: db,
+	}
+}
+
+func (evr *EmailVerificationsRepository) QueryCreateEmailVerificationToken(verificationToken *models.EmailVerificationToken) *models.ResponseError {
+	query := `
+		INSERT INTO
+			email_verification_tokens(user_id, email, token_hash, expires_at)
+		VALUES
+			($1, $2, $3, $4)`
+	_, err := evr.db.Exec(query, verificationToken.UserId, verificationToken.Email, verificationToken.TokenHash, verificationToken.ExpiresAt)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (evr *EmailVerificationsRepository) QueryGetEmailVerificationToken(tokenHash string) (*models.EmailVerificationToken, *models.ResponseError) {
+	query := `
+		SELECT
+			id, user_id, email, token_hash, expires_at, created_at, used_at
+		FROM
+			email_verification_tokens
+		WHERE
+			token_hash = $1
+		FOR UPDATE`
+	row := evr.db.QueryRow(query, tokenHash)
+
+	var verificationToken models.EmailVerificationToken
+	err := row.Scan(
+		&verificationToken.ID,
+		&verificationToken.UserId,
+		&verificationToken.Email,
+		&verificationToken.TokenHash,
+		&verificationToken.ExpiresAt,
+		&verificationToken.CreatedAt,
+		&verificationToken.UsedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Verification token not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &verificationToken, nil
+}
+
+func (evr *EmailVerificationsRepository) QueryUseEmailVerificationToken(verificationTokenId string) *models.ResponseError {
+	query := `
+		UPDATE
+			email_verification_tokens
+		SET
+			used_at = now()
+		WHERE
+			id = $1`
+	_, err := evr.db.Exec(query, verificationTokenId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+var _ EmailVerificationsRepositoryInterface = (*EmailVerificationsRepository)(nil)
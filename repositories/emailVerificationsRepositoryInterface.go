@@ -0,0 +1,11 @@
+package repositories
+
+import "eventom-backend/models"
+
+type EmailVerificationsRepositoryInterface interface {
+	QueryCreateEmailVerificationToken(verificationToken *models.EmailVerificationToken) *models.ResponseError
+
+	QueryGetEmailVerificationToken(tokenHash string) (*models.EmailVerificationToken, *models.ResponseError)
+
+	QueryUseEmailVerificationToken(verificationTokenId string) *models.ResponseError
+}
@@ -0,0 +1,157 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+)
+
+type ErasureRequestsRepository struct {
+	db DBTX
+}
+
+func NewErasureRequestsRepository(db DBTX) *ErasureRequestsRepository {
+	return &ErasureRequestsRepository{
+		db: db,
+	}
+}
+
+// QueryCreateErasureRequest creates a pending erasure request. If the user has a pending request already, that one is returned
+func (erq *ErasureRequestsRepository) QueryCreateErasureRequest(userId string, requestedBy string) (*models.ErasureRequest, *models.ResponseError) {
+	query := `
+		WITH created_request AS (
+			INSERT INTO
+				erasure_requests(user_id, requested_by)
+			VALUES
+				($1, $2)
+			ON CONFLICT (user_id) WHERE completed_at IS NULL DO NOTHING
+			RETURNING
+				id, user_id, requested_by, created_at, completed_at
+		)
+		SELECT
+			id, user_id, requested_by, created_at, completed_at
+		FROM
+			created_request
+		UNION ALL
+		SELECT
+			id, user_id, requested_by, created_at, completed_at
+		FROM
+			erasure_requests
+		WHERE
+			user_id = $1
+			AND
+			completed_at IS NULL
+		LIMIT 1`
+	row := erq.db.QueryRow(query, userId, requestedBy)
+
+	var erasureRequest models.ErasureRequest
+	err := row.Scan(&erasureRequest.ID, &erasureRequest.UserId, &erasureRequest.RequestedBy, &erasureRequest.CreatedAt, &erasureRequest.CompletedAt)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &erasureRequest, nil
+}
+
+func (erq *ErasureRequestsRepository) QueryGetUserErasureRequests(userId string) ([]*models.ErasureRequest, *models.ResponseError) {
+	query := `
+		SELECT
+			id, user_id, requested_by, created_at, completed_at
+		FROM
+			erasure_requests
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at, id`
+	rows, err := erq.db.Query(query, userId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	erasureRequestsList := make([]*models.ErasureRequest, 0)
+
+	for rows.Next() {
+		var erasureRequest models.ErasureRequest
+		err = rows.Scan(&erasureRequest.ID, &erasureRequest.UserId, &erasureRequest.RequestedBy, &erasureRequest.CreatedAt, &erasureRequest.CompletedAt)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		erasureRequestsList = append(erasureRequestsList, &erasureRequest)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return erasureRequestsList, nil
+}
+
+// QueryGetNextPendingErasureRequest locks the oldest pending request. Requests locked by other instances of the app are skipped
+func (erq *ErasureRequestsRepository) QueryGetNextPendingErasureRequest() (*models.ErasureRequest, *models.ResponseError) {
+	query := `
+		SELECT
+			id, user_id, requested_by, created_at, completed_at
+		FROM
+			erasure_requests
+		WHERE
+			completed_at IS NULL
+		ORDER BY
+			created_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED`
+	row := erq.db.QueryRow(query)
+
+	var erasureRequest models.ErasureRequest
+	err := row.Scan(&erasureRequest.ID, &erasureRequest.UserId, &erasureRequest.RequestedBy, &erasureRequest.CreatedAt, &erasureRequest.CompletedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &erasureRequest, nil
+}
+
+func (erq *ErasureRequestsRepository) QueryCompleteErasureRequest(erasureRequestId string) *models.ResponseError {
+	query := `
+		UPDATE
+			erasure_requests
+		SET
+			completed_at = now()
+		WHERE
+			id = $1`
+	_, err := erq.db.Exec(query, erasureRequestId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+var _ ErasureRequestsRepositoryInterface = (*ErasureRequestsRepository)(nil)
@@ -0,0 +1,13 @@
+package repositories
+
+import "eventom-backend/models"
+
+type ErasureRequestsRepositoryInterface interface {
+	QueryCreateErasureRequest(userId string, requestedBy string) (*models.ErasureRequest, *models.ResponseError)
+
+	QueryGetUserErasureRequests(userId string) ([]*models.ErasureRequest, *models.ResponseError)
+
+	QueryGetNextPendingErasureRequest() (*models.ErasureRequest, *models.ResponseError)
+
+	QueryCompleteErasureRequest(erasureRequestId string) *models.ResponseError
+}
@@ -0,0 +1,133 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// exceptionDateLayout is used to store the exception dates of a series in a date array
+const exceptionDateLayout = "2006-01-02"
+
+type EventSeriesRepository struct {
+	db DBTX
+}
+
+func NewEventSeriesRepository(db DBTX) *EventSeriesRepository {
+	return &EventSeriesRepository{
+		db: db,
+	}
+}
+
+func (esr *EventSeriesRepository) QueryCreateEventSeries(series *models.EventSeries) (*models.EventSeries, *models.ResponseError) {
+	query := `
+		INSERT INTO
+			event_series(user_id, recurrence_rule, start_date, timezone, exception_dates)
+		VALUES
+			($1, $2, $3, $4, $5)
+		RETURNING
+			id, user_id, recurrence_rule, start_date, timezone, exception_dates, created_at`
+
+	exceptionDates := make([]string, 0, len(series.ExceptionDates))
+	for _, exceptionDate := range series.ExceptionDates {
+		exceptionDates = append(exceptionDates, exceptionDate.Format(exceptionDateLayout))
+	}
+
+	row := esr.db.QueryRow(query, series.UserId, series.RecurrenceRule, series.StartDate, series.Timezone, pq.Array(exceptionDates))
+
+	return scanEventSeries(row)
+}
+
+func (esr *EventSeriesRepository) QueryGetEventSeries(seriesId string) (*models.EventSeries, *models.ResponseError) {
+	query := `
+		SELECT
+			id, user_id, recurrence_rule, start_date, timezone, exception_dates, created_at
+		FROM
+			event_series
+		WHERE
+			id = $1`
+	row := esr.db.QueryRow(query, seriesId)
+
+	return scanEventSeries(row)
+}
+
+// QueryGetUserEventSeries returns all series created by the user
+func (esr *EventSeriesRepository) QueryGetUserEventSeries(userId string) ([]*models.EventSeries, *models.ResponseError) {
+	query := `
+		SELECT
+			id, user_id, recurrence_rule, start_date, timezone, exception_dates, created_at
+		FROM
+			event_series
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at, id`
+	rows, err := esr.db.Query(query, userId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	seriesList := make([]*models.EventSeries, 0)
+
+	for rows.Next() {
+		series, responseErr := scanEventSeries(rows)
+		if responseErr != nil {
+			return nil, responseErr
+		}
+		seriesList = append(seriesList, series)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return seriesList, nil
+}
+
+func scanEventSeries(row interface{ Scan(dest ...any) error }) (*models.EventSeries, *models.ResponseError) {
+	var series models.EventSeries
+	var exceptionDates []string
+	err := row.Scan(&series.ID, &series.UserId, &series.RecurrenceRule, &series.StartDate, &series.Timezone, pq.Array(&exceptionDates), &series.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Event series not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	series.ExceptionDates = make([]time.Time, 0, len(exceptionDates))
+	for _, exceptionDate := range exceptionDates {
+		date, err := time.Parse(exceptionDateLayout, exceptionDate)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		series.ExceptionDates = append(series.ExceptionDates, date)
+	}
+
+	return &series, nil
+}
+
+var _ EventSeriesRepositoryInterface = (*EventSeriesRepository)(nil)
@@ -0,0 +1,11 @@
+package repositories
+
+import "eventom-backend/models"
+
+type EventSeriesRepositoryInterface interface {
+	QueryCreateEventSeries(series *models.EventSeries) (*models.EventSeries, *models.ResponseError)
+
+	QueryGetEventSeries(seriesId string) (*models.EventSeries, *models.ResponseError)
+
+	QueryGetUserEventSeries(userId string) ([]*models.EventSeries, *models.ResponseError)
+}
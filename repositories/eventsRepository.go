@@ -4,11 +4,136 @@ import (
 	"database/sql"
 	"eventom-backend/dtos"
 	"eventom-backend/models"
+	"eventom-backend/utils"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
+
+	"github.com/lib/pq"
+)
+
+// eventColumns are qualified with the table name, so they can be used in joins as well. Tombstones of events of deleted users have no owner
+const eventColumns = `events.id, events.event_name, events.event_description, events.event_location, events.category, events.tags,
+	events.event_start, events.event_end, events.event_timezone, events.event_language, events.max_capacity, events.amount_registrations, COALESCE(events.user_id::text, ''), events.deleted_at, events.deletion_reason, events.series_id,
+	events.venue_id, events.updated_at, events.revision`
+
+// eventFields returns the scan destinations that match eventColumns
+func eventFields(event *models.Event) []any {
+	return []any{
+		&event.ID,
+		&event.Name,
+		&event.Description,
+		&event.Location,
+		&event.Category,
+		pq.Array(&event.Tags),
+		&event.StartsAt,
+		&event.EndsAt,
+		&event.Timezone,
+		&event.Language,
+		&event.MaxCapacity,
+		&event.AmountRegistration,
+		&event.UserId,
+		&event.DeletedAt,
+		&event.DeletionReason,
+		&event.SeriesId,
+		&event.VenueId,
+		&event.UpdatedAt,
+		&event.Revision,
+	}
+}
+
+// venueForeignKey is the name of the constraint that is violated if an event is linked to a venue that does not exist
+const venueForeignKey = "events_venue_id_fkey"
+
+// eventSearchConfiguration is the text search configuration of the search_vector column of an event, search queries have to use the
+// same one so the words are stemmed alike
+const eventSearchConfiguration = "text_search_configuration(events.event_language)"
+
+// eventSearchCondition matches the events with the query of utils.BuildSearchQuery in the parameter search and the queries of
+// utils.BuildPrefixSearchQuery in the parameters words and prefix. The words are stemmed in the language of the event and the last one
+// also matches as prefix of the unstemmed words in prefix_vector, so festiva already finds festival whose stem is festiv.
+// All events match an empty search
+func eventSearchCondition(search string, words string, prefix string) string {
+	return fmt.Sprintf(`(
+				%[1]s = ''
+				OR
+				events.search_vector @@ to_tsquery(`+eventSearchConfiguration+`, %[1]s)
+				OR
+				(
+					events.prefix_vector @@ to_tsquery('simple', %[3]s)
+					AND
+					(%[2]s = '' OR events.search_vector @@ to_tsquery(`+eventSearchConfiguration+`, %[2]s))
+				)
+			)`, search, words, prefix)
+}
+
+// Options of ts_headline for the highlights of search results. Names are highlighted as a whole, descriptions are shortened to
+// the fragments around the matches
+var (
+	nameHeadlineOptions        = fmt.Sprintf(`StartSel="%s", StopSel="%s", HighlightAll=true`, utils.SearchHighlightStart, utils.SearchHighlightStop)
+	descriptionHeadlineOptions = fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxFragments=2, MaxWords=20, MinWords=5, FragmentDelimiter=" … "`,
+		utils.SearchHighlightStart, utils.SearchHighlightStop)
 )
 
+// eventFilterConditions select the events that match an EventFilterDto, the arguments are returned by eventFilterArgs.
+// Events match any of the categories and all of the tags
+var eventFilterConditions = `
+			deleted_at IS NULL
+			AND
+			` + eventSearchCondition("$1", "$11", "$12") + `
+			AND
+			(event_location = $2 OR $2 = '')
+			AND
+			((((max_capacity - amount_registrations) >= $3) AND $3 != 0) OR $3 = 0)
+			AND
+			(event_end > $4 OR $4 IS NULL)
+			AND
+			(event_start < $5 OR $5 IS NULL)
+			AND
+			(category = ANY($6::text[]) OR COALESCE(cardinality($6::text[]), 0) = 0)
+			AND
+			(tags @> $7::text[] OR $7 IS NULL)
+			AND
+			(venue_distances.distance_km <= $10 OR $8 IS NULL)`
+
+// eventFilterSource joins the distance between the venue of every event and the point of an EventFilterDto in kilometers.
+// The distance is calculated with the haversine formula and is NULL if no point is given or the event has no venue
+const eventFilterSource = `
+			events
+		LEFT JOIN LATERAL (
+			SELECT
+				2 * 6371 * asin(least(1, sqrt(
+					power(sin(radians(venues.latitude - $8) / 2), 2) +
+					cos(radians($8)) * cos(radians(venues.latitude)) * power(sin(radians(venues.longitude - $9) / 2), 2)
+				))) AS distance_km
+			FROM
+				venues
+			WHERE
+				venues.id = events.venue_id
+				AND
+				$8 IS NOT NULL
+		) AS venue_distances ON true`
+
+func eventFilterArgs(eventFilters *dtos.EventFilterDto) []any {
+	searchWords, searchPrefix := utils.BuildPrefixSearchQuery(eventFilters.Search)
+
+	return []any{
+		utils.BuildSearchQuery(eventFilters.Search),
+		eventFilters.Location,
+		eventFilters.FreeCapacity,
+		eventFilters.From,
+		eventFilters.To,
+		pq.Array(eventFilters.Categories),
+		pq.Array(eventFilters.Tags),
+		eventFilters.NearLatitude,
+		eventFilters.NearLongitude,
+		eventFilters.RadiusKm,
+		searchWords,
+		searchPrefix,
+	}
+}
+
 type EventsRepository struct {
 	db DBTX
 }
@@ -22,17 +147,25 @@ func NewEventsRepository(db DBTX) *EventsRepository {
 func (er *EventsRepository) QueryCreateEvent(event *models.Event) (*models.Event, *models.ResponseError) {
 	query := `
 		INSERT INTO
-			events(event_name, event_description, event_location, event_date, max_capacity, user_id)
+			events(event_name, event_description, event_location, category, tags, event_start, event_end, event_timezone, max_capacity, user_id, series_id, venue_id, event_language)
 		VALUES
-			($1, $2, $3, $4, $5, $6)
+			($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
 		RETURNING
-			id`
-	row := er.db.QueryRow(query, event.Name, event.Description, event.Location, event.Date, event.MaxCapacity, event.UserId)
+			id, updated_at`
+	row := er.db.QueryRow(query, event.Name, event.Description, event.Location, event.Category, pq.Array(event.Tags), event.StartsAt, event.EndsAt, event.Timezone,
+		event.MaxCapacity, event.UserId, event.SeriesId, event.VenueId, event.Language)
 
 	var eventId string
-	err := row.Scan(&eventId)
+	var updatedAt time.Time
+	err := row.Scan(&eventId, &updatedAt)
 
 	if err != nil {
+		if strings.Contains(err.Error(), venueForeignKey) {
+			return nil, &models.ResponseError{
+				Message: "Venue not found",
+				Status:  http.StatusBadRequest,
+			}
+		}
 		return nil, &models.ResponseError{
 			Message: err.Error(),
 			Status:  http.StatusInternalServerError,
@@ -44,23 +177,34 @@ func (er *EventsRepository) QueryCreateEvent(event *models.Event) (*models.Event
 		Name:        event.Name,
 		Description: event.Description,
 		Location:    event.Location,
-		Date:        event.Date,
+		Category:    event.Category,
+		Tags:        event.Tags,
+		StartsAt:    event.StartsAt,
+		EndsAt:      event.EndsAt,
+		Timezone:    event.Timezone,
+		Language:    event.Language,
+		MaxCapacity: event.MaxCapacity,
 		UserId:      event.UserId,
+		SeriesId:    event.SeriesId,
+		VenueId:     event.VenueId,
+		UpdatedAt:   updatedAt,
 	}, nil
 }
 
 func (er *EventsRepository) QueryGetEvent(eventId string) (*models.Event, *models.ResponseError) {
 	query := `
 		SELECT
-			*
+			` + eventColumns + `
 		FROM
 			events
 		WHERE
-			id = $1`
+			id = $1
+			AND
+			deleted_at IS NULL`
 	row := er.db.QueryRow(query, eventId)
 
 	var event models.Event
-	err := row.Scan(&event.ID, &event.Name, &event.Description, &event.Location, &event.Date, &event.MaxCapacity, &event.AmountRegistration, &event.UserId)
+	err := row.Scan(eventFields(&event)...)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -79,27 +223,36 @@ func (er *EventsRepository) QueryGetEvent(eventId string) (*models.Event, *model
 }
 
 func (er *EventsRepository) QueryGetAllEvents(eventFilters *dtos.EventFilterDto) ([]*models.Event, int, *models.ResponseError) {
+	sortColumn := eventFilters.SortColumn
+	switch sortColumn {
+	case "distance":
+		sortColumn = "venue_distances.distance_km"
+	case "relevance":
+		// matches in the name weigh more than matches in the description and the location, words that are still typed are ranked by prefix
+		sortColumn = "ts_rank(search_vector, to_tsquery(" + eventSearchConfiguration + ", $1)) + ts_rank(prefix_vector, to_tsquery('simple', $12))"
+	}
+
 	// TODO: checkout squirrel for conditional query building on runtime so the query only has the parts it needs to run. That might improve caching performance
 	query := fmt.Sprintf(`
 		SELECT
 			COUNT(*) OVER(),
-			*
+			`+eventColumns+`,
+			venue_distances.distance_km,
+			CASE WHEN $1 = '' THEN NULL ELSE ts_headline(`+eventSearchConfiguration+`, event_name, to_tsquery(`+eventSearchConfiguration+`, $1), $15) END,
+			CASE WHEN $1 = '' THEN NULL ELSE ts_headline(`+eventSearchConfiguration+`, event_description, to_tsquery(`+eventSearchConfiguration+`, $1), $16) END
 		FROM
-			events
+			`+eventFilterSource+`
 		WHERE
-			(to_tsvector('simple', event_name) @@ plainto_tsquery('simple', $1) OR $1 = '')
-			AND
-			(event_location = $2 OR $2 = '')
-			AND
-			((((max_capacity - amount_registrations) >= $3) AND $3 != 0) OR $3 = 0)
+			`+eventFilterConditions+`
 		ORDER BY
 			%s %s, id ASC
 		LIMIT
-			$4
+			$13
 		OFFSET
-			$5`, eventFilters.SortColumn, eventFilters.SortOrder)
+			$14`, sortColumn, eventFilters.SortOrder)
 	offset := eventFilters.PageSize * (eventFilters.Page - 1)
-	rows, err := er.db.Query(query, eventFilters.Name, eventFilters.Location, eventFilters.FreeCapacity, eventFilters.PageSize, offset)
+	// ts_headline is expensive, postgres only runs it for the rows of the page because it is not used for sorting
+	rows, err := er.db.Query(query, append(eventFilterArgs(eventFilters), eventFilters.PageSize, offset, nameHeadlineOptions, descriptionHeadlineOptions)...)
 
 	if err != nil {
 		return nil, 0, &models.ResponseError{
@@ -112,29 +265,24 @@ func (er *EventsRepository) QueryGetAllEvents(eventFilters *dtos.EventFilterDto)
 
 	totalcount := 0
 	eventsList := make([]*models.Event, 0)
-	var eventId, name, description, location, userId string
-	var maxCapacity, amountRegistrations int
-	var date time.Time
 
 	for rows.Next() {
-		err = rows.Scan(&totalcount, &eventId, &name, &description, &location, &date, &maxCapacity, &amountRegistrations, &userId)
+		var event models.Event
+		var nameHighlight, descriptionHighlight sql.NullString
+		err = rows.Scan(append(append([]any{&totalcount}, eventFields(&event)...), &event.DistanceKm, &nameHighlight, &descriptionHighlight)...)
 		if err != nil {
 			return nil, 0, &models.ResponseError{
 				Message: err.Error(),
 				Status:  http.StatusInternalServerError,
 			}
 		}
-		event := &models.Event{
-			ID:                 eventId,
-			Name:               name,
-			Description:        description,
-			Location:           location,
-			Date:               date,
-			MaxCapacity:        maxCapacity,
-			AmountRegistration: amountRegistrations,
-			UserId:             userId,
+		if nameHighlight.Valid {
+			event.Highlights = &models.EventHighlights{
+				Name:        utils.HighlightHtml(nameHighlight.String),
+				Description: utils.HighlightHtml(descriptionHighlight.String),
+			}
 		}
-		eventsList = append(eventsList, event)
+		eventsList = append(eventsList, &event)
 	}
 
 	err = rows.Err()
@@ -148,6 +296,89 @@ func (er *EventsRepository) QueryGetAllEvents(eventFilters *dtos.EventFilterDto)
 	return eventsList, totalcount, nil
 }
 
+// QueryGetEventFacets counts the events that match the filters per category and per tag. Only the most frequent values are returned
+func (er *EventsRepository) QueryGetEventFacets(eventFilters *dtos.EventFilterDto, maxValues int) (*dtos.EventFacets, *models.ResponseError) {
+	query := `
+		WITH filtered_events AS (
+			SELECT
+				category, tags
+			FROM
+				` + eventFilterSource + `
+			WHERE
+				` + eventFilterConditions + `
+		), facets AS (
+			SELECT
+				'category' AS facet, category AS value, COUNT(*) AS amount
+			FROM
+				filtered_events
+			WHERE
+				category != ''
+			GROUP BY
+				category
+			UNION ALL
+			SELECT
+				'tag' AS facet, tag AS value, COUNT(*) AS amount
+			FROM
+				filtered_events, unnest(tags) AS tag
+			GROUP BY
+				tag
+		)
+		SELECT
+			facet, value, amount
+		FROM (
+			SELECT
+				facet, value, amount, row_number() OVER (PARTITION BY facet ORDER BY amount DESC, value ASC) AS position
+			FROM
+				facets
+		) AS ranked_facets
+		WHERE
+			position <= $13
+		ORDER BY
+			facet, position`
+	rows, err := er.db.Query(query, append(eventFilterArgs(eventFilters), maxValues)...)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	eventFacets := &dtos.EventFacets{
+		Categories: make([]*dtos.FacetCount, 0),
+		Tags:       make([]*dtos.FacetCount, 0),
+	}
+
+	for rows.Next() {
+		var facet string
+		var facetCount dtos.FacetCount
+		err = rows.Scan(&facet, &facetCount.Value, &facetCount.Count)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		if facet == "category" {
+			eventFacets.Categories = append(eventFacets.Categories, &facetCount)
+		} else {
+			eventFacets.Tags = append(eventFacets.Tags, &facetCount)
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return eventFacets, nil
+}
+
 func (er *EventsRepository) QueryUpdateEvent(event *models.Event) (*models.Event, *models.ResponseError) {
 	query := `
 		UPDATE
@@ -156,26 +387,35 @@ func (er *EventsRepository) QueryUpdateEvent(event *models.Event) (*models.Event
 			event_name = $1,
 			event_description = $2,
 			event_location = $3,
-			event_date = $4
+			category = $4,
+			tags = $5,
+			event_start = $6,
+			event_end = $7,
+			event_timezone = $8,
+			max_capacity = $9,
+			venue_id = $10,
+			event_language = $11,
+			updated_at = now(),
+			revision = revision + 1
 		WHERE
-			id = $5
+			id = $12
+			AND
+			deleted_at IS NULL
 		RETURNING
-			*`
-	row := er.db.QueryRow(query, event.Name, event.Description, event.Location, event.Date, event.ID)
+			` + eventColumns
+	row := er.db.QueryRow(query, event.Name, event.Description, event.Location, event.Category, pq.Array(event.Tags), event.StartsAt, event.EndsAt, event.Timezone,
+		event.MaxCapacity, event.VenueId, event.Language, event.ID)
 
 	var updatedEvent models.Event
-	err := row.Scan(
-		&updatedEvent.ID,
-		&updatedEvent.Name,
-		&updatedEvent.Description,
-		&updatedEvent.Location,
-		&updatedEvent.Date,
-		&updatedEvent.MaxCapacity,
-		&updatedEvent.AmountRegistration,
-		&updatedEvent.UserId,
-	)
+	err := row.Scan(eventFields(&updatedEvent)...)
 
 	if err != nil {
+		if strings.Contains(err.Error(), venueForeignKey) {
+			return nil, &models.ResponseError{
+				Message: "Venue not found",
+				Status:  http.StatusBadRequest,
+			}
+		}
 		if err == sql.ErrNoRows {
 			return nil, &models.ResponseError{
 				Message: "Event not found",
@@ -199,13 +439,22 @@ func (er *EventsRepository) QueryIncrementAmountRegistrations(eventId string) (*
 			amount_registrations = amount_registrations + 1
 		WHERE
 			id = $1
-		RETURNING *`
+			AND
+			deleted_at IS NULL
+		RETURNING
+			` + eventColumns
 	row := er.db.QueryRow(query, eventId)
 
 	var event models.Event
-	err := row.Scan(&event.ID, &event.Name, &event.Description, &event.Location, &event.Date, &event.MaxCapacity, &event.AmountRegistration, &event.UserId)
+	err := row.Scan(eventFields(&event)...)
 
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Event not found",
+				Status:  http.StatusNotFound,
+			}
+		}
 		return nil, &models.ResponseError{
 			Message: err.Error(),
 			Status:  http.StatusInternalServerError,
@@ -213,7 +462,59 @@ func (er *EventsRepository) QueryIncrementAmountRegistrations(eventId string) (*
 	}
 
 	return &event, nil
+}
 
+// QueryGetEventForUpdate locks the event until the end of the transaction, so the seats of the event cannot change in the meantime
+func (er *EventsRepository) QueryGetEventForUpdate(eventId string) (*models.Event, *models.ResponseError) {
+	query := `
+		SELECT
+			` + eventColumns + `
+		FROM
+			events
+		WHERE
+			id = $1
+			AND
+			deleted_at IS NULL
+		FOR UPDATE`
+	row := er.db.QueryRow(query, eventId)
+
+	var event models.Event
+	err := row.Scan(eventFields(&event)...)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Event not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &event, nil
+}
+
+func (er *EventsRepository) QueryDecrementAmountRegistrations(eventId string) *models.ResponseError {
+	query := `
+		UPDATE
+			events
+		SET
+			amount_registrations = amount_registrations - 1
+		WHERE
+			id = $1`
+	_, err := er.db.Exec(query, eventId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
 }
 
 func (er *EventsRepository) QueryDeleteEvent(eventId string) *models.ResponseError {
@@ -234,4 +535,211 @@ func (er *EventsRepository) QueryDeleteEvent(eventId string) *models.ResponseErr
 	return nil
 }
 
+// QuerySoftDeleteEvent keeps the event as tombstone. It does not show up anywhere else, but the history of its registrations stays intact
+func (er *EventsRepository) QuerySoftDeleteEvent(eventId string, reason string) (*models.Event, *models.ResponseError) {
+	query := `
+		UPDATE
+			events
+		SET
+			deleted_at = now(),
+			deletion_reason = $2,
+			amount_registrations = 0,
+			updated_at = now(),
+			revision = revision + 1
+		WHERE
+			id = $1
+			AND
+			deleted_at IS NULL
+		RETURNING
+			` + eventColumns
+	row := er.db.QueryRow(query, eventId, reason)
+
+	var event models.Event
+	err := row.Scan(eventFields(&event)...)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Event not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &event, nil
+}
+
+// QueryGetUserEvents returns all events created by the user including deleted ones
+func (er *EventsRepository) QueryGetUserEvents(userId string) ([]*models.Event, *models.ResponseError) {
+	query := `
+		SELECT
+			` + eventColumns + `
+		FROM
+			events
+		WHERE
+			user_id = $1
+		ORDER BY
+			event_start, id`
+	rows, err := er.db.Query(query, userId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	eventsList := make([]*models.Event, 0)
+
+	for rows.Next() {
+		var event models.Event
+		err = rows.Scan(eventFields(&event)...)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		eventsList = append(eventsList, &event)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return eventsList, nil
+}
+
+// QueryGetSeriesOccurrences returns the occurrences of the series on or after the given date
+func (er *EventsRepository) QueryGetSeriesOccurrences(seriesId string, from time.Time) ([]*models.Event, *models.ResponseError) {
+	query := `
+		SELECT
+			` + eventColumns + `
+		FROM
+			events
+		WHERE
+			series_id = $1
+			AND
+			event_start >= $2
+			AND
+			deleted_at IS NULL
+		ORDER BY
+			event_start, id`
+
+	return er.queryEvents(query, seriesId, from)
+}
+
+// QueryGetSeriesOccurrencesForUpdate locks the occurrences of the series on or after the given date, see QueryGetEventForUpdate
+func (er *EventsRepository) QueryGetSeriesOccurrencesForUpdate(seriesId string, from time.Time) ([]*models.Event, *models.ResponseError) {
+	query := `
+		SELECT
+			` + eventColumns + `
+		FROM
+			events
+		WHERE
+			series_id = $1
+			AND
+			event_start >= $2
+			AND
+			deleted_at IS NULL
+		ORDER BY
+			event_start, id
+		FOR UPDATE`
+
+	return er.queryEvents(query, seriesId, from)
+}
+
+func (er *EventsRepository) queryEvents(query string, args ...any) ([]*models.Event, *models.ResponseError) {
+	rows, err := er.db.Query(query, args...)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	eventsList := make([]*models.Event, 0)
+
+	for rows.Next() {
+		var event models.Event
+		err = rows.Scan(eventFields(&event)...)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		eventsList = append(eventsList, &event)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return eventsList, nil
+}
+
+// QueryGetUserCalendarEvents returns the events the user is registered for together with the deleted events the user was registered for
+func (er *EventsRepository) QueryGetUserCalendarEvents(userId string) ([]*models.Event, *models.ResponseError) {
+	query := `
+		SELECT
+			` + eventColumns + `
+		FROM
+			events
+		WHERE
+			(
+				deleted_at IS NULL
+				AND
+				id IN (SELECT event_id FROM registrations WHERE user_id = $1)
+			)
+			OR
+			(
+				deleted_at IS NOT NULL
+				AND
+				id IN (SELECT event_id FROM cancelled_registrations WHERE user_id = $1 AND reason = $2)
+			)
+		ORDER BY
+			event_start, id`
+
+	return er.queryEvents(query, userId, models.CancellationReasonEventDeleted)
+}
+
+// QueryDecrementAmountRegistrationsOfUser frees the seats of all registrations of the given user
+func (er *EventsRepository) QueryDecrementAmountRegistrationsOfUser(userId string) *models.ResponseError {
+	query := `
+		UPDATE
+			events
+		SET
+			amount_registrations = amount_registrations - 1
+		WHERE
+			id IN (SELECT event_id FROM registrations WHERE user_id = $1)`
+	_, err := er.db.Exec(query, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
 var _ EventsRepositoryInterface = (*EventsRepository)(nil)
@@ -3,6 +3,7 @@ package repositories
 import (
 	"eventom-backend/dtos"
 	"eventom-backend/models"
+	"time"
 )
 
 type EventsRepositoryInterface interface {
@@ -12,9 +13,27 @@ type EventsRepositoryInterface interface {
 
 	QueryGetAllEvents(eventFilters *dtos.EventFilterDto) ([]*models.Event, int, *models.ResponseError)
 
+	QueryGetEventFacets(eventFilters *dtos.EventFilterDto, maxValues int) (*dtos.EventFacets, *models.ResponseError)
+
 	QueryUpdateEvent(event *models.Event) (*models.Event, *models.ResponseError)
 
+	QueryGetEventForUpdate(eventId string) (*models.Event, *models.ResponseError)
+
 	QueryIncrementAmountRegistrations(eventId string) (*models.Event, *models.ResponseError)
 
+	QueryDecrementAmountRegistrations(eventId string) *models.ResponseError
+
 	QueryDeleteEvent(eventId string) *models.ResponseError
+
+	QuerySoftDeleteEvent(eventId string, reason string) (*models.Event, *models.ResponseError)
+
+	QueryGetUserEvents(userId string) ([]*models.Event, *models.ResponseError)
+
+	QueryGetSeriesOccurrences(seriesId string, from time.Time) ([]*models.Event, *models.ResponseError)
+
+	QueryGetSeriesOccurrencesForUpdate(seriesId string, from time.Time) ([]*models.Event, *models.ResponseError)
+
+	QueryGetUserCalendarEvents(userId string) ([]*models.Event, *models.ResponseError)
+
+	QueryDecrementAmountRegistrationsOfUser(userId string) *models.ResponseError
 }
@@ -0,0 +1,248 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+	"time"
+)
+
+type LoginAttemptsRepository struct {
+	db DBTX
+}
+
+func NewLoginAttemptsRepository(db DBTX) *LoginAttemptsRepository {
+	return &LoginAttemptsRepository{
+		db: db,
+	}
+}
+
+func (lar *LoginAttemptsRepository) QueryGetLoginAttempt(scope string, subject string) (*models.LoginAttempt, *models.ResponseError) {
+	query := `
+		SELECT
+			scope, subject, failed_attempts, last_failed_at, locked_until
+		FROM
+			login_attempts
+		WHERE
+			scope = $1
+			AND
+			subject = $2`
+	row := lar.db.QueryRow(query, scope, subject)
+
+	var loginAttempt models.LoginAttempt
+	err := row.Scan(&loginAttempt.Scope, &loginAttempt.Subject, &loginAttempt.FailedAttempts, &loginAttempt.LastFailedAt, &loginAttempt.LockedUntil)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &loginAttempt, nil
+}
+
+// QueryReserveLoginAttempt counts an attempt as failed before the credentials are checked, the row lock of the upsert makes parallel
+// attempts wait for each other. No attempt is reserved and false is returned while the subject is locked, has maxAttempts attempts or
+// the delay after the last attempt has not passed, the delay doubles with every attempt up to maxDelay. The counter starts over if the
+// last attempt is older than resetAfter
+func (lar *LoginAttemptsRepository) QueryReserveLoginAttempt(scope string, subject string, maxAttempts int, maxDelay time.Duration, resetAfter time.Duration) (bool, *models.ResponseError) {
+	query := `
+		INSERT INTO
+			login_attempts(scope, subject, failed_attempts, last_failed_at)
+		VALUES
+			($1, $2, 1, now())
+		ON CONFLICT (scope, subject) DO UPDATE SET
+			failed_attempts = CASE
+				WHEN login_attempts.last_failed_at < now() - $3 * interval '1 second' THEN 1
+				ELSE login_attempts.failed_attempts + 1
+			END,
+			last_failed_at = now()
+		WHERE
+			(login_attempts.locked_until IS NULL OR login_attempts.locked_until <= now())
+			AND
+			(
+				login_attempts.last_failed_at < now() - $3 * interval '1 second'
+				OR
+				(
+					login_attempts.failed_attempts < $4
+					AND
+					login_attempts.last_failed_at + least(power(2, login_attempts.failed_attempts - 1), $5) * interval '1 second' <= now()
+				)
+			)
+		RETURNING
+			failed_attempts`
+	row := lar.db.QueryRow(query, scope, subject, int(resetAfter.Seconds()), maxAttempts, int(maxDelay.Seconds()))
+
+	var failedAttempts int
+	err := row.Scan(&failedAttempts)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return true, nil
+}
+
+// QueryReleaseLoginAttempt takes back an attempt reserved by QueryReserveLoginAttempt that turned out to be successful
+func (lar *LoginAttemptsRepository) QueryReleaseLoginAttempt(scope string, subject string) *models.ResponseError {
+	query := `
+		UPDATE
+			login_attempts
+		SET
+			failed_attempts = GREATEST(failed_attempts - 1, 0)
+		WHERE
+			scope = $1
+			AND
+			subject = $2`
+	_, err := lar.db.Exec(query, scope, subject)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// QueryLockLogin locks the subject if it is not locked already, the result tells whether it has been locked by this call
+func (lar *LoginAttemptsRepository) QueryLockLogin(scope string, subject string, lockedUntil time.Time) (bool, *models.ResponseError) {
+	query := `
+		UPDATE
+			login_attempts
+		SET
+			locked_until = $3
+		WHERE
+			scope = $1
+			AND
+			subject = $2
+			AND
+			(locked_until IS NULL OR locked_until <= now())`
+	result, err := lar.db.Exec(query, scope, subject, lockedUntil)
+
+	if err != nil {
+		return false, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return false, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return rowsAffected > 0, nil
+}
+
+func (lar *LoginAttemptsRepository) QueryResetLoginAttempts(scope string, subject string) *models.ResponseError {
+	query := `
+		DELETE FROM
+			login_attempts
+		WHERE
+			scope = $1
+			AND
+			subject = $2`
+	_, err := lar.db.Exec(query, scope, subject)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (lar *LoginAttemptsRepository) QueryCreateAccountUnlockToken(unlockToken *models.AccountUnlockToken) *models.ResponseError {
+	query := `
+		INSERT INTO
+			account_unlock_tokens(user_id, token_hash, expires_at)
+		VALUES
+			($1, $2, $3)`
+	_, err := lar.db.Exec(query, unlockToken.UserId, unlockToken.TokenHash, unlockToken.ExpiresAt)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (lar *LoginAttemptsRepository) QueryGetAccountUnlockToken(tokenHash string) (*models.AccountUnlockToken, *models.ResponseError) {
+	query := `
+		SELECT
+			id, user_id, token_hash, expires_at, created_at, used_at
+		FROM
+			account_unlock_tokens
+		WHERE
+			token_hash = $1
+		FOR UPDATE`
+	row := lar.db.QueryRow(query, tokenHash)
+
+	var unlockToken models.AccountUnlockToken
+	err := row.Scan(
+		&unlockToken.ID,
+		&unlockToken.UserId,
+		&unlockToken.TokenHash,
+		&unlockToken.ExpiresAt,
+		&unlockToken.CreatedAt,
+		&unlockToken.UsedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Unlock token not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &unlockToken, nil
+}
+
+func (lar *LoginAttemptsRepository) QueryUseAccountUnlockToken(unlockTokenId string) *models.ResponseError {
+	query := `
+		UPDATE
+			account_unlock_tokens
+		SET
+			used_at = now()
+		WHERE
+			id = $1`
+	_, err := lar.db.Exec(query, unlockTokenId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+var _ LoginAttemptsRepositoryInterface = (*LoginAttemptsRepository)(nil)
@@ -0,0 +1,24 @@
+package repositories
+
+import (
+	"eventom-backend/models"
+	"time"
+)
+
+type LoginAttemptsRepositoryInterface interface {
+	QueryGetLoginAttempt(scope string, subject string) (*models.LoginAttempt, *models.ResponseError)
+
+	QueryReserveLoginAttempt(scope string, subject string, maxAttempts int, maxDelay time.Duration, resetAfter time.Duration) (bool, *models.ResponseError)
+
+	QueryReleaseLoginAttempt(scope string, subject string) *models.ResponseError
+
+	QueryLockLogin(scope string, subject string, lockedUntil time.Time) (bool, *models.ResponseError)
+
+	QueryResetLoginAttempts(scope string, subject string) *models.ResponseError
+
+	QueryCreateAccountUnlockToken(unlockToken *models.AccountUnlockToken) *models.ResponseError
+
+	QueryGetAccountUnlockToken(tokenHash string) (*models.AccountUnlockToken, *models.ResponseError)
+
+	QueryUseAccountUnlockToken(unlockTokenId string) *models.ResponseError
+}
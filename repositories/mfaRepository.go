@@ -0,0 +1,171 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+
+	"github.com/lib/pq"
+)
+
+type MfaRepository struct {
+	db DBTX
+}
+
+func NewMfaRepository(db DBTX) *MfaRepository {
+	return &MfaRepository{
+		db: db,
+	}
+}
+
+func (mr *MfaRepository) QueryGetUserTotp(userId string) (*models.UserTotp, *models.ResponseError) {
+	query := `
+		SELECT
+			user_id, secret, confirmed_at, last_used_step, created_at
+		FROM
+			user_totp
+		WHERE
+			user_id = $1`
+	row := mr.db.QueryRow(query, userId)
+
+	var userTotp models.UserTotp
+	err := row.Scan(&userTotp.UserId, &userTotp.Secret, &userTotp.ConfirmedAt, &userTotp.LastUsedStep, &userTotp.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Two-factor authentication is not set up",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &userTotp, nil
+}
+
+// QueryCreateUserTotp stores a new unconfirmed secret. A pending enrollment is replaced, a confirmed one is kept
+func (mr *MfaRepository) QueryCreateUserTotp(userId string, secret string) *models.ResponseError {
+	query := `
+		INSERT INTO
+			user_totp(user_id, secret)
+		VALUES
+			($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			secret = EXCLUDED.secret,
+			last_used_step = 0,
+			created_at = now()
+		WHERE
+			user_totp.confirmed_at IS NULL`
+
+	return mr.execSingleRow(query, "Two-factor authentication is already enabled", http.StatusConflict, userId, secret)
+}
+
+func (mr *MfaRepository) QueryConfirmUserTotp(userId string, step int64) *models.ResponseError {
+	query := `
+		UPDATE
+			user_totp
+		SET
+			confirmed_at = now(),
+			last_used_step = $2
+		WHERE
+			user_id = $1
+			AND
+			confirmed_at IS NULL`
+
+	return mr.execSingleRow(query, "Two-factor authentication is already enabled", http.StatusConflict, userId, step)
+}
+
+// QueryUseTotpStep remembers the time step of an accepted code, so the same code cannot be used twice
+func (mr *MfaRepository) QueryUseTotpStep(userId string, step int64) *models.ResponseError {
+	query := `
+		UPDATE
+			user_totp
+		SET
+			last_used_step = $2
+		WHERE
+			user_id = $1
+			AND
+			last_used_step < $2`
+
+	return mr.execSingleRow(query, "Code has already been used", http.StatusUnauthorized, userId, step)
+}
+
+func (mr *MfaRepository) QueryDeleteUserTotp(userId string) *models.ResponseError {
+	query := `
+		DELETE FROM
+			user_totp
+		WHERE
+			user_id = $1`
+
+	return mr.execSingleRow(query, "Two-factor authentication is not set up", http.StatusNotFound, userId)
+}
+
+func (mr *MfaRepository) QueryCreateRecoveryCodes(userId string, codeHashes []string) *models.ResponseError {
+	query := `
+		INSERT INTO
+			totp_recovery_codes(user_id, code_hash)
+		SELECT
+			$1, unnest($2::text[])`
+	_, err := mr.db.Exec(query, userId, pq.Array(codeHashes))
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (mr *MfaRepository) QueryUseRecoveryCode(userId string, codeHash string) *models.ResponseError {
+	query := `
+		UPDATE
+			totp_recovery_codes
+		SET
+			used_at = now()
+		WHERE
+			user_id = $1
+			AND
+			code_hash = $2
+			AND
+			used_at IS NULL`
+
+	return mr.execSingleRow(query, "Invalid code", http.StatusUnauthorized, userId, codeHash)
+}
+
+// execSingleRow executes the query and returns an error with the given message and status if no row has been affected
+func (mr *MfaRepository) execSingleRow(query string, message string, status int, args ...any) *models.ResponseError {
+	result, err := mr.db.Exec(query, args...)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: message,
+			Status:  status,
+		}
+	}
+
+	return nil
+}
+
+var _ MfaRepositoryInterface = (*MfaRepository)(nil)
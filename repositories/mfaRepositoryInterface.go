@@ -0,0 +1,19 @@
+package repositories
+
+import "eventom-backend/models"
+
+type MfaRepositoryInterface interface {
+	QueryGetUserTotp(userId string) (*models.UserTotp, *models.ResponseError)
+
+	QueryCreateUserTotp(userId string, secret string) *models.ResponseError
+
+	QueryConfirmUserTotp(userId string, step int64) *models.ResponseError
+
+	QueryUseTotpStep(userId string, step int64) *models.ResponseError
+
+	QueryDeleteUserTotp(userId string) *models.ResponseError
+
+	QueryCreateRecoveryCodes(userId string, codeHashes []string) *models.ResponseError
+
+	QueryUseRecoveryCode(userId string, codeHash string) *models.ResponseError
+}
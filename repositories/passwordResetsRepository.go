@@ -0,0 +1,97 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+)
+
+type PasswordResetsRepository struct {
+	db DBTX
+}
+
+func NewPasswordResetsRepository(db DBTX) *PasswordResetsRepository {
+	return &PasswordResetsRepository{
+		db: db,
+	}
+}
+
+func (prr *PasswordResetsRepository) QueryCreatePasswordResetToken(resetToken *models.PasswordResetToken) *models.ResponseError {
+	query := `
+		INSERT INTO
+			password_reset_tokens(user_id, token_hash, expires_at)
+		VALUES
+			($1, $2, $3)`
+	_, err := prr.db.Exec(query, resetToken.UserId, resetToken.TokenHash, resetToken.ExpiresAt)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (prr *PasswordResetsRepository) QueryGetPasswordResetToken(tokenHash string) (*models.PasswordResetToken, *models.ResponseError) {
+	query := `
+		SELECT
+			id, user_id, token_hash, expires_at, created_at, used_at
+		FROM
+			password_reset_tokens
+		WHERE
+			token_hash = $1
+		FOR UPDATE`
+	row := prr.db.QueryRow(query, tokenHash)
+
+	var resetToken models.PasswordResetToken
+	err := row.Scan(
+		&resetToken.ID,
+		&resetToken.UserId,
+		&resetToken.TokenHash,
+		&resetToken.ExpiresAt,
+		&resetToken.CreatedAt,
+		&resetToken.UsedAt,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Password reset token not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &resetToken, nil
+}
+
+// QueryUseUserPasswordResetTokens marks all outstanding reset tokens of the user as used, so none of them can be used after a successful reset
+func (prr *PasswordResetsRepository) QueryUseUserPasswordResetTokens(userId string) *models.ResponseError {
+	query := `
+		UPDATE
+			password_reset_tokens
+		SET
+			used_at = now()
+		WHERE
+			user_id = $1
+			AND
+			used_at IS NULL`
+	_, err := prr.db.Exec(query, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+var _ PasswordResetsRepositoryInterface = (*PasswordResetsRepository)(nil)
@@ -0,0 +1,11 @@
+package repositories
+
+import "eventom-backend/models"
+
+type PasswordResetsRepositoryInterface interface {
+	QueryCreatePasswordResetToken(resetToken *models.PasswordResetToken) *models.ResponseError
+
+	QueryGetPasswordResetToken(tokenHash string) (*models.PasswordResetToken, *models.ResponseError)
+
+	QueryUseUserPasswordResetTokens(userId string) *models.ResponseError
+}
@@ -0,0 +1,149 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+)
+
+type RefreshTokensRepository struct {
+	db DBTX
+}
+
+func NewRefreshTokensRepository(db DBTX) *RefreshTokensRepository {
+	return &RefreshTokensRepository{
+		db: db,
+	}
+}
+
+func (rtr *RefreshTokensRepository) QueryCreateRefreshToken(refreshToken *models.RefreshToken) (*models.RefreshToken, *models.ResponseError) {
+	query := `
+		INSERT INTO
+			refresh_tokens(user_id, family_id, token_hash, expires_at)
+		VALUES
+			($1, $2, $3, $4)
+		RETURNING
+			id, created_at`
+	row := rtr.db.QueryRow(query, refreshToken.UserId, refreshToken.FamilyId, refreshToken.TokenHash, refreshToken.ExpiresAt)
+
+	createdToken := *refreshToken
+	err := row.Scan(&createdToken.ID, &createdToken.CreatedAt)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &createdToken, nil
+}
+
+func (rtr *RefreshTokensRepository) QueryGetRefreshToken(tokenHash string) (*models.RefreshToken, *models.ResponseError) {
+	// lock the row so two concurrent refreshes with the same token cannot both rotate it
+	query := `
+		SELECT
+			id, user_id, family_id, token_hash, expires_at, created_at, revoked_at, replaced_by
+		FROM
+			refresh_tokens
+		WHERE
+			token_hash = $1
+		FOR UPDATE`
+	row := rtr.db.QueryRow(query, tokenHash)
+
+	var refreshToken models.RefreshToken
+	err := row.Scan(
+		&refreshToken.ID,
+		&refreshToken.UserId,
+		&refreshToken.FamilyId,
+		&refreshToken.TokenHash,
+		&refreshToken.ExpiresAt,
+		&refreshToken.CreatedAt,
+		&refreshToken.RevokedAt,
+		&refreshToken.ReplacedBy,
+	)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Refresh token not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &refreshToken, nil
+}
+
+func (rtr *RefreshTokensRepository) QueryRevokeRefreshToken(refreshTokenId string, replacedBy string) *models.ResponseError {
+	query := `
+		UPDATE
+			refresh_tokens
+		SET
+			revoked_at = now(),
+			replaced_by = NULLIF($2, '')::uuid
+		WHERE
+			id = $1
+			AND
+			revoked_at IS NULL`
+	_, err := rtr.db.Exec(query, refreshTokenId, replacedBy)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (rtr *RefreshTokensRepository) QueryRevokeRefreshTokenFamily(familyId string) *models.ResponseError {
+	query := `
+		UPDATE
+			refresh_tokens
+		SET
+			revoked_at = now()
+		WHERE
+			family_id = $1
+			AND
+			revoked_at IS NULL`
+	_, err := rtr.db.Exec(query, familyId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (rtr *RefreshTokensRepository) QueryRevokeUserRefreshTokens(userId string) *models.ResponseError {
+	query := `
+		UPDATE
+			refresh_tokens
+		SET
+			revoked_at = now()
+		WHERE
+			user_id = $1
+			AND
+			revoked_at IS NULL`
+	_, err := rtr.db.Exec(query, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+var _ RefreshTokensRepositoryInterface = (*RefreshTokensRepository)(nil)
@@ -0,0 +1,15 @@
+package repositories
+
+import "eventom-backend/models"
+
+type RefreshTokensRepositoryInterface interface {
+	QueryCreateRefreshToken(refreshToken *models.RefreshToken) (*models.RefreshToken, *models.ResponseError)
+
+	QueryGetRefreshToken(tokenHash string) (*models.RefreshToken, *models.ResponseError)
+
+	QueryRevokeRefreshToken(refreshTokenId string, replacedBy string) *models.ResponseError
+
+	QueryRevokeRefreshTokenFamily(familyId string) *models.ResponseError
+
+	QueryRevokeUserRefreshTokens(userId string) *models.ResponseError
+}
@@ -2,7 +2,10 @@ package repositories
 
 import (
 	"database/sql"
+	"eventom-backend/dtos"
 	"eventom-backend/models"
+	"eventom-backend/utils"
+	"fmt"
 	"net/http"
 	"strings"
 )
@@ -45,6 +48,68 @@ func (rr *RegistrationsRepository) QueryGetRegistration(eventId string, userId s
 	return &registration, nil
 }
 
+func (rr *RegistrationsRepository) QueryGetRegistrationById(registrationId string) (*models.Registration, *models.ResponseError) {
+	query := `
+		SELECT
+			id, event_id, user_id
+		FROM
+			registrations
+		WHERE
+			id = $1`
+	row := rr.db.QueryRow(query, registrationId)
+
+	var registration models.Registration
+	err := row.Scan(&registration.ID, &registration.EventId, &registration.UserId)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Registration not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &registration, nil
+}
+
+// QueryGetUserRegistrationById returns the registration with the given id only if it belongs to the user, registrations of other users
+// are not found
+func (rr *RegistrationsRepository) QueryGetUserRegistrationById(registrationId string, userId string) (*models.Registration, *models.ResponseError) {
+	query := `
+		SELECT
+			id, event_id, user_id
+		FROM
+			registrations
+		WHERE
+			id = $1
+			AND
+			user_id = $2`
+	row := rr.db.QueryRow(query, registrationId, userId)
+
+	var registration models.Registration
+	err := row.Scan(&registration.ID, &registration.EventId, &registration.UserId)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Registration not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &registration, nil
+}
+
 func (rr *RegistrationsRepository) QueryGetAllRegistrations() ([]*models.Registration, *models.ResponseError) {
 	query := `
 		SELECT
@@ -160,4 +225,396 @@ func (rr *RegistrationsRepository) QueryCancelRegistration(eventId string, userI
 	return &deletedRegistration, nil
 }
 
+// QueryCancelEventRegistrations deletes all registrations for the event and returns them, the seats have to be freed separately
+func (rr *RegistrationsRepository) QueryCancelEventRegistrations(eventId string) ([]*models.Registration, *models.ResponseError) {
+	query := `
+		DELETE FROM
+			registrations
+		WHERE
+			event_id = $1
+		RETURNING
+			id, event_id, user_id`
+	rows, err := rr.db.Query(query, eventId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	registrationsList := make([]*models.Registration, 0)
+
+	for rows.Next() {
+		var registration models.Registration
+		err = rows.Scan(&registration.ID, &registration.EventId, &registration.UserId)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		registrationsList = append(registrationsList, &registration)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return registrationsList, nil
+}
+
+// QueryCreateCancelledRegistration records why the registration has been cancelled
+func (rr *RegistrationsRepository) QueryCreateCancelledRegistration(registration *models.Registration, reason string) (*models.CancelledRegistration, *models.ResponseError) {
+	query := `
+		INSERT INTO
+			cancelled_registrations(id, event_id, user_id, reason)
+		VALUES
+			($1, $2, $3, $4)
+		RETURNING
+			id, event_id, user_id, reason, cancelled_at`
+	row := rr.db.QueryRow(query, registration.ID, registration.EventId, registration.UserId, reason)
+
+	var cancelledRegistration models.CancelledRegistration
+	err := row.Scan(
+		&cancelledRegistration.ID,
+		&cancelledRegistration.EventId,
+		&cancelledRegistration.UserId,
+		&cancelledRegistration.Reason,
+		&cancelledRegistration.CancelledAt,
+	)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &cancelledRegistration, nil
+}
+
+// QueryGetUserCancelledRegistrations returns the records of the registrations of the user that were cancelled by the system
+func (rr *RegistrationsRepository) QueryGetUserCancelledRegistrations(userId string) ([]*models.CancelledRegistration, *models.ResponseError) {
+	query := `
+		SELECT
+			id, event_id, user_id, reason, cancelled_at
+		FROM
+			cancelled_registrations
+		WHERE
+			user_id = $1
+		ORDER BY
+			cancelled_at, id`
+	rows, err := rr.db.Query(query, userId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	cancelledRegistrationsList := make([]*models.CancelledRegistration, 0)
+
+	for rows.Next() {
+		var cancelledRegistration models.CancelledRegistration
+		err = rows.Scan(
+			&cancelledRegistration.ID,
+			&cancelledRegistration.EventId,
+			&cancelledRegistration.UserId,
+			&cancelledRegistration.Reason,
+			&cancelledRegistration.CancelledAt,
+		)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		cancelledRegistrationsList = append(cancelledRegistrationsList, &cancelledRegistration)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return cancelledRegistrationsList, nil
+}
+
+// QueryGetUserRegistrations returns the registrations of the user together with the events
+func (rr *RegistrationsRepository) QueryGetUserRegistrations(userId string) ([]*models.Registration, *models.ResponseError) {
+	query := `
+		SELECT
+			registrations.id, registrations.event_id, registrations.user_id,
+			` + eventColumns + `
+		FROM
+			registrations
+		JOIN
+			events ON events.id = registrations.event_id
+		WHERE
+			registrations.user_id = $1
+		ORDER BY
+			events.event_start, events.id`
+	rows, err := rr.db.Query(query, userId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	registrationsList := make([]*models.Registration, 0)
+
+	for rows.Next() {
+		var registration models.Registration
+		var event models.Event
+		err = rows.Scan(append([]any{&registration.ID, &registration.EventId, &registration.UserId}, eventFields(&event)...)...)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		registration.Event = &event
+		registrationsList = append(registrationsList, &registration)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return registrationsList, nil
+}
+
+// QueryGetUserRegistrationsPage returns a page of the registrations of the user together with the events and the total amount of registrations
+func (rr *RegistrationsRepository) QueryGetUserRegistrationsPage(userId string, filters *dtos.UserRegistrationFilterDto) ([]*models.Registration, int, *models.ResponseError) {
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(),
+			registrations.id, registrations.event_id, registrations.user_id,
+			`+eventColumns+`
+		FROM
+			registrations
+		JOIN
+			events ON events.id = registrations.event_id
+		WHERE
+			registrations.user_id = $1
+			AND
+			`+eventSearchCondition("$2", "$7", "$8")+`
+			AND
+			(events.event_location = $3 OR $3 = '')
+			AND
+			(events.event_end > now() OR NOT $4)
+		ORDER BY
+			events.%s %s, registrations.id ASC
+		LIMIT
+			$5
+		OFFSET
+			$6`, filters.SortColumn, filters.SortOrder)
+	offset := filters.PageSize * (filters.Page - 1)
+	searchWords, searchPrefix := utils.BuildPrefixSearchQuery(filters.Name)
+	rows, err := rr.db.Query(query, userId, utils.BuildSearchQuery(filters.Name), filters.Location, filters.Upcoming, filters.PageSize, offset, searchWords, searchPrefix)
+
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	totalCount := 0
+	registrationsList := make([]*models.Registration, 0)
+
+	for rows.Next() {
+		var registration models.Registration
+		var event models.Event
+		err = rows.Scan(append([]any{&totalCount, &registration.ID, &registration.EventId, &registration.UserId}, eventFields(&event)...)...)
+		if err != nil {
+			return nil, 0, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		registration.Event = &event
+		registrationsList = append(registrationsList, &registration)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return registrationsList, totalCount, nil
+}
+
+// QueryGetEventAttendees returns a page of the users registered for the event and the total amount of attendees
+func (rr *RegistrationsRepository) QueryGetEventAttendees(eventId string, filters *dtos.AttendeeFilterDto) ([]*dtos.Attendee, int, *models.ResponseError) {
+	query := fmt.Sprintf(`
+		SELECT
+			COUNT(*) OVER(),
+			registrations.id, users.id, users.email, users.display_name
+		FROM
+			registrations
+		JOIN
+			users ON users.id = registrations.user_id
+		WHERE
+			registrations.event_id = $1
+			AND
+			(users.email ILIKE '%%' || $2 || '%%' OR users.display_name ILIKE '%%' || $2 || '%%' OR $2 = '')
+		ORDER BY
+			users.%s %s, registrations.id ASC
+		LIMIT
+			$3
+		OFFSET
+			$4`, filters.SortColumn, filters.SortOrder)
+	offset := filters.PageSize * (filters.Page - 1)
+	rows, err := rr.db.Query(query, eventId, filters.Search, filters.PageSize, offset)
+
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	totalCount := 0
+	attendeesList := make([]*dtos.Attendee, 0)
+
+	for rows.Next() {
+		var attendee dtos.Attendee
+		err = rows.Scan(&totalCount, &attendee.RegistrationId, &attendee.UserId, &attendee.Email, &attendee.DisplayName)
+		if err != nil {
+			return nil, 0, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		attendeesList = append(attendeesList, &attendee)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return attendeesList, totalCount, nil
+}
+
+// QueryStreamEventAttendees passes the attendees of the event one by one to the given function without loading all of them into memory.
+// Streaming stops at the first error of the function
+func (rr *RegistrationsRepository) QueryStreamEventAttendees(eventId string, each func(attendee *dtos.Attendee) error) *models.ResponseError {
+	query := `
+		SELECT
+			registrations.id, users.id, users.email, users.display_name
+		FROM
+			registrations
+		JOIN
+			users ON users.id = registrations.user_id
+		WHERE
+			registrations.event_id = $1
+		ORDER BY
+			users.email, registrations.id`
+	rows, err := rr.db.Query(query, eventId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var attendee dtos.Attendee
+		err = rows.Scan(&attendee.RegistrationId, &attendee.UserId, &attendee.Email, &attendee.DisplayName)
+		if err == nil {
+			err = each(&attendee)
+		}
+		if err != nil {
+			return &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// QueryCancelUserRegistrations deletes the registrations of the user, the seats have to be freed separately
+func (rr *RegistrationsRepository) QueryCancelUserRegistrations(userId string) *models.ResponseError {
+	query := `
+		DELETE FROM
+			registrations
+		WHERE
+			user_id = $1`
+	_, err := rr.db.Exec(query, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// QueryDeleteUserRegistrations deletes the registrations of the user
+func (rr *RegistrationsRepository) QueryDeleteUserRegistrations(userId string) *models.ResponseError {
+	query := `
+		DELETE FROM
+			registrations
+		WHERE
+			user_id = $1`
+	_, err := rr.db.Exec(query, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
 var _ RegistrationsRepositoryInterface = (*RegistrationsRepository)(nil)
@@ -1,13 +1,38 @@
 package repositories
 
-import "eventom-backend/models"
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
 
 type RegistrationsRepositoryInterface interface {
 	QueryRegisterUserForEvent(eventId string, userId string) (*models.Registration, *models.ResponseError)
 
 	QueryGetRegistration(eventId string, userId string) (*models.Registration, *models.ResponseError)
 
+	QueryGetRegistrationById(registrationId string) (*models.Registration, *models.ResponseError)
+
+	QueryGetUserRegistrationById(registrationId string, userId string) (*models.Registration, *models.ResponseError)
+
 	QueryGetAllRegistrations() ([]*models.Registration, *models.ResponseError)
 
 	QueryCancelRegistration(eventId string, userId string) (*models.Registration, *models.ResponseError)
+
+	QueryCancelEventRegistrations(eventId string) ([]*models.Registration, *models.ResponseError)
+
+	QueryCreateCancelledRegistration(registration *models.Registration, reason string) (*models.CancelledRegistration, *models.ResponseError)
+
+	QueryGetUserCancelledRegistrations(userId string) ([]*models.CancelledRegistration, *models.ResponseError)
+
+	QueryGetUserRegistrations(userId string) ([]*models.Registration, *models.ResponseError)
+
+	QueryGetUserRegistrationsPage(userId string, filters *dtos.UserRegistrationFilterDto) ([]*models.Registration, int, *models.ResponseError)
+
+	QueryGetEventAttendees(eventId string, filters *dtos.AttendeeFilterDto) ([]*dtos.Attendee, int, *models.ResponseError)
+
+	QueryStreamEventAttendees(eventId string, each func(attendee *dtos.Attendee) error) *models.ResponseError
+
+	QueryCancelUserRegistrations(userId string) *models.ResponseError
+
+	QueryDeleteUserRegistrations(userId string) *models.ResponseError
 }
@@ -0,0 +1,164 @@
+package repositories
+
+import (
+	"eventom-backend/models"
+	"net/http"
+	"time"
+)
+
+type RevokedTokensRepository struct {
+	db DBTX
+}
+
+func NewRevokedTokensRepository(db DBTX) *RevokedTokensRepository {
+	return &RevokedTokensRepository{
+		db: db,
+	}
+}
+
+func (rtr *RevokedTokensRepository) QueryRevokeToken(revokedToken *models.RevokedToken) *models.ResponseError {
+	query := `
+		INSERT INTO
+			revoked_tokens(jti, user_id, expires_at)
+		VALUES
+			($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING`
+	_, err := rtr.db.Exec(query, revokedToken.TokenId, revokedToken.UserId, revokedToken.ExpiresAt)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (rtr *RevokedTokensRepository) QueryGetRevokedTokens() ([]*models.RevokedToken, *models.ResponseError) {
+	query := `
+		SELECT
+			jti, user_id, expires_at
+		FROM
+			revoked_tokens
+		WHERE
+			expires_at > now()`
+	rows, err := rtr.db.Query(query)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	revokedTokensList := make([]*models.RevokedToken, 0)
+
+	for rows.Next() {
+		var revokedToken models.RevokedToken
+		err = rows.Scan(&revokedToken.TokenId, &revokedToken.UserId, &revokedToken.ExpiresAt)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		revokedTokensList = append(revokedTokensList, &revokedToken)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return revokedTokensList, nil
+}
+
+func (rtr *RevokedTokensRepository) QueryDeleteExpiredRevokedTokens() *models.ResponseError {
+	query := `
+		DELETE FROM
+			revoked_tokens
+		WHERE
+			expires_at <= now()`
+	_, err := rtr.db.Exec(query)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (rtr *RevokedTokensRepository) QueryRevokeUserTokens(userId string, revokedBefore time.Time) *models.ResponseError {
+	query := `
+		INSERT INTO
+			user_token_revocations(user_id, revoked_before)
+		VALUES
+			($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET
+			revoked_before = GREATEST(user_token_revocations.revoked_before, EXCLUDED.revoked_before)`
+	_, err := rtr.db.Exec(query, userId, revokedBefore)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (rtr *RevokedTokensRepository) QueryGetUserTokenRevocations(since time.Time) ([]*models.UserTokenRevocation, *models.ResponseError) {
+	query := `
+		SELECT
+			user_id, revoked_before
+		FROM
+			user_token_revocations
+		WHERE
+			revoked_before > $1`
+	rows, err := rtr.db.Query(query, since)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	revocationsList := make([]*models.UserTokenRevocation, 0)
+
+	for rows.Next() {
+		var revocation models.UserTokenRevocation
+		err = rows.Scan(&revocation.UserId, &revocation.RevokedBefore)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		revocationsList = append(revocationsList, &revocation)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return revocationsList, nil
+}
+
+var _ RevokedTokensRepositoryInterface = (*RevokedTokensRepository)(nil)
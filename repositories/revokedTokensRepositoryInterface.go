@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"eventom-backend/models"
+	"time"
+)
+
+type RevokedTokensRepositoryInterface interface {
+	QueryRevokeToken(revokedToken *models.RevokedToken) *models.ResponseError
+
+	QueryGetRevokedTokens() ([]*models.RevokedToken, *models.ResponseError)
+
+	QueryDeleteExpiredRevokedTokens() *models.ResponseError
+
+	QueryRevokeUserTokens(userId string, revokedBefore time.Time) *models.ResponseError
+
+	QueryGetUserTokenRevocations(since time.Time) ([]*models.UserTokenRevocation, *models.ResponseError)
+}
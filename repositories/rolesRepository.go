@@ -0,0 +1,198 @@
+package repositories
+
+import (
+	"eventom-backend/models"
+	"net/http"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+type RolesRepository struct {
+	db DBTX
+}
+
+func NewRolesRepository(db DBTX) *RolesRepository {
+	return &RolesRepository{
+		db: db,
+	}
+}
+
+func (rr *RolesRepository) QueryGetRoles() ([]*models.Role, *models.ResponseError) {
+	query := `
+		SELECT
+			roles.role_name,
+			COALESCE(array_agg(role_permissions.permission_name ORDER BY role_permissions.permission_name) FILTER (WHERE role_permissions.permission_name IS NOT NULL), '{}')
+		FROM
+			roles
+		LEFT JOIN
+			role_permissions ON role_permissions.role_name = roles.role_name
+		GROUP BY
+			roles.role_name
+		ORDER BY
+			roles.role_name`
+	rows, err := rr.db.Query(query)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	rolesList := make([]*models.Role, 0)
+
+	for rows.Next() {
+		var role models.Role
+		err = rows.Scan(&role.Name, pq.Array(&role.Permissions))
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		rolesList = append(rolesList, &role)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return rolesList, nil
+}
+
+func (rr *RolesRepository) QueryGetUserRoles(userId string) ([]string, *models.ResponseError) {
+	query := `
+		SELECT
+			role_name
+		FROM
+			user_roles
+		WHERE
+			user_id = $1
+		ORDER BY
+			role_name`
+
+	return rr.queryNames(query, userId)
+}
+
+func (rr *RolesRepository) QueryGetUserPermissions(userId string) ([]string, *models.ResponseError) {
+	query := `
+		SELECT DISTINCT
+			role_permissions.permission_name
+		FROM
+			user_roles
+		JOIN
+			role_permissions ON role_permissions.role_name = user_roles.role_name
+		WHERE
+			user_roles.user_id = $1
+		ORDER BY
+			role_permissions.permission_name`
+
+	return rr.queryNames(query, userId)
+}
+
+func (rr *RolesRepository) QueryAssignRole(userId string, roleName string) *models.ResponseError {
+	query := `
+		INSERT INTO
+			user_roles(user_id, role_name)
+		VALUES
+			($1, $2)
+		ON CONFLICT DO NOTHING`
+	_, err := rr.db.Exec(query, userId, roleName)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "foreign key constraint") {
+			return &models.ResponseError{
+				Message: "User or role not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (rr *RolesRepository) QueryRemoveRole(userId string, roleName string) *models.ResponseError {
+	query := `
+		DELETE FROM
+			user_roles
+		WHERE
+			user_id = $1
+			AND
+			role_name = $2`
+	result, err := rr.db.Exec(query, userId, roleName)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Role not assigned to user",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+func (rr *RolesRepository) queryNames(query string, args ...any) ([]string, *models.ResponseError) {
+	rows, err := rr.db.Query(query, args...)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	namesList := make([]string, 0)
+	var name string
+
+	for rows.Next() {
+		err = rows.Scan(&name)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		namesList = append(namesList, name)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return namesList, nil
+}
+
+var _ RolesRepositoryInterface = (*RolesRepository)(nil)
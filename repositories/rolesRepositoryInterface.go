@@ -0,0 +1,15 @@
+package repositories
+
+import "eventom-backend/models"
+
+type RolesRepositoryInterface interface {
+	QueryGetRoles() ([]*models.Role, *models.ResponseError)
+
+	QueryGetUserRoles(userId string) ([]string, *models.ResponseError)
+
+	QueryGetUserPermissions(userId string) ([]string, *models.ResponseError)
+
+	QueryAssignRole(userId string, roleName string) *models.ResponseError
+
+	QueryRemoveRole(userId string, roleName string) *models.ResponseError
+}
@@ -0,0 +1,227 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+	"time"
+)
+
+const sessionColumns = `id, user_id, user_agent, ip_address, created_at, last_seen_at, expires_at, revoked_at`
+
+type SessionsRepository struct {
+	db DBTX
+}
+
+func NewSessionsRepository(db DBTX) *SessionsRepository {
+	return &SessionsRepository{
+		db: db,
+	}
+}
+
+func (sr *SessionsRepository) QueryCreateSession(session *models.Session) *models.ResponseError {
+	query := `
+		INSERT INTO
+			sessions(id, user_id, user_agent, ip_address, expires_at)
+		VALUES
+			($1, $2, $3, $4, $5)
+		RETURNING
+			created_at, last_seen_at`
+	row := sr.db.QueryRow(query, session.ID, session.UserId, session.UserAgent, session.IpAddress, session.ExpiresAt)
+
+	err := row.Scan(&session.CreatedAt, &session.LastSeenAt)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// QueryGetUserSessions returns the active sessions of the user. Sessions that ended by logout, refresh token reuse or
+// expiry have no valid refresh token anymore
+func (sr *SessionsRepository) QueryGetUserSessions(userId string) ([]*models.Session, *models.ResponseError) {
+	query := `
+		SELECT
+			` + sessionColumns + `
+		FROM
+			sessions
+		WHERE
+			user_id = $1
+			AND
+			revoked_at IS NULL
+			AND
+			expires_at > now()
+			AND
+			EXISTS (
+				SELECT
+					1
+				FROM
+					refresh_tokens
+				WHERE
+					refresh_tokens.family_id = sessions.id
+					AND
+					refresh_tokens.revoked_at IS NULL
+					AND
+					refresh_tokens.expires_at > now()
+			)
+		ORDER BY
+			last_seen_at DESC`
+
+	return sr.querySessions(query, userId)
+}
+
+// QueryGetAllUserSessions returns all sessions of the user including the ended ones
+func (sr *SessionsRepository) QueryGetAllUserSessions(userId string) ([]*models.Session, *models.ResponseError) {
+	query := `
+		SELECT
+			` + sessionColumns + `
+		FROM
+			sessions
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at, id`
+
+	return sr.querySessions(query, userId)
+}
+
+// QueryTouchSession records that the session has been used, it is called whenever the refresh token of the session is rotated
+func (sr *SessionsRepository) QueryTouchSession(sessionId string, ipAddress string, userAgent string, expiresAt time.Time) *models.ResponseError {
+	query := `
+		UPDATE
+			sessions
+		SET
+			ip_address = $2,
+			user_agent = $3,
+			expires_at = $4,
+			last_seen_at = now()
+		WHERE
+			id = $1`
+	_, err := sr.db.Exec(query, sessionId, ipAddress, userAgent, expiresAt)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (sr *SessionsRepository) QueryRevokeSession(userId string, sessionId string) (*models.Session, *models.ResponseError) {
+	query := `
+		UPDATE
+			sessions
+		SET
+			revoked_at = now()
+		WHERE
+			id = $1
+			AND
+			user_id = $2
+			AND
+			revoked_at IS NULL
+		RETURNING
+			` + sessionColumns
+	row := sr.db.QueryRow(query, sessionId, userId)
+
+	session, err := scanSession(row)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Session not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return session, nil
+}
+
+// QueryRevokeUserSessions ends all active sessions of the user and returns them
+func (sr *SessionsRepository) QueryRevokeUserSessions(userId string) ([]*models.Session, *models.ResponseError) {
+	query := `
+		UPDATE
+			sessions
+		SET
+			revoked_at = now()
+		WHERE
+			user_id = $1
+			AND
+			revoked_at IS NULL
+		RETURNING
+			` + sessionColumns
+
+	return sr.querySessions(query, userId)
+}
+
+// QueryGetRevokedSessions returns the sessions revoked since the given time, access tokens of older revocations have expired already
+func (sr *SessionsRepository) QueryGetRevokedSessions(since time.Time) ([]*models.Session, *models.ResponseError) {
+	query := `
+		SELECT
+			` + sessionColumns + `
+		FROM
+			sessions
+		WHERE
+			revoked_at > $1`
+
+	return sr.querySessions(query, since)
+}
+
+func (sr *SessionsRepository) querySessions(query string, args ...any) ([]*models.Session, *models.ResponseError) {
+	rows, err := sr.db.Query(query, args...)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	sessionsList := make([]*models.Session, 0)
+
+	for rows.Next() {
+		session, err := scanSession(rows)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		sessionsList = append(sessionsList, session)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return sessionsList, nil
+}
+
+func scanSession(scanner interface{ Scan(dest ...any) error }) (*models.Session, error) {
+	var session models.Session
+	err := scanner.Scan(&session.ID, &session.UserId, &session.UserAgent, &session.IpAddress, &session.CreatedAt, &session.LastSeenAt, &session.ExpiresAt, &session.RevokedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+var _ SessionsRepositoryInterface = (*SessionsRepository)(nil)
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"eventom-backend/models"
+	"time"
+)
+
+type SessionsRepositoryInterface interface {
+	QueryCreateSession(session *models.Session) *models.ResponseError
+
+	QueryGetUserSessions(userId string) ([]*models.Session, *models.ResponseError)
+
+	QueryGetAllUserSessions(userId string) ([]*models.Session, *models.ResponseError)
+
+	QueryTouchSession(sessionId string, ipAddress string, userAgent string, expiresAt time.Time) *models.ResponseError
+
+	QueryRevokeSession(userId string, sessionId string) (*models.Session, *models.ResponseError)
+
+	QueryRevokeUserSessions(userId string) ([]*models.Session, *models.ResponseError)
+
+	QueryGetRevokedSessions(since time.Time) ([]*models.Session, *models.ResponseError)
+}
@@ -2,8 +2,13 @@ package repositories
 
 import (
 	"database/sql"
+	"eventom-backend/dtos"
 	"eventom-backend/models"
+	"eventom-backend/utils"
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
 )
 
 type TransactionHandler struct {
@@ -55,3 +60,1221 @@ func (th *TransactionHandler) ExecTx(evntId string, userId string) (*models.Regi
 
 	return registration, nil
 }
+
+// ExecJoinWaitlistTx puts the user on the waitlist of a full event. Users can only wait for events that have no free seat left
+func (th *TransactionHandler) ExecJoinWaitlistTx(eventId string, userId string) (*models.WaitlistEntry, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	eventsRepository := NewEventsRepository(tx)
+	registrationsRepository := NewRegistrationsRepository(tx)
+	waitlistRepository := NewWaitlistRepository(tx)
+
+	event, responseErr := eventsRepository.QueryGetEventForUpdate(eventId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	registration, responseErr := registrationsRepository.QueryGetRegistration(eventId, userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	if registration != nil {
+		tx.Rollback()
+		return nil, &models.ResponseError{
+			Message: "User is already registered for this event",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	if event.AmountRegistration < event.MaxCapacity {
+		tx.Rollback()
+		return nil, &models.ResponseError{
+			Message: "Event is not full",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	waitlistEntry, responseErr := waitlistRepository.QueryCreateWaitlistEntry(eventId, userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return waitlistEntry, nil
+}
+
+// ExecCancelRegistrationTx cancels the registration and hands the free seat to the next user on the waitlist.
+// Returns the cancelled registration and the registrations of the promoted users
+func (th *TransactionHandler) ExecCancelRegistrationTx(eventId string, userId string) (*models.Registration, []*models.Registration, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	eventsRepository := NewEventsRepository(tx)
+	registrationsRepository := NewRegistrationsRepository(tx)
+	waitlistRepository := NewWaitlistRepository(tx)
+
+	event, responseErr := eventsRepository.QueryGetEventForUpdate(eventId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	cancelledRegistration, responseErr := registrationsRepository.QueryCancelRegistration(eventId, userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	responseErr = eventsRepository.QueryDecrementAmountRegistrations(eventId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	event.AmountRegistration--
+
+	promotedRegistrations, responseErr := promoteFromWaitlist(event, eventsRepository, registrationsRepository, waitlistRepository)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return cancelledRegistration, promotedRegistrations, nil
+}
+
+// ExecUpdateEventTx updates the event while it is locked, so registrations cannot race a capacity change. The capacity can only drop
+// below the amount of registrations if overbooking is allowed, existing registrations are kept then. Free seats of a raised capacity go to
+// the waitlist. Returns the updated event and the registrations of the users that were promoted from the waitlist
+func (th *TransactionHandler) ExecUpdateEventTx(event *models.Event, allowOverbooking bool) (*models.Event, []*models.Registration, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	eventsRepository := NewEventsRepository(tx)
+	registrationsRepository := NewRegistrationsRepository(tx)
+	waitlistRepository := NewWaitlistRepository(tx)
+
+	currentEvent, responseErr := eventsRepository.QueryGetEventForUpdate(event.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	updatedEvent, promotedRegistrations, responseErr := updateLockedEvent(currentEvent, event, allowOverbooking, eventsRepository, registrationsRepository, waitlistRepository)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return updatedEvent, promotedRegistrations, nil
+}
+
+// ExecCreateEventSeriesTx creates the series together with the given occurrences
+func (th *TransactionHandler) ExecCreateEventSeriesTx(series *models.EventSeries, occurrences []*models.Event) (*models.EventSeries, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	eventSeriesRepository := NewEventSeriesRepository(tx)
+	eventsRepository := NewEventsRepository(tx)
+
+	createdSeries, responseErr := eventSeriesRepository.QueryCreateEventSeries(series)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	createdSeries.Occurrences = make([]*models.Event, 0, len(occurrences))
+
+	for _, occurrence := range occurrences {
+		occurrence.SeriesId = &createdSeries.ID
+
+		createdOccurrence, responseErr := eventsRepository.QueryCreateEvent(occurrence)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, responseErr
+		}
+
+		createdSeries.Occurrences = append(createdSeries.Occurrences, createdOccurrence)
+	}
+
+	_ = tx.Commit()
+
+	return createdSeries, nil
+}
+
+// ExecUpdateEventSeriesTx applies the changes of the given occurrence to the following occurrences of its series or to all of them.
+// A changed date moves every affected occurrence by the same amount of days, all of them start at the new local time of day and
+// last as long as the given occurrence. The capacity of every occurrence is checked like in ExecUpdateEventTx. Returns the updated occurrence and the registrations that were promoted from the waitlists
+func (th *TransactionHandler) ExecUpdateEventSeriesTx(event *models.Event, scope string, allowOverbooking bool) (*models.Event, []*models.Registration, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	eventsRepository := NewEventsRepository(tx)
+	registrationsRepository := NewRegistrationsRepository(tx)
+	waitlistRepository := NewWaitlistRepository(tx)
+
+	currentEvent, responseErr := eventsRepository.QueryGetEventForUpdate(event.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	if currentEvent.SeriesId == nil {
+		tx.Rollback()
+		return nil, nil, &models.ResponseError{
+			Message: "Event is not part of a series",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	from := time.Time{}
+	if scope == models.EventEditScopeFollowing {
+		from = currentEvent.StartsAt
+	}
+
+	occurrences, responseErr := eventsRepository.QueryGetSeriesOccurrencesForUpdate(*currentEvent.SeriesId, from)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	dayOffset := int(event.LocalDate().Sub(currentEvent.LocalDate()).Hours() / 24)
+	var updatedEvent *models.Event
+	promotedRegistrations := make([]*models.Registration, 0)
+
+	for _, occurrence := range occurrences {
+		changedOccurrence := event.AtDate(occurrence.LocalDate().AddDate(0, 0, dayOffset))
+		changedOccurrence.ID = occurrence.ID
+
+		updatedOccurrence, promoted, responseErr := updateLockedEvent(occurrence, changedOccurrence, allowOverbooking, eventsRepository, registrationsRepository, waitlistRepository)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, nil, responseErr
+		}
+
+		if updatedOccurrence.ID == event.ID {
+			updatedEvent = updatedOccurrence
+		}
+
+		promotedRegistrations = append(promotedRegistrations, promoted...)
+	}
+
+	_ = tx.Commit()
+
+	return updatedEvent, promotedRegistrations, nil
+}
+
+// updateLockedEvent updates an event that was locked with QueryGetEventForUpdate and fills the seats that became free from its waitlist.
+// The capacity must not be lowered below the current registrations unless allowOverbooking is set
+func updateLockedEvent(
+	currentEvent *models.Event,
+	event *models.Event,
+	allowOverbooking bool,
+	eventsRepository *EventsRepository,
+	registrationsRepository *RegistrationsRepository,
+	waitlistRepository *WaitlistRepository,
+) (*models.Event, []*models.Registration, *models.ResponseError) {
+	if event.MaxCapacity < currentEvent.AmountRegistration && !allowOverbooking {
+		return nil, nil, &models.ResponseError{
+			Message: fmt.Sprintf("Capacity must not be lower than the %d current registrations", currentEvent.AmountRegistration),
+			Status:  http.StatusConflict,
+		}
+	}
+
+	updatedEvent, responseErr := eventsRepository.QueryUpdateEvent(event)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	promotedRegistrations, responseErr := promoteFromWaitlist(updatedEvent, eventsRepository, registrationsRepository, waitlistRepository)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	return updatedEvent, promotedRegistrations, nil
+}
+
+// ExecDeleteEventTx cancels all registrations for the event, clears its waitlist and deletes it. The event is kept as tombstone together
+// with the records of the cancelled registrations unless purge is set. Returns the deleted event and the cancelled registrations
+func (th *TransactionHandler) ExecDeleteEventTx(eventId string, reason string, purge bool) (*models.Event, []*models.Registration, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	eventsRepository := NewEventsRepository(tx)
+	registrationsRepository := NewRegistrationsRepository(tx)
+	waitlistRepository := NewWaitlistRepository(tx)
+
+	deletedEvent, cancelledRegistrations, responseErr := deleteEvent(eventId, reason, purge, eventsRepository, registrationsRepository, waitlistRepository)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return deletedEvent, cancelledRegistrations, nil
+}
+
+// deleteEvent locks the event, cancels its registrations, clears its waitlist and deletes it, see ExecDeleteEventTx
+func deleteEvent(
+	eventId string,
+	reason string,
+	purge bool,
+	eventsRepository *EventsRepository,
+	registrationsRepository *RegistrationsRepository,
+	waitlistRepository *WaitlistRepository,
+) (*models.Event, []*models.Registration, *models.ResponseError) {
+	event, responseErr := eventsRepository.QueryGetEventForUpdate(eventId)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	cancelledRegistrations, responseErr := registrationsRepository.QueryCancelEventRegistrations(eventId)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	responseErr = waitlistRepository.QueryDeleteEventWaitlist(eventId)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	if purge {
+		responseErr = eventsRepository.QueryDeleteEvent(eventId)
+
+		if responseErr != nil {
+			return nil, nil, responseErr
+		}
+
+		event.DeletionReason = reason
+
+		return event, cancelledRegistrations, nil
+	}
+
+	for _, registration := range cancelledRegistrations {
+		_, responseErr = registrationsRepository.QueryCreateCancelledRegistration(registration, models.CancellationReasonEventDeleted)
+
+		if responseErr != nil {
+			return nil, nil, responseErr
+		}
+	}
+
+	deletedEvent, responseErr := eventsRepository.QuerySoftDeleteEvent(eventId, reason)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	return deletedEvent, cancelledRegistrations, nil
+}
+
+// promoteFromWaitlistOfEvents locks each of the events and fills its free seats from the waitlist
+func promoteFromWaitlistOfEvents(
+	eventIds []string,
+	eventsRepository *EventsRepository,
+	registrationsRepository *RegistrationsRepository,
+	waitlistRepository *WaitlistRepository,
+) ([]*models.Registration, *models.ResponseError) {
+	promotedRegistrations := make([]*models.Registration, 0)
+
+	for _, eventId := range eventIds {
+		event, responseErr := eventsRepository.QueryGetEventForUpdate(eventId)
+
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		registrations, responseErr := promoteFromWaitlist(event, eventsRepository, registrationsRepository, waitlistRepository)
+
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		promotedRegistrations = append(promotedRegistrations, registrations...)
+	}
+
+	return promotedRegistrations, nil
+}
+
+// promoteFromWaitlist registers the users on the waitlist of the event in the order they joined until the event is full again.
+// The event has to be locked by the caller and has to hold the current amount of registrations
+func promoteFromWaitlist(
+	event *models.Event,
+	eventsRepository *EventsRepository,
+	registrationsRepository *RegistrationsRepository,
+	waitlistRepository *WaitlistRepository,
+) ([]*models.Registration, *models.ResponseError) {
+	promotedRegistrations := make([]*models.Registration, 0)
+
+	for event.AmountRegistration < event.MaxCapacity {
+		waitlistEntry, responseErr := waitlistRepository.QueryPopWaitlistEntry(event.ID)
+
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		if waitlistEntry == nil {
+			break
+		}
+
+		registration, responseErr := registrationsRepository.QueryRegisterUserForEvent(event.ID, waitlistEntry.UserId)
+
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		event, responseErr = eventsRepository.QueryIncrementAmountRegistrations(event.ID)
+
+		if responseErr != nil {
+			return nil, responseErr
+		}
+
+		promotedRegistrations = append(promotedRegistrations, registration)
+	}
+
+	return promotedRegistrations, nil
+}
+
+// ExecCreateSessionTx starts a session together with the first refresh token of its family
+func (th *TransactionHandler) ExecCreateSessionTx(session *models.Session, refreshToken *models.RefreshToken) (*models.RefreshToken, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	sessionsRepository := NewSessionsRepository(tx)
+	refreshTokensRepository := NewRefreshTokensRepository(tx)
+
+	responseErr := sessionsRepository.QueryCreateSession(session)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	createdToken, responseErr := refreshTokensRepository.QueryCreateRefreshToken(refreshToken)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return createdToken, nil
+}
+
+// ExecRevokeSessionTx ends a session of the user and revokes its refresh tokens
+func (th *TransactionHandler) ExecRevokeSessionTx(userId string, sessionId string) (*models.Session, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	sessionsRepository := NewSessionsRepository(tx)
+	refreshTokensRepository := NewRefreshTokensRepository(tx)
+
+	revokedSession, responseErr := sessionsRepository.QueryRevokeSession(userId, sessionId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	responseErr = refreshTokensRepository.QueryRevokeRefreshTokenFamily(revokedSession.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return revokedSession, nil
+}
+
+// ExecRevokeUserTokensTx ends all sessions of the user together with their refresh tokens, revokes the api keys of the user and revokes
+// the access tokens issued before revokedBefore that do not belong to a session. The revoked sessions are returned, their access tokens
+// are rejected by the session id
+func (th *TransactionHandler) ExecRevokeUserTokensTx(userId string, revokedBefore time.Time) ([]*models.Session, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	revokedTokensRepository := NewRevokedTokensRepository(tx)
+	sessionsRepository := NewSessionsRepository(tx)
+	refreshTokensRepository := NewRefreshTokensRepository(tx)
+	apiKeysRepository := NewApiKeysRepository(tx)
+
+	responseErr := revokedTokensRepository.QueryRevokeUserTokens(userId, revokedBefore)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	revokedSessions, responseErr := sessionsRepository.QueryRevokeUserSessions(userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	responseErr = refreshTokensRepository.QueryRevokeUserRefreshTokens(userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	responseErr = apiKeysRepository.QueryRevokeUserApiKeys(userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return revokedSessions, nil
+}
+
+func (th *TransactionHandler) ExecRotateRefreshTokenTx(tokenHash string, newTokenHash string, expiresAt time.Time, client dtos.ClientInfo) (*models.RefreshToken, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	refreshTokensRepository := NewRefreshTokensRepository(tx)
+	sessionsRepository := NewSessionsRepository(tx)
+
+	currentToken, responseErr := refreshTokensRepository.QueryGetRefreshToken(tokenHash)
+
+	if responseErr != nil {
+		tx.Rollback()
+		if responseErr.Status == http.StatusNotFound {
+			return nil, &models.ResponseError{
+				Message: "Invalid refresh token",
+				Status:  http.StatusUnauthorized,
+			}
+		}
+		return nil, responseErr
+	}
+
+	// the row is locked, so of parallel refreshes with the same token only the first one rotates it. The others find it rotated
+	// shortly before and get a conflict, the client already received the successor with the response of the first one
+	if currentToken.RevokedAt != nil && currentToken.ReplacedBy != nil && time.Since(*currentToken.RevokedAt) < utils.RefreshTokenReuseGracePeriod {
+		tx.Rollback()
+		return nil, &models.ResponseError{
+			Message: "Refresh token has already been rotated",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	// an already rotated token is presented again, so either the client or an attacker holds a stolen copy.
+	// revoke the whole family to end the session for both of them
+	if currentToken.RevokedAt != nil {
+		responseErr = refreshTokensRepository.QueryRevokeRefreshTokenFamily(currentToken.FamilyId)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, responseErr
+		}
+
+		_ = tx.Commit()
+
+		return nil, &models.ResponseError{
+			Message: "Refresh token reuse detected",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	if time.Now().After(currentToken.ExpiresAt) {
+		tx.Rollback()
+		return nil, &models.ResponseError{
+			Message: "Refresh token expired",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	newToken, responseErr := refreshTokensRepository.QueryCreateRefreshToken(&models.RefreshToken{
+		UserId:    currentToken.UserId,
+		FamilyId:  currentToken.FamilyId,
+		TokenHash: newTokenHash,
+		ExpiresAt: expiresAt,
+	})
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	responseErr = refreshTokensRepository.QueryRevokeRefreshToken(currentToken.ID, newToken.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	responseErr = sessionsRepository.QueryTouchSession(currentToken.FamilyId, client.IpAddress, client.UserAgent, expiresAt)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return newToken, nil
+}
+
+func (th *TransactionHandler) ExecSignupUserTx(email string, hashedPassword string, roles []string) (*models.User, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	usersRepository := NewUsersRepository(tx)
+	rolesRepository := NewRolesRepository(tx)
+
+	responseErr := usersRepository.QuerySignupUser(email, hashedPassword)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	createdUser, responseErr := usersRepository.QueryGetUser(email)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	for _, role := range roles {
+		responseErr = rolesRepository.QueryAssignRole(createdUser.ID, role)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, responseErr
+		}
+	}
+
+	_ = tx.Commit()
+
+	return createdUser, nil
+}
+
+func (th *TransactionHandler) ExecVerifyEmailTx(tokenHash string) (*models.EmailVerificationToken, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	emailVerificationsRepository := NewEmailVerificationsRepository(tx)
+	usersRepository := NewUsersRepository(tx)
+
+	verificationToken, responseErr := emailVerificationsRepository.QueryGetEmailVerificationToken(tokenHash)
+
+	if responseErr != nil {
+		tx.Rollback()
+		if responseErr.Status == http.StatusNotFound {
+			return nil, &models.ResponseError{
+				Message: "Invalid verification token",
+				Status:  http.StatusBadRequest,
+			}
+		}
+		return nil, responseErr
+	}
+
+	if verificationToken.UsedAt != nil {
+		tx.Rollback()
+		return nil, &models.ResponseError{
+			Message: "Verification token has already been used",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if time.Now().After(verificationToken.ExpiresAt) {
+		tx.Rollback()
+		return nil, &models.ResponseError{
+			Message: "Verification token expired",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	responseErr = emailVerificationsRepository.QueryUseEmailVerificationToken(verificationToken.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	responseErr = usersRepository.QueryVerifyEmail(verificationToken.UserId, verificationToken.Email)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return verificationToken, nil
+}
+
+// ExecResetPasswordTx sets the new password if the reset token is valid and returns the id of the user the password was reset for
+func (th *TransactionHandler) ExecResetPasswordTx(tokenHash string, hashedPassword string) (string, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	passwordResetsRepository := NewPasswordResetsRepository(tx)
+	usersRepository := NewUsersRepository(tx)
+
+	resetToken, responseErr := passwordResetsRepository.QueryGetPasswordResetToken(tokenHash)
+
+	if responseErr != nil {
+		tx.Rollback()
+		if responseErr.Status == http.StatusNotFound {
+			return "", &models.ResponseError{
+				Message: "Invalid password reset token",
+				Status:  http.StatusBadRequest,
+			}
+		}
+		return "", responseErr
+	}
+
+	if resetToken.UsedAt != nil || time.Now().After(resetToken.ExpiresAt) {
+		tx.Rollback()
+		return "", &models.ResponseError{
+			Message: "Password reset token is expired or has already been used",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	responseErr = usersRepository.QueryUpdatePassword(resetToken.UserId, hashedPassword)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return "", responseErr
+	}
+
+	responseErr = passwordResetsRepository.QueryUseUserPasswordResetTokens(resetToken.UserId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return "", responseErr
+	}
+
+	_ = tx.Commit()
+
+	return resetToken.UserId, nil
+}
+
+// ExecUnlockAccountTx resets the failed login attempts of the account the unlock token was issued for
+func (th *TransactionHandler) ExecUnlockAccountTx(tokenHash string) *models.ResponseError {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	loginAttemptsRepository := NewLoginAttemptsRepository(tx)
+	usersRepository := NewUsersRepository(tx)
+
+	unlockToken, responseErr := loginAttemptsRepository.QueryGetAccountUnlockToken(tokenHash)
+
+	if responseErr != nil {
+		tx.Rollback()
+		if responseErr.Status == http.StatusNotFound {
+			return &models.ResponseError{
+				Message: "Invalid unlock token",
+				Status:  http.StatusBadRequest,
+			}
+		}
+		return responseErr
+	}
+
+	if unlockToken.UsedAt != nil || time.Now().After(unlockToken.ExpiresAt) {
+		tx.Rollback()
+		return &models.ResponseError{
+			Message: "Unlock token is expired or has already been used",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	user, responseErr := usersRepository.QueryGetUserById(unlockToken.UserId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return responseErr
+	}
+
+	responseErr = loginAttemptsRepository.QueryUseAccountUnlockToken(unlockToken.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return responseErr
+	}
+
+	responseErr = loginAttemptsRepository.QueryResetLoginAttempts(models.LoginAttemptScopeAccount, strings.ToLower(user.Email))
+
+	if responseErr != nil {
+		tx.Rollback()
+		return responseErr
+	}
+
+	_ = tx.Commit()
+
+	return nil
+}
+
+// ExecConfirmTotpTx enables two-factor authentication and stores the recovery codes of the user together
+func (th *TransactionHandler) ExecConfirmTotpTx(userId string, step int64, recoveryCodeHashes []string) *models.ResponseError {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	mfaRepository := NewMfaRepository(tx)
+
+	responseErr := mfaRepository.QueryConfirmUserTotp(userId, step)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return responseErr
+	}
+
+	responseErr = mfaRepository.QueryCreateRecoveryCodes(userId, recoveryCodeHashes)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return responseErr
+	}
+
+	_ = tx.Commit()
+
+	return nil
+}
+
+// ExecDeleteUserTx deletes the user. Events created by the user are deleted the same way as by ExecDeleteEventTx with the
+// given reason, so they are kept as tombstones without an owner. Registrations of the user for events of other users are cancelled
+// and their seats go to the waitlists. Tokens, keys and other user data are deleted by the cascade.
+// Returns the cancelled registrations of other users with their deleted event and the registrations of the users that were promoted from a waitlist
+func (th *TransactionHandler) ExecDeleteUserTx(userId string, reason string) ([]*models.Registration, []*models.Registration, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	usersRepository := NewUsersRepository(tx)
+	eventsRepository := NewEventsRepository(tx)
+	registrationsRepository := NewRegistrationsRepository(tx)
+	waitlistRepository := NewWaitlistRepository(tx)
+
+	userRegistrations, responseErr := registrationsRepository.QueryGetUserRegistrations(userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	userEvents, responseErr := eventsRepository.QueryGetUserEvents(userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	cancelledRegistrations := make([]*models.Registration, 0)
+	for _, event := range userEvents {
+		if event.DeletedAt != nil {
+			continue
+		}
+
+		deletedEvent, registrations, responseErr := deleteEvent(event.ID, reason, false, eventsRepository, registrationsRepository, waitlistRepository)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, nil, responseErr
+		}
+
+		// the user does not need to be told about the own event
+		for _, registration := range registrations {
+			if registration.UserId != userId {
+				registration.Event = deletedEvent
+				cancelledRegistrations = append(cancelledRegistrations, registration)
+			}
+		}
+	}
+
+	// events of the user are deleted, so only the seats of other events are handed out
+	freedEventIds := make([]string, 0)
+	for _, registration := range userRegistrations {
+		if registration.Event.UserId != userId {
+			freedEventIds = append(freedEventIds, registration.EventId)
+		}
+	}
+
+	responseErr = eventsRepository.QueryDecrementAmountRegistrationsOfUser(userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	responseErr = registrationsRepository.QueryDeleteUserRegistrations(userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	// the owner of the tombstones is cleared by the foreign key
+	responseErr = usersRepository.QueryDeleteUser(userId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	// the waitlist entries of the user are gone with the user, so the user cannot be promoted
+	promotedRegistrations, responseErr := promoteFromWaitlistOfEvents(freedEventIds, eventsRepository, registrationsRepository, waitlistRepository)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return cancelledRegistrations, promotedRegistrations, nil
+}
+
+// ExecEraseNextUserTx processes the oldest pending erasure request. The user is anonymized instead of deleted, so events created by
+// the user stay available to their attendees. Registrations of the user are cancelled and their seats go to the waitlists.
+// Returns nil if no request is pending, otherwise the processed request and the registrations of the users that were promoted from a waitlist
+func (th *TransactionHandler) ExecEraseNextUserTx() (*models.ErasureRequest, []*models.Registration, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	erasureRequestsRepository := NewErasureRequestsRepository(tx)
+	usersRepository := NewUsersRepository(tx)
+	eventsRepository := NewEventsRepository(tx)
+	registrationsRepository := NewRegistrationsRepository(tx)
+	loginAttemptsRepository := NewLoginAttemptsRepository(tx)
+	waitlistRepository := NewWaitlistRepository(tx)
+
+	erasureRequest, responseErr := erasureRequestsRepository.QueryGetNextPendingErasureRequest()
+
+	if responseErr != nil || erasureRequest == nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	user, responseErr := usersRepository.QueryGetUserById(erasureRequest.UserId)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	userRegistrations, responseErr := registrationsRepository.QueryGetUserRegistrations(user.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	responseErr = eventsRepository.QueryDecrementAmountRegistrationsOfUser(user.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	responseErr = registrationsRepository.QueryCancelUserRegistrations(user.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	responseErr = loginAttemptsRepository.QueryResetLoginAttempts(models.LoginAttemptScopeAccount, strings.ToLower(user.Email))
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	responseErr = usersRepository.QueryDeleteUserCredentials(user.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	responseErr = usersRepository.QueryAnonymizeUser(user.ID, fmt.Sprintf("erased-%s@erased.invalid", user.ID))
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	// the waitlist entries of the user have been deleted with the credentials, so the user cannot be promoted
+	freedEventIds := make([]string, 0, len(userRegistrations))
+	for _, registration := range userRegistrations {
+		freedEventIds = append(freedEventIds, registration.EventId)
+	}
+
+	promotedRegistrations, responseErr := promoteFromWaitlistOfEvents(freedEventIds, eventsRepository, registrationsRepository, waitlistRepository)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	responseErr = erasureRequestsRepository.QueryCompleteErasureRequest(erasureRequest.ID)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	return erasureRequest, promotedRegistrations, nil
+}
+
+// ExecOidcLoginTx returns the user of an external identity. On the first login a new user without password is created for the identity.
+// If a user with the same email address exists already, the identity is only linked if linkExistingUser is set and both the identity
+// provider and the user have verified the address, otherwise the login is rejected and the user is left unchanged
+func (th *TransactionHandler) ExecOidcLoginTx(issuer string, claims *dtos.OidcIdTokenClaims, roles []string, linkExistingUser bool) (*models.User, *models.ResponseError) {
+	tx, err := th.db.Begin()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	usersRepository := NewUsersRepository(tx)
+	rolesRepository := NewRolesRepository(tx)
+	userIdentitiesRepository := NewUserIdentitiesRepository(tx)
+
+	userIdentity, responseErr := userIdentitiesRepository.QueryGetUserIdentity(issuer, claims.Subject)
+
+	if responseErr == nil {
+		responseErr = userIdentitiesRepository.QueryUpdateLastLogin(userIdentity.ID)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, responseErr
+		}
+
+		user, responseErr := usersRepository.QueryGetUserById(userIdentity.UserId)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, responseErr
+		}
+
+		_ = tx.Commit()
+
+		return user, nil
+	}
+
+	if responseErr.Status != http.StatusNotFound {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	if claims.Email == "" || !claims.EmailVerified {
+		tx.Rollback()
+		return nil, &models.ResponseError{
+			Message: "Email address has not been verified by the identity provider",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	user, responseErr := usersRepository.QueryGetUser(claims.Email)
+
+	if responseErr != nil && responseErr.Status != http.StatusNotFound {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	if responseErr != nil {
+		// users that sign up with openid connect have no password
+		responseErr = usersRepository.QuerySignupUser(claims.Email, models.NoPassword)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, responseErr
+		}
+
+		user, responseErr = usersRepository.QueryGetUser(claims.Email)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, responseErr
+		}
+
+		for _, role := range roles {
+			responseErr = rolesRepository.QueryAssignRole(user.ID, role)
+
+			if responseErr != nil {
+				tx.Rollback()
+				return nil, responseErr
+			}
+		}
+
+		user.DisplayName = claims.Name
+
+		user, responseErr = usersRepository.QueryUpdateProfile(user)
+
+		if responseErr != nil {
+			tx.Rollback()
+			return nil, responseErr
+		}
+	} else if !linkExistingUser || !user.Verified {
+		tx.Rollback()
+		return nil, &models.ResponseError{
+			Message: "An account with this email address exists already, log in with its password",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	responseErr = usersRepository.QueryVerifyEmail(user.ID, claims.Email)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	responseErr = userIdentitiesRepository.QueryCreateUserIdentity(user.ID, issuer, claims.Subject, claims.Email)
+
+	if responseErr != nil {
+		tx.Rollback()
+		return nil, responseErr
+	}
+
+	_ = tx.Commit()
+
+	user.Verified = true
+
+	return user, nil
+}
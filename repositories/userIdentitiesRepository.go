@@ -0,0 +1,215 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type UserIdentitiesRepository struct {
+	db DBTX
+}
+
+func NewUserIdentitiesRepository(db DBTX) *UserIdentitiesRepository {
+	return &UserIdentitiesRepository{
+		db: db,
+	}
+}
+
+func (uir *UserIdentitiesRepository) QueryGetUserIdentity(issuer string, subject string) (*models.UserIdentity, *models.ResponseError) {
+	query := `
+		SELECT
+			id, user_id, issuer, subject, email, created_at, last_login_at
+		FROM
+			user_identities
+		WHERE
+			issuer = $1
+			AND
+			subject = $2`
+	row := uir.db.QueryRow(query, issuer, subject)
+
+	var userIdentity models.UserIdentity
+	err := row.Scan(&userIdentity.ID, &userIdentity.UserId, &userIdentity.Issuer, &userIdentity.Subject, &userIdentity.Email, &userIdentity.CreatedAt, &userIdentity.LastLoginAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Identity not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &userIdentity, nil
+}
+
+func (uir *UserIdentitiesRepository) QueryGetUserIdentities(userId string) ([]*models.UserIdentity, *models.ResponseError) {
+	query := `
+		SELECT
+			id, user_id, issuer, subject, email, created_at, last_login_at
+		FROM
+			user_identities
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at, id`
+	rows, err := uir.db.Query(query, userId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	identitiesList := make([]*models.UserIdentity, 0)
+
+	for rows.Next() {
+		var userIdentity models.UserIdentity
+		err = rows.Scan(&userIdentity.ID, &userIdentity.UserId, &userIdentity.Issuer, &userIdentity.Subject, &userIdentity.Email, &userIdentity.CreatedAt, &userIdentity.LastLoginAt)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		identitiesList = append(identitiesList, &userIdentity)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return identitiesList, nil
+}
+
+func (uir *UserIdentitiesRepository) QueryCreateUserIdentity(userId string, issuer string, subject string, email string) *models.ResponseError {
+	query := `
+		INSERT INTO
+			user_identities(user_id, issuer, subject, email, last_login_at)
+		VALUES
+			($1, $2, $3, $4, now())`
+	_, err := uir.db.Exec(query, userId, issuer, subject, email)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "unique constraint") {
+			return &models.ResponseError{
+				Message: "Identity already linked",
+				Status:  http.StatusConflict,
+			}
+		}
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (uir *UserIdentitiesRepository) QueryUpdateLastLogin(identityId string) *models.ResponseError {
+	query := `
+		UPDATE
+			user_identities
+		SET
+			last_login_at = now()
+		WHERE
+			id = $1`
+	_, err := uir.db.Exec(query, identityId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (uir *UserIdentitiesRepository) QueryCreateOidcAuthRequest(stateHash string, nonce string, codeVerifier string, expiresAt time.Time) *models.ResponseError {
+	query := `
+		INSERT INTO
+			oidc_auth_requests(state_hash, nonce, code_verifier, expires_at)
+		VALUES
+			($1, $2, $3, $4)`
+	_, err := uir.db.Exec(query, stateHash, nonce, codeVerifier, expiresAt)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// QueryConsumeOidcAuthRequest deletes and returns the pending login, so every state can only be used once
+func (uir *UserIdentitiesRepository) QueryConsumeOidcAuthRequest(stateHash string) (*models.OidcAuthRequest, *models.ResponseError) {
+	query := `
+		DELETE FROM
+			oidc_auth_requests
+		WHERE
+			state_hash = $1
+		RETURNING
+			state_hash, nonce, code_verifier, expires_at`
+	row := uir.db.QueryRow(query, stateHash)
+
+	var authRequest models.OidcAuthRequest
+	err := row.Scan(&authRequest.StateHash, &authRequest.Nonce, &authRequest.CodeVerifier, &authRequest.ExpiresAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Invalid or expired login",
+				Status:  http.StatusUnauthorized,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if authRequest.ExpiresAt.Before(time.Now()) {
+		return nil, &models.ResponseError{
+			Message: "Invalid or expired login",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	return &authRequest, nil
+}
+
+func (uir *UserIdentitiesRepository) QueryDeleteExpiredOidcAuthRequests() *models.ResponseError {
+	query := `
+		DELETE FROM
+			oidc_auth_requests
+		WHERE
+			expires_at < now()`
+	_, err := uir.db.Exec(query)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+var _ UserIdentitiesRepositoryInterface = (*UserIdentitiesRepository)(nil)
@@ -0,0 +1,22 @@
+package repositories
+
+import (
+	"eventom-backend/models"
+	"time"
+)
+
+type UserIdentitiesRepositoryInterface interface {
+	QueryGetUserIdentity(issuer string, subject string) (*models.UserIdentity, *models.ResponseError)
+
+	QueryGetUserIdentities(userId string) ([]*models.UserIdentity, *models.ResponseError)
+
+	QueryCreateUserIdentity(userId string, issuer string, subject string, email string) *models.ResponseError
+
+	QueryUpdateLastLogin(identityId string) *models.ResponseError
+
+	QueryCreateOidcAuthRequest(stateHash string, nonce string, codeVerifier string, expiresAt time.Time) *models.ResponseError
+
+	QueryConsumeOidcAuthRequest(stateHash string) (*models.OidcAuthRequest, *models.ResponseError)
+
+	QueryDeleteExpiredOidcAuthRequests() *models.ResponseError
+}
@@ -3,15 +3,16 @@ package repositories
 import (
 	"database/sql"
 	"eventom-backend/models"
+	"fmt"
 	"net/http"
 	"strings"
 )
 
 type UsersRepository struct {
-	db *sql.DB
+	db DBTX
 }
 
-func NewUsersRepository(db *sql.DB) *UsersRepository {
+func NewUsersRepository(db DBTX) *UsersRepository {
 	return &UsersRepository{
 		db: db,
 	}
@@ -44,7 +45,7 @@ func (ur *UsersRepository) QuerySignupUser(email string, hashedPassword string)
 func (ur *UsersRepository) QueryGetUser(email string) (*models.User, *models.ResponseError) {
 	query := `
 		SELECT
-			*
+			id, email, password, verified, display_name, timezone, locale
 		FROM
 			users
 		WHERE
@@ -52,7 +53,7 @@ func (ur *UsersRepository) QueryGetUser(email string) (*models.User, *models.Res
 	row := ur.db.QueryRow(query, email)
 
 	var user models.User
-	err := row.Scan(&user.ID, &user.Email, &user.Password)
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Verified, &user.DisplayName, &user.Timezone, &user.Locale)
 
 	if err != nil {
 		if err == sql.ErrNoRows {
@@ -70,4 +71,211 @@ func (ur *UsersRepository) QueryGetUser(email string) (*models.User, *models.Res
 	return &user, nil
 }
 
+func (ur *UsersRepository) QueryGetUserById(userId string) (*models.User, *models.ResponseError) {
+	query := `
+		SELECT
+			id, email, password, verified, display_name, timezone, locale
+		FROM
+			users
+		WHERE
+			id = $1`
+	row := ur.db.QueryRow(query, userId)
+
+	var user models.User
+	err := row.Scan(&user.ID, &user.Email, &user.Password, &user.Verified, &user.DisplayName, &user.Timezone, &user.Locale)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "User not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &user, nil
+}
+
+// QueryVerifyEmail marks the user as verified and sets the verified address as email of the user
+func (ur *UsersRepository) QueryVerifyEmail(userId string, email string) *models.ResponseError {
+	query := `
+		UPDATE
+			users
+		SET
+			email = $2,
+			verified = true
+		WHERE
+			id = $1`
+	result, err := ur.db.Exec(query, userId, email)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "unique constraint") {
+			return &models.ResponseError{
+				Message: "Email already registered",
+				Status:  http.StatusConflict,
+			}
+		}
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "User not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+func (ur *UsersRepository) QueryUpdatePassword(userId string, hashedPassword string) *models.ResponseError {
+	query := `
+		UPDATE
+			users
+		SET
+			password = $2
+		WHERE
+			id = $1`
+	_, err := ur.db.Exec(query, userId, hashedPassword)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+func (ur *UsersRepository) QueryUpdateProfile(user *models.User) (*models.User, *models.ResponseError) {
+	query := `
+		UPDATE
+			users
+		SET
+			display_name = $2,
+			timezone = $3,
+			locale = $4
+		WHERE
+			id = $1
+		RETURNING
+			id, email, password, verified, display_name, timezone, locale`
+	row := ur.db.QueryRow(query, user.ID, user.DisplayName, user.Timezone, user.Locale)
+
+	var updatedUser models.User
+	err := row.Scan(&updatedUser.ID, &updatedUser.Email, &updatedUser.Password, &updatedUser.Verified, &updatedUser.DisplayName, &updatedUser.Timezone, &updatedUser.Locale)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "User not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &updatedUser, nil
+}
+
+func (ur *UsersRepository) QueryDeleteUser(userId string) *models.ResponseError {
+	query := `
+		DELETE FROM
+			users
+		WHERE
+			id = $1`
+	_, err := ur.db.Exec(query, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// QueryAnonymizeUser replaces all personal data of the user. The password is removed, so nobody can log in anymore
+func (ur *UsersRepository) QueryAnonymizeUser(userId string, anonymizedEmail string) *models.ResponseError {
+	query := `
+		UPDATE
+			users
+		SET
+			email = $2,
+			password = $3,
+			verified = false,
+			display_name = '',
+			timezone = 'UTC',
+			locale = 'en',
+			erased_at = now()
+		WHERE
+			id = $1`
+	_, err := ur.db.Exec(query, userId, anonymizedEmail, models.NoPassword)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// userCredentialTables hold credentials and personal data that are linked to a user by the column user_id
+var userCredentialTables = []string{
+	"refresh_tokens",
+	"api_keys",
+	"user_totp",
+	"email_verification_tokens",
+	"password_reset_tokens",
+	"account_unlock_tokens",
+	"user_identities",
+	"sessions",
+	"waitlist_entries",
+	"user_roles",
+	"calendar_feed_tokens",
+}
+
+// QueryDeleteUserCredentials deletes everything the user could log in with or that could be used to contact the user
+func (ur *UsersRepository) QueryDeleteUserCredentials(userId string) *models.ResponseError {
+	for _, table := range userCredentialTables {
+		query := fmt.Sprintf(`
+			DELETE FROM
+				%s
+			WHERE
+				user_id = $1`, table)
+		_, err := ur.db.Exec(query, userId)
+
+		if err != nil {
+			return &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+	}
+
+	return nil
+}
+
 var _ UsersRepositoryInterface = (*UsersRepository)(nil)
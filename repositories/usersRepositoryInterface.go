@@ -6,4 +6,18 @@ type UsersRepositoryInterface interface {
 	QuerySignupUser(email string, password string) *models.ResponseError
 
 	QueryGetUser(email string) (*models.User, *models.ResponseError)
+
+	QueryGetUserById(userId string) (*models.User, *models.ResponseError)
+
+	QueryVerifyEmail(userId string, email string) *models.ResponseError
+
+	QueryUpdatePassword(userId string, hashedPassword string) *models.ResponseError
+
+	QueryUpdateProfile(user *models.User) (*models.User, *models.ResponseError)
+
+	QueryDeleteUser(userId string) *models.ResponseError
+
+	QueryAnonymizeUser(userId string, anonymizedEmail string) *models.ResponseError
+
+	QueryDeleteUserCredentials(userId string) *models.ResponseError
 }
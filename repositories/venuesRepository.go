@@ -0,0 +1,194 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/utils"
+	"net/http"
+)
+
+const venueColumns = `id, venue_name, address, latitude, longitude, user_id, created_at`
+
+func venueFields(venue *models.Venue) []any {
+	return []any{
+		&venue.ID,
+		&venue.Name,
+		&venue.Address,
+		&venue.Latitude,
+		&venue.Longitude,
+		&venue.UserId,
+		&venue.CreatedAt,
+	}
+}
+
+type VenuesRepository struct {
+	db DBTX
+}
+
+func NewVenuesRepository(db DBTX) *VenuesRepository {
+	return &VenuesRepository{
+		db: db,
+	}
+}
+
+func (vr *VenuesRepository) QueryCreateVenue(venue *models.Venue) (*models.Venue, *models.ResponseError) {
+	query := `
+		INSERT INTO
+			venues(venue_name, address, latitude, longitude, user_id)
+		VALUES
+			($1, $2, $3, $4, $5)
+		RETURNING
+			` + venueColumns
+	row := vr.db.QueryRow(query, venue.Name, venue.Address, venue.Latitude, venue.Longitude, venue.UserId)
+
+	var createdVenue models.Venue
+	err := row.Scan(venueFields(&createdVenue)...)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &createdVenue, nil
+}
+
+func (vr *VenuesRepository) QueryGetVenue(venueId string) (*models.Venue, *models.ResponseError) {
+	query := `
+		SELECT
+			` + venueColumns + `
+		FROM
+			venues
+		WHERE
+			id = $1`
+	row := vr.db.QueryRow(query, venueId)
+
+	var venue models.Venue
+	err := row.Scan(venueFields(&venue)...)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Venue not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &venue, nil
+}
+
+// QueryGetVenues returns a page of the venues whose name or address contains the search term and the total amount of matching venues.
+// All venues match an empty search term
+func (vr *VenuesRepository) QueryGetVenues(filters *dtos.VenueFilterDto) ([]*models.Venue, int, *models.ResponseError) {
+	query := `
+		SELECT
+			COUNT(*) OVER(),
+			` + venueColumns + `
+		FROM
+			venues
+		WHERE
+			(venue_name ILIKE '%' || $1 || '%' OR address ILIKE '%' || $1 || '%' OR $1 = '')
+		ORDER BY
+			venue_name, id
+		LIMIT
+			$2
+		OFFSET
+			$3`
+	offset := filters.PageSize * (filters.Page - 1)
+	rows, err := vr.db.Query(query, utils.EscapeLike(filters.Search), filters.PageSize, offset)
+
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	totalCount := 0
+	venuesList := make([]*models.Venue, 0)
+
+	for rows.Next() {
+		var venue models.Venue
+		err = rows.Scan(append([]any{&totalCount}, venueFields(&venue)...)...)
+		if err != nil {
+			return nil, 0, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		venuesList = append(venuesList, &venue)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, 0, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return venuesList, totalCount, nil
+}
+
+// QueryGetUserVenues returns the venues created by the user
+func (vr *VenuesRepository) QueryGetUserVenues(userId string) ([]*models.Venue, *models.ResponseError) {
+	query := `
+		SELECT
+			` + venueColumns + `
+		FROM
+			venues
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at, id`
+
+	return vr.queryVenues(query, userId)
+}
+
+func (vr *VenuesRepository) queryVenues(query string, args ...any) ([]*models.Venue, *models.ResponseError) {
+	rows, err := vr.db.Query(query, args...)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	venuesList := make([]*models.Venue, 0)
+
+	for rows.Next() {
+		var venue models.Venue
+		err = rows.Scan(venueFields(&venue)...)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		venuesList = append(venuesList, &venue)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return venuesList, nil
+}
+
+var _ VenuesRepositoryInterface = (*VenuesRepository)(nil)
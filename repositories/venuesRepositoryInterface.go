@@ -0,0 +1,16 @@
+package repositories
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
+
+type VenuesRepositoryInterface interface {
+	QueryCreateVenue(venue *models.Venue) (*models.Venue, *models.ResponseError)
+
+	QueryGetVenue(venueId string) (*models.Venue, *models.ResponseError)
+
+	QueryGetVenues(filters *dtos.VenueFilterDto) ([]*models.Venue, int, *models.ResponseError)
+
+	QueryGetUserVenues(userId string) ([]*models.Venue, *models.ResponseError)
+}
@@ -0,0 +1,236 @@
+package repositories
+
+import (
+	"database/sql"
+	"eventom-backend/models"
+	"net/http"
+	"strings"
+)
+
+// waitlistEntryColumns selects an entry together with its position, entries are served first come first served
+const waitlistEntryColumns = `
+	waitlist_entries.id, waitlist_entries.event_id, waitlist_entries.user_id, waitlist_entries.created_at,
+	(
+		SELECT
+			COUNT(*)
+		FROM
+			waitlist_entries AS ahead
+		WHERE
+			ahead.event_id = waitlist_entries.event_id
+			AND
+			(ahead.created_at, ahead.id) <= (waitlist_entries.created_at, waitlist_entries.id)
+	)`
+
+type WaitlistRepository struct {
+	db DBTX
+}
+
+func NewWaitlistRepository(db DBTX) *WaitlistRepository {
+	return &WaitlistRepository{
+		db: db,
+	}
+}
+
+func (wr *WaitlistRepository) QueryCreateWaitlistEntry(eventId string, userId string) (*models.WaitlistEntry, *models.ResponseError) {
+	query := `
+		INSERT INTO
+			waitlist_entries(event_id, user_id)
+		VALUES
+			($1, $2)
+		RETURNING
+			id`
+	row := wr.db.QueryRow(query, eventId, userId)
+
+	var entryId string
+	err := row.Scan(&entryId)
+
+	if err != nil {
+		if strings.Contains(err.Error(), "unique constraint") {
+			return nil, &models.ResponseError{
+				Message: "User is already on the waitlist of this event",
+				Status:  http.StatusConflict,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return wr.QueryGetWaitlistEntry(eventId, userId)
+}
+
+func (wr *WaitlistRepository) QueryGetWaitlistEntry(eventId string, userId string) (*models.WaitlistEntry, *models.ResponseError) {
+	query := `
+		SELECT
+			` + waitlistEntryColumns + `
+		FROM
+			waitlist_entries
+		WHERE
+			event_id = $1
+			AND
+			user_id = $2`
+	row := wr.db.QueryRow(query, eventId, userId)
+
+	var entry models.WaitlistEntry
+	err := row.Scan(&entry.ID, &entry.EventId, &entry.UserId, &entry.CreatedAt, &entry.Position)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, &models.ResponseError{
+				Message: "Waitlist entry not found",
+				Status:  http.StatusNotFound,
+			}
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &entry, nil
+}
+
+// QueryGetUserWaitlistEntries returns all events the user is waiting for together with the current position
+func (wr *WaitlistRepository) QueryGetUserWaitlistEntries(userId string) ([]*models.WaitlistEntry, *models.ResponseError) {
+	query := `
+		SELECT
+			` + waitlistEntryColumns + `
+		FROM
+			waitlist_entries
+		WHERE
+			user_id = $1
+		ORDER BY
+			created_at, id`
+	rows, err := wr.db.Query(query, userId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	defer rows.Close()
+
+	entriesList := make([]*models.WaitlistEntry, 0)
+
+	for rows.Next() {
+		var entry models.WaitlistEntry
+		err = rows.Scan(&entry.ID, &entry.EventId, &entry.UserId, &entry.CreatedAt, &entry.Position)
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+		entriesList = append(entriesList, &entry)
+	}
+
+	err = rows.Err()
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return entriesList, nil
+}
+
+func (wr *WaitlistRepository) QueryDeleteWaitlistEntry(eventId string, userId string) *models.ResponseError {
+	query := `
+		DELETE FROM
+			waitlist_entries
+		WHERE
+			event_id = $1
+			AND
+			user_id = $2`
+	result, err := wr.db.Exec(query, eventId, userId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rowsAffected, err := result.RowsAffected()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	if rowsAffected == 0 {
+		return &models.ResponseError{
+			Message: "Waitlist entry not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return nil
+}
+
+func (wr *WaitlistRepository) QueryDeleteEventWaitlist(eventId string) *models.ResponseError {
+	query := `
+		DELETE FROM
+			waitlist_entries
+		WHERE
+			event_id = $1`
+	_, err := wr.db.Exec(query, eventId)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// QueryPopWaitlistEntry removes the first entry of the waitlist of the event and returns it. Returns nil if nobody is waiting.
+// The event has to be locked by the caller, otherwise two transactions could hand out the same seat
+func (wr *WaitlistRepository) QueryPopWaitlistEntry(eventId string) (*models.WaitlistEntry, *models.ResponseError) {
+	query := `
+		DELETE FROM
+			waitlist_entries
+		WHERE
+			id = (
+				SELECT
+					id
+				FROM
+					waitlist_entries
+				WHERE
+					event_id = $1
+				ORDER BY
+					created_at, id
+				LIMIT
+					1
+			)
+		RETURNING
+			id, event_id, user_id, created_at`
+	row := wr.db.QueryRow(query, eventId)
+
+	var entry models.WaitlistEntry
+	err := row.Scan(&entry.ID, &entry.EventId, &entry.UserId, &entry.CreatedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	entry.Position = 1
+
+	return &entry, nil
+}
+
+var _ WaitlistRepositoryInterface = (*WaitlistRepository)(nil)
@@ -0,0 +1,17 @@
+package repositories
+
+import "eventom-backend/models"
+
+type WaitlistRepositoryInterface interface {
+	QueryCreateWaitlistEntry(eventId string, userId string) (*models.WaitlistEntry, *models.ResponseError)
+
+	QueryGetWaitlistEntry(eventId string, userId string) (*models.WaitlistEntry, *models.ResponseError)
+
+	QueryGetUserWaitlistEntries(userId string) ([]*models.WaitlistEntry, *models.ResponseError)
+
+	QueryDeleteWaitlistEntry(eventId string, userId string) *models.ResponseError
+
+	QueryDeleteEventWaitlist(eventId string) *models.ResponseError
+
+	QueryPopWaitlistEntry(eventId string) (*models.WaitlistEntry, *models.ResponseError)
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"eventom-backend/services"
+	"log"
+	"os"
+)
+
+// InitAdmin grants the admin role to the user configured by ADMIN_EMAIL. The user has to sign up and verify the address first,
+// until then the app starts without promoting anyone
+func InitAdmin(rolesService services.RolesServiceInterface) {
+	email := os.Getenv("ADMIN_EMAIL")
+
+	if email == "" {
+		log.Println("No admin email configured, admins have to be assigned by another admin")
+		return
+	}
+
+	responseErr := rolesService.BootstrapAdmin(email)
+
+	if responseErr != nil {
+		log.Printf("Could not grant the admin role to %s: %s", email, responseErr.Message)
+		return
+	}
+
+	log.Printf("Granted the admin role to %s", email)
+}
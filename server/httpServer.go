@@ -4,60 +4,174 @@ import (
 	"database/sql"
 	"eventom-backend/controllers"
 	"eventom-backend/middlewares"
+	"eventom-backend/models"
 	"eventom-backend/repositories"
 	"eventom-backend/services"
 	"eventom-backend/utils"
 	"log"
 	"net/http"
 	"os"
-	"path/filepath"
+	"time"
 )
 
 func InitHttpServer(db *sql.DB) *http.Server {
-	// initialize private key that is used to sign and verify jwt
-	err := utils.ReadPrivateKeyFromFile(filepath.Join("./", "app/", os.Getenv("PRIVATE_KEY_PATH")))
-	if err != nil {
-		log.Fatalf("Error while reading private key: %v", err)
-	}
-
-	// initialize protected routes map that is used in auth middleware to determine whether a request needs to be authenticated or not
-	utils.SetProtectedRoutes()
-
 	logger := utils.NewLogger(os.Stdout)
 
+	// initialize the keys that are used to sign and verify jwt
+	InitSigningKeys(logger)
+
 	transactionHandler := repositories.NewTxHandler(db)
 
 	eventsRepository := repositories.NewEventsRepository(db)
+	eventSeriesRepository := repositories.NewEventSeriesRepository(db)
 	usersRepository := repositories.NewUsersRepository(db)
 	registrationsRepository := repositories.NewRegistrationsRepository(db)
+	refreshTokensRepository := repositories.NewRefreshTokensRepository(db)
+	revokedTokensRepository := repositories.NewRevokedTokensRepository(db)
+	sessionsRepository := repositories.NewSessionsRepository(db)
+	rolesRepository := repositories.NewRolesRepository(db)
+	emailVerificationsRepository := repositories.NewEmailVerificationsRepository(db)
+	passwordResetsRepository := repositories.NewPasswordResetsRepository(db)
+	loginAttemptsRepository := repositories.NewLoginAttemptsRepository(db)
+	mfaRepository := repositories.NewMfaRepository(db)
+	apiKeysRepository := repositories.NewApiKeysRepository(db)
+	erasureRequestsRepository := repositories.NewErasureRequestsRepository(db)
+	userIdentitiesRepository := repositories.NewUserIdentitiesRepository(db)
+	waitlistRepository := repositories.NewWaitlistRepository(db)
+	calendarFeedTokensRepository := repositories.NewCalendarFeedTokensRepository(db)
+	venuesRepository := repositories.NewVenuesRepository(db)
+
+	mailer := InitMailer()
+	oidcProvider := InitOidcProvider()
+
+	// attendees are told by mail when they get a seat from a waitlist or lose it because the event is deleted
+	registrationNotifier := services.NewMailRegistrationNotifier(usersRepository, eventsRepository, mailer, logger)
 
-	eventsService := services.NewEventsService(eventsRepository)
-	usersService := services.NewUsersService(usersRepository)
-	registrationsService := services.NewRegistrationsService(registrationsRepository, *transactionHandler)
+	tokensService := services.NewTokensService(refreshTokensRepository, revokedTokensRepository, sessionsRepository, rolesRepository, *transactionHandler)
+	eventsService := services.NewEventsService(eventsRepository, eventSeriesRepository, *transactionHandler, registrationNotifier)
+	loginAttemptsService := services.NewLoginAttemptsService(loginAttemptsRepository, usersRepository, *transactionHandler, mailer)
+	mfaService := services.NewMfaService(mfaRepository, usersRepository, *transactionHandler)
+	usersService := services.NewUsersService(usersRepository, emailVerificationsRepository, passwordResetsRepository, tokensService, loginAttemptsService, mfaService, *transactionHandler, mailer, registrationNotifier)
+	rolesService := services.NewRolesService(rolesRepository, usersRepository)
+	apiKeysService := services.NewApiKeysService(apiKeysRepository, rolesRepository)
+	registrationsService := services.NewRegistrationsService(registrationsRepository, waitlistRepository, eventsRepository, *transactionHandler, registrationNotifier)
+	calendarService := services.NewCalendarService(eventsRepository, calendarFeedTokensRepository)
+	venuesService := services.NewVenuesService(venuesRepository)
+	privacyService := services.NewPrivacyService(usersRepository, rolesRepository, apiKeysRepository, eventsRepository, registrationsRepository, erasureRequestsRepository, sessionsRepository, userIdentitiesRepository, waitlistRepository, calendarFeedTokensRepository, eventSeriesRepository, venuesRepository, mfaService, tokensService, *transactionHandler, registrationNotifier)
+
+	// load revoked tokens into the in-memory cache that is consulted by the auth middleware and keep it in sync
+	responseErr := tokensService.LoadRevocations()
+	if responseErr != nil {
+		log.Fatalf("Error while loading revoked tokens: %v", responseErr.Message)
+	}
+	tokensService.StartRevocationSweeper(time.Minute, logger)
+
+	// the user with ADMIN_EMAIL is made admin on every start, so a fresh installation has someone who can assign roles
+	InitAdmin(rolesService)
+
+	// erasure requests are processed in the background, so the request returns right away
+	privacyService.StartErasureJob(time.Minute, logger)
 
 	eventsController := controllers.NewEventsController(eventsService, logger)
 	usersController := controllers.NewUsersController(usersService, logger)
 	registrationsController := controllers.NewRegistrationsController(registrationsService, logger)
+	tokensController := controllers.NewTokensController(tokensService, logger)
+	rolesController := controllers.NewRolesController(rolesService, logger)
+	mfaController := controllers.NewMfaController(mfaService, logger)
+	apiKeysController := controllers.NewApiKeysController(apiKeysService, logger)
+	privacyController := controllers.NewPrivacyController(privacyService, logger)
+	calendarController := controllers.NewCalendarController(calendarService, logger)
+	venuesController := controllers.NewVenuesController(venuesService, logger)
 
+	// routes without policy are public, all others require a logged in user or an api key with the listed permissions.
+	// Routes that manage credentials require a logged in user
+	policy := middlewares.NewPolicy(tokensService, logger)
 	router := http.NewServeMux()
 
-	router.HandleFunc("POST /events", eventsController.HandleCreateEvent)
-	router.HandleFunc("GET /events/{id}", eventsController.HandleGetEvent)
+	router.HandleFunc("POST /events", policy.RequirePermissions(eventsController.HandleCreateEvent, models.PermissionEventsWrite))
+	// GET /events/{id}.ics returns the event as iCalendar file
+	router.HandleFunc("GET /events/{id}", controllers.HandleCalendarFile("id", calendarController.HandleGetEventCalendar, eventsController.HandleGetEvent))
 	router.HandleFunc("GET /events", eventsController.HandleGetAllEvents)
-	router.HandleFunc("PUT /events/{id}", eventsController.HandleUpdateEvent)
-	router.HandleFunc("DELETE /events/{id}", eventsController.HandleDeleteEvent)
+	router.HandleFunc("PUT /events/{id}", policy.RequirePermissions(eventsController.HandleUpdateEvent, models.PermissionEventsWrite))
+	router.HandleFunc("DELETE /events/{id}", policy.RequirePermissions(eventsController.HandleDeleteEvent, models.PermissionEventsWrite))
+	router.HandleFunc("GET /events/{id}/registrations", policy.RequirePermissions(registrationsController.HandleGetEventAttendees, models.PermissionEventsWrite))
+	router.HandleFunc("GET /events/{id}/attendees.csv", policy.RequirePermissions(registrationsController.HandleExportAttendeesCsv, models.PermissionEventsWrite))
+	router.HandleFunc("GET /events/{id}/attendees.jsonl", policy.RequirePermissions(registrationsController.HandleExportAttendeesJsonLines, models.PermissionEventsWrite))
+	router.HandleFunc("DELETE /events/{id}/registrations", policy.RequirePermissions(registrationsController.HandleCancelEventRegistration, models.PermissionRegistrationsWrite))
+	router.HandleFunc("POST /event-series", policy.RequirePermissions(eventsController.HandleCreateEventSeries, models.PermissionEventsWrite))
+	router.HandleFunc("GET /event-series/{id}", eventsController.HandleGetEventSeries)
+
+	router.HandleFunc("POST /venues", policy.RequirePermissions(venuesController.HandleCreateVenue, models.PermissionEventsWrite))
+	router.HandleFunc("GET /venues", venuesController.HandleGetVenues)
+	router.HandleFunc("GET /venues/{id}", venuesController.HandleGetVenue)
 
 	router.HandleFunc("POST /signup", usersController.HandleSignupUser)
+	router.HandleFunc("GET /verify-email", usersController.HandleVerifyEmail)
+	router.HandleFunc("POST /verify-email/resend", usersController.HandleResendVerificationEmail)
 	router.HandleFunc("POST /login", usersController.HandleLoginUser)
-	router.HandleFunc("POST /logout", usersController.HandleLogoutUser)
+	router.HandleFunc("POST /login/mfa", usersController.HandleLoginUserMfa)
+
+	// login with openid connect is only available if an identity provider is configured
+	if oidcProvider != nil {
+		oidcService := services.NewOidcService(oidcProvider, userIdentitiesRepository, mfaService, tokensService, *transactionHandler)
+		oidcController := controllers.NewOidcController(oidcService, logger)
+
+		router.HandleFunc("GET /login/oidc", oidcController.HandleOidcLogin)
+		router.HandleFunc("GET /login/oidc/callback", oidcController.HandleOidcCallback)
+	}
+
+	router.HandleFunc("GET /unlock-account", usersController.HandleUnlockAccount)
+	router.HandleFunc("POST /users/{id}/unlock", policy.RequirePermissions(usersController.HandleUnlockUser, models.PermissionUsersManage))
+	router.HandleFunc("POST /password/forgot", usersController.HandleForgotPassword)
+	router.HandleFunc("GET /password/reset", usersController.HandleShowPasswordResetForm)
+	router.HandleFunc("POST /password/reset", usersController.HandleResetPassword)
+	router.HandleFunc("POST /logout", policy.Authenticated(usersController.HandleLogoutUser))
+	router.HandleFunc("POST /logout/all", policy.RequireSession(usersController.HandleLogoutUserEverywhere))
+
+	router.HandleFunc("GET /me", policy.Authenticated(usersController.HandleGetMe))
+	router.HandleFunc("PATCH /me", policy.Authenticated(usersController.HandleUpdateMe))
+	router.HandleFunc("POST /me/password", policy.RequireSession(usersController.HandleChangePassword))
+	router.HandleFunc("POST /me/email", policy.RequireSession(usersController.HandleChangeEmail))
+	router.HandleFunc("DELETE /me", policy.RequireSession(usersController.HandleDeleteMe))
+	router.HandleFunc("GET /me/registrations", policy.RequirePermissions(registrationsController.HandleGetMyRegistrations, models.PermissionRegistrationsRead))
+	router.HandleFunc("GET /me/sessions", policy.RequireSession(tokensController.HandleGetSessions))
+	router.HandleFunc("DELETE /me/sessions/{id}", policy.RequireSession(tokensController.HandleRevokeSession))
+	router.HandleFunc("GET /me/export", policy.RequireSession(privacyController.HandleExportMe))
+	router.HandleFunc("POST /me/erasure", policy.RequireSession(privacyController.HandleRequestErasureMe))
+	router.HandleFunc("GET /users/{id}/export", policy.RequirePermissions(privacyController.HandleExportUser, models.PermissionUsersManage))
+	router.HandleFunc("POST /users/{id}/erasure", policy.RequirePermissions(privacyController.HandleRequestErasureUser, models.PermissionUsersManage))
+
+	router.HandleFunc("POST /mfa/totp", policy.RequireSession(mfaController.HandleEnrollTotp))
+	router.HandleFunc("POST /mfa/totp/confirm", policy.RequireSession(mfaController.HandleConfirmTotp))
+	router.HandleFunc("DELETE /mfa/totp", policy.RequireSession(mfaController.HandleDisableTotp))
+
+	router.HandleFunc("POST /api-keys", policy.RequireSession(apiKeysController.HandleCreateApiKey))
+	router.HandleFunc("GET /api-keys", policy.RequireSession(apiKeysController.HandleGetApiKeys))
+	router.HandleFunc("PATCH /api-keys/{id}", policy.RequireSession(apiKeysController.HandleRenameApiKey))
+	router.HandleFunc("DELETE /api-keys/{id}", policy.RequireSession(apiKeysController.HandleRevokeApiKey))
+
+	router.HandleFunc("POST /token/refresh", tokensController.HandleRefreshToken)
+	router.HandleFunc("GET /.well-known/jwks.json", tokensController.HandleGetJwks)
+
+	router.HandleFunc("POST /registrations", policy.RequirePermissions(registrationsController.HandleRegisterUserForEvent, models.PermissionRegistrationsWrite))
+	router.HandleFunc("GET /registrations", policy.RequirePermissions(registrationsController.HandleGetAllRegistrations, models.PermissionRegistrationsManageAny))
+	router.HandleFunc("DELETE /registrations/{id}", policy.RequirePermissions(registrationsController.HandleCancleRegistration, models.PermissionRegistrationsWrite))
+	router.HandleFunc("POST /waitlist", policy.RequirePermissions(registrationsController.HandleJoinWaitlist, models.PermissionRegistrationsWrite))
+	router.HandleFunc("GET /waitlist", policy.RequirePermissions(registrationsController.HandleGetWaitlistEntries, models.PermissionRegistrationsRead))
+	router.HandleFunc("DELETE /waitlist/{id}", policy.RequirePermissions(registrationsController.HandleLeaveWaitlist, models.PermissionRegistrationsWrite))
+
+	router.HandleFunc("GET /calendar/{token}", controllers.HandleCalendarFile("token", calendarController.HandleGetCalendarFeed, http.NotFound))
+	router.HandleFunc("POST /me/calendar", policy.RequireSession(calendarController.HandleCreateCalendarFeed))
+	router.HandleFunc("DELETE /me/calendar", policy.RequireSession(calendarController.HandleDeleteCalendarFeed))
 
-	router.HandleFunc("POST /registrations", registrationsController.HandleRegisterUserForEvent)
-	router.HandleFunc("GET /registrations", registrationsController.HandleGetAllRegistrations)
-	router.HandleFunc("DELETE /registrations/{id}", registrationsController.HandleCancleRegistration)
+	router.HandleFunc("GET /roles", policy.RequirePermissions(rolesController.HandleGetRoles, models.PermissionUsersManage))
+	router.HandleFunc("GET /users/{id}/roles", policy.RequirePermissions(rolesController.HandleGetUserRoles, models.PermissionUsersManage))
+	router.HandleFunc("PUT /users/{id}/roles/{role}", policy.RequirePermissions(rolesController.HandleAssignRole, models.PermissionUsersManage))
+	router.HandleFunc("DELETE /users/{id}/roles/{role}", policy.RequirePermissions(rolesController.HandleRemoveRole, models.PermissionUsersManage))
 
 	middlewareStack := middlewares.CreateStack(
 		middlewares.RateLimiterMiddleware,
-		middlewares.AuthMiddleware,
+		middlewares.NewAuthMiddleware(tokensService, apiKeysService),
 	)
 
 	return &http.Server{
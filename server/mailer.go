@@ -0,0 +1,42 @@
+package server
+
+import (
+	"eventom-backend/mailers"
+	"log"
+	"os"
+)
+
+// InitMailer creates the mailer configured by MAILER_TYPE, which is either smtp, file or memory. The app does not start without a
+// mailer, otherwise verification and password reset mails would be lost without anyone noticing
+func InitMailer() mailers.Mailer {
+	from := os.Getenv("MAIL_FROM")
+
+	switch mailerType := os.Getenv("MAILER_TYPE"); mailerType {
+	case "smtp":
+		if os.Getenv("SMTP_HOST") == "" || os.Getenv("SMTP_PORT") == "" {
+			log.Fatal("Error while initializing smtp mailer: SMTP_HOST and SMTP_PORT are required")
+		}
+		return mailers.NewSmtpMailer(
+			os.Getenv("SMTP_HOST"),
+			os.Getenv("SMTP_PORT"),
+			os.Getenv("SMTP_USERNAME"),
+			os.Getenv("SMTP_PASSWORD"),
+			from,
+		)
+	case "file":
+		fileMailer, err := mailers.NewFileMailer(os.Getenv("MAIL_DIRECTORY"), from)
+		if err != nil {
+			log.Fatalf("Error while initializing file mailer: %v", err)
+		}
+		return fileMailer
+	case "memory":
+		log.Println("Mails are kept in memory and are not delivered")
+		return mailers.NewInMemoryMailer()
+	case "":
+		log.Fatal("No mailer configured, set MAILER_TYPE to smtp, file or memory")
+	default:
+		log.Fatalf("Unknown mailer type %s, set MAILER_TYPE to smtp, file or memory", mailerType)
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"eventom-backend/utils"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const defaultOidcScopes = "openid email profile"
+
+// InitOidcProvider connects to the openid provider configured by OIDC_ISSUER and OIDC_CLIENT_ID. Returns nil if openid connect is not configured
+func InitOidcProvider() *utils.OidcProvider {
+	issuer := os.Getenv("OIDC_ISSUER")
+
+	if issuer == "" {
+		log.Println("No openid provider configured, login with openid connect is disabled")
+		return nil
+	}
+
+	redirectUrl := os.Getenv("OIDC_REDIRECT_URL")
+	if redirectUrl == "" {
+		redirectUrl = utils.BuildAppUrl("/login/oidc/callback", nil)
+	}
+
+	scopes := os.Getenv("OIDC_SCOPES")
+	if scopes == "" {
+		scopes = defaultOidcScopes
+	}
+
+	oidcProvider, err := utils.NewOidcProvider(utils.OidcConfig{
+		Issuer:       issuer,
+		ClientId:     os.Getenv("OIDC_CLIENT_ID"),
+		ClientSecret: os.Getenv("OIDC_CLIENT_SECRET"),
+		RedirectUrl:  redirectUrl,
+		Scopes:       strings.Fields(scopes),
+		// linking trusts the identity provider to have verified the email address, so it has to be enabled explicitly
+		LinkExistingUsers: os.Getenv("OIDC_LINK_EXISTING_USERS") == "true",
+	}, &http.Client{Timeout: 10 * time.Second})
+
+	if err != nil {
+		log.Fatalf("Error while initializing openid provider: %v", err)
+	}
+
+	return oidcProvider
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"eventom-backend/utils"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const defaultKeyActivationDelay = 10 * time.Minute
+const defaultKeyReloadInterval = time.Minute
+
+// InitSigningKeys loads the keys that sign and verify jwt. With KEY_DIRECTORY set, the directory is rescanned
+// every KEY_RELOAD_INTERVAL and new keys sign tokens after KEY_ACTIVATION_DELAY. Otherwise the single key of PRIVATE_KEY_PATH is used
+func InitSigningKeys(logger *utils.Logger) {
+	keyDirectory := os.Getenv("KEY_DIRECTORY")
+
+	if keyDirectory == "" {
+		err := utils.ReadPrivateKeyFromFile(filepath.Join("./", "app/", os.Getenv("PRIVATE_KEY_PATH")))
+		if err != nil {
+			log.Fatalf("Error while reading private key: %v", err)
+		}
+		return
+	}
+
+	err := utils.LoadKeyDirectory(filepath.Join("./", "app/", keyDirectory), durationFromEnv("KEY_ACTIVATION_DELAY", defaultKeyActivationDelay))
+	if err != nil {
+		log.Fatalf("Error while reading signing keys: %v", err)
+	}
+
+	utils.StartKeyRingReloader(durationFromEnv("KEY_RELOAD_INTERVAL", defaultKeyReloadInterval), logger)
+}
+
+func durationFromEnv(name string, defaultDuration time.Duration) time.Duration {
+	value := os.Getenv(name)
+
+	if value == "" {
+		return defaultDuration
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		log.Fatalf("Error while parsing %s: %v", name, err)
+	}
+
+	return duration
+}
@@ -0,0 +1,152 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// apiKeyDisplayLength is the number of leading characters that are stored in plain text to tell keys apart
+const apiKeyDisplayLength = 12
+
+type ApiKeysService struct {
+	apiKeysRepository repositories.ApiKeysRepositoryInterface
+	rolesRepository   repositories.RolesRepositoryInterface
+}
+
+func NewApiKeysService(apiKeysRepository repositories.ApiKeysRepositoryInterface, rolesRepository repositories.RolesRepositoryInterface) *ApiKeysService {
+	return &ApiKeysService{
+		apiKeysRepository: apiKeysRepository,
+		rolesRepository:   rolesRepository,
+	}
+}
+
+// CreateApiKey creates a key that is limited to the given scopes. Users can only grant scopes they have the permission for
+func (aks ApiKeysService) CreateApiKey(userId string, apiKeyCreate *dtos.ApiKeyCreateDto) (*dtos.CreatedApiKey, *models.ResponseError) {
+	if apiKeyCreate.ExpiresAt != nil && apiKeyCreate.ExpiresAt.Before(time.Now()) {
+		return nil, &models.ResponseError{
+			Message: "Expiration date must be in the future",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	permissions, responseErr := aks.rolesRepository.QueryGetUserPermissions(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	scopes := make([]string, 0, len(apiKeyCreate.Scopes))
+	for _, scope := range apiKeyCreate.Scopes {
+		if !slices.Contains(permissions, scope) {
+			return nil, &models.ResponseError{
+				Message: fmt.Sprintf("Scope %s is not granted to the user", scope),
+				Status:  http.StatusForbidden,
+			}
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+
+	opaqueToken, err := utils.GenerateOpaqueToken()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	key := models.ApiKeyPrefix + opaqueToken
+
+	apiKey := &models.ApiKey{
+		UserId:    userId,
+		Name:      apiKeyCreate.Name,
+		KeyPrefix: key[:apiKeyDisplayLength],
+		KeyHash:   utils.HashToken(key),
+		Scopes:    scopes,
+		ExpiresAt: apiKeyCreate.ExpiresAt,
+	}
+
+	responseErr = aks.apiKeysRepository.QueryCreateApiKey(apiKey)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return &dtos.CreatedApiKey{
+		ApiKey: apiKey,
+		Key:    key,
+	}, nil
+}
+
+func (aks ApiKeysService) GetApiKeys(userId string) ([]*models.ApiKey, *models.ResponseError) {
+	return aks.apiKeysRepository.QueryGetUserApiKeys(userId)
+}
+
+func (aks ApiKeysService) RenameApiKey(userId string, apiKeyId string, name string) (*models.ApiKey, *models.ResponseError) {
+	return aks.apiKeysRepository.QueryRenameApiKey(userId, apiKeyId, name)
+}
+
+func (aks ApiKeysService) RevokeApiKey(userId string, apiKeyId string) *models.ResponseError {
+	return aks.apiKeysRepository.QueryRevokeApiKey(userId, apiKeyId)
+}
+
+// AuthenticateApiKey returns the claims of the key owner. The permissions are the scopes of the key that the owner still holds,
+// so keys lose permissions together with the roles of their owner
+func (aks ApiKeysService) AuthenticateApiKey(key string) (*dtos.AccessTokenClaims, *models.ResponseError) {
+	if !strings.HasPrefix(key, models.ApiKeyPrefix) {
+		return nil, &models.ResponseError{
+			Message: "Invalid api key",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	apiKey, responseErr := aks.apiKeysRepository.QueryUseApiKey(utils.HashToken(key))
+
+	if responseErr != nil {
+		if responseErr.Status == http.StatusNotFound {
+			return nil, &models.ResponseError{
+				Message: "Invalid api key",
+				Status:  http.StatusUnauthorized,
+			}
+		}
+		return nil, responseErr
+	}
+
+	roles, responseErr := aks.rolesRepository.QueryGetUserRoles(apiKey.UserId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	userPermissions, responseErr := aks.rolesRepository.QueryGetUserPermissions(apiKey.UserId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	permissions := make([]string, 0, len(apiKey.Scopes))
+	for _, scope := range apiKey.Scopes {
+		if slices.Contains(userPermissions, scope) {
+			permissions = append(permissions, scope)
+		}
+	}
+
+	return &dtos.AccessTokenClaims{
+		UserId:      apiKey.UserId,
+		Roles:       roles,
+		Permissions: permissions,
+		IssuedAt:    apiKey.CreatedAt,
+		ApiKeyId:    apiKey.ID,
+	}, nil
+}
+
+var _ ApiKeysServiceInterface = (*ApiKeysService)(nil)
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
+
+type ApiKeysServiceInterface interface {
+	CreateApiKey(userId string, apiKeyCreate *dtos.ApiKeyCreateDto) (*dtos.CreatedApiKey, *models.ResponseError)
+
+	GetApiKeys(userId string) ([]*models.ApiKey, *models.ResponseError)
+
+	RenameApiKey(userId string, apiKeyId string, name string) (*models.ApiKey, *models.ResponseError)
+
+	RevokeApiKey(userId string, apiKeyId string) *models.ResponseError
+
+	AuthenticateApiKey(key string) (*dtos.AccessTokenClaims, *models.ResponseError)
+}
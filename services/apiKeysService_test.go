@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/testutils"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type ApiKeysServiceTestSuite struct {
+	suite.Suite
+	ctx                context.Context
+	apiKeysService     ApiKeysServiceInterface
+	transactionHandler *repositories.TransactionHandler
+	userId             string
+}
+
+func TestApiKeysServiceSuite(t *testing.T) {
+	suite.Run(t, &ApiKeysServiceTestSuite{})
+}
+
+func (suite *ApiKeysServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	apiKeysRepository := repositories.NewApiKeysRepository(testutils.TestContainer.DB)
+	rolesRepository := repositories.NewRolesRepository(testutils.TestContainer.DB)
+	suite.transactionHandler = repositories.NewTxHandler(testutils.TestContainer.DB)
+	suite.apiKeysService = NewApiKeysService(apiKeysRepository, rolesRepository)
+}
+
+func (suite *ApiKeysServiceTestSuite) BeforeTest(suiteName, testName string) {
+	// clear users table before every test, api keys are deleted by the cascade
+	query := `
+		DELETE FROM
+			users`
+	_, err := testutils.TestContainer.DB.Exec(query)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	user, responseErr := suite.transactionHandler.ExecSignupUserTx("test@test.com", "Test123", []string{models.RoleOrganizer})
+	require.Nil(suite.T(), responseErr)
+
+	suite.userId = user.ID
+}
+
+func (suite *ApiKeysServiceTestSuite) TestCreateApiKeyFailScopeNotGranted() {
+	createdApiKey, err := suite.apiKeysService.CreateApiKey(suite.userId, &dtos.ApiKeyCreateDto{
+		Name:   "script",
+		Scopes: []string{models.PermissionUsersManage},
+	})
+
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 403, err.Status)
+	assert.Nil(suite.T(), createdApiKey)
+}
+
+func (suite *ApiKeysServiceTestSuite) TestAuthenticateApiKey() {
+	createdApiKey, err := suite.apiKeysService.CreateApiKey(suite.userId, &dtos.ApiKeyCreateDto{
+		Name:   "script",
+		Scopes: []string{models.PermissionEventsWrite},
+	})
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), len(createdApiKey.Key) > len(createdApiKey.KeyPrefix))
+
+	claims, err := suite.apiKeysService.AuthenticateApiKey(createdApiKey.Key)
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.userId, claims.UserId)
+	assert.Equal(suite.T(), createdApiKey.ID, claims.ApiKeyId)
+	assert.Equal(suite.T(), []string{models.PermissionEventsWrite}, claims.Permissions)
+
+	apiKeysList, err := suite.apiKeysService.GetApiKeys(suite.userId)
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), apiKeysList, 1)
+	assert.NotNil(suite.T(), apiKeysList[0].LastUsedAt)
+}
+
+func (suite *ApiKeysServiceTestSuite) TestAuthenticateRevokedApiKey() {
+	createdApiKey, err := suite.apiKeysService.CreateApiKey(suite.userId, &dtos.ApiKeyCreateDto{
+		Name:   "script",
+		Scopes: []string{models.PermissionEventsWrite},
+	})
+	require.Nil(suite.T(), err)
+
+	err = suite.apiKeysService.RevokeApiKey(suite.userId, createdApiKey.ID)
+	assert.Nil(suite.T(), err)
+
+	claims, err := suite.apiKeysService.AuthenticateApiKey(createdApiKey.Key)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+	assert.Nil(suite.T(), claims)
+}
+
+func (suite *ApiKeysServiceTestSuite) TestAuthenticateApiKeyAfterUserTokensRevoked() {
+	createdApiKey, err := suite.apiKeysService.CreateApiKey(suite.userId, &dtos.ApiKeyCreateDto{
+		Name:   "script",
+		Scopes: []string{models.PermissionEventsWrite},
+	})
+	require.Nil(suite.T(), err)
+
+	// logging out everywhere or changing the password revokes the api keys as well
+	_, err = suite.transactionHandler.ExecRevokeUserTokensTx(suite.userId, time.Now())
+	require.Nil(suite.T(), err)
+
+	claims, err := suite.apiKeysService.AuthenticateApiKey(createdApiKey.Key)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+	assert.Nil(suite.T(), claims)
+}
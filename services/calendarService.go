@@ -0,0 +1,118 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/utils"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// calendarUidDomain makes the uids of events globally unique as required by RFC 5545. It must not change, otherwise calendar apps
+// show every event twice
+const calendarUidDomain = "eventom"
+
+const calendarFeedName = "eventom"
+
+type CalendarService struct {
+	eventsRepository             repositories.EventsRepositoryInterface
+	calendarFeedTokensRepository repositories.CalendarFeedTokensRepositoryInterface
+}
+
+func NewCalendarService(
+	eventsRepository repositories.EventsRepositoryInterface,
+	calendarFeedTokensRepository repositories.CalendarFeedTokensRepositoryInterface,
+) *CalendarService {
+	return &CalendarService{
+		eventsRepository:             eventsRepository,
+		calendarFeedTokensRepository: calendarFeedTokensRepository,
+	}
+}
+
+// GetEventCalendar returns an iCalendar file with the given event
+func (cs CalendarService) GetEventCalendar(eventId string) ([]byte, *models.ResponseError) {
+	if uuid.Validate(eventId) != nil {
+		return nil, &models.ResponseError{
+			Message: "Event not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	event, responseErr := cs.eventsRepository.QueryGetEvent(eventId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return utils.BuildCalendar("", []*utils.CalendarEvent{toCalendarEvent(event)}, time.Now()), nil
+}
+
+// CreateCalendarFeed creates the calendar feed of the user. Calendar apps can subscribe to the feed without logging in,
+// so creating a new feed revokes the url of the previous one
+func (cs CalendarService) CreateCalendarFeed(userId string) (*dtos.CalendarFeed, *models.ResponseError) {
+	token, err := utils.GenerateOpaqueToken()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	responseErr := cs.calendarFeedTokensRepository.QuerySaveCalendarFeedToken(userId, utils.HashToken(token))
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return &dtos.CalendarFeed{
+		Url: utils.BuildAppUrl("/calendar/"+token+".ics", nil),
+	}, nil
+}
+
+func (cs CalendarService) DeleteCalendarFeed(userId string) *models.ResponseError {
+	return cs.calendarFeedTokensRepository.QueryDeleteCalendarFeedToken(userId)
+}
+
+// GetCalendarFeed returns an iCalendar file with the events the owner of the token is registered for. Events that were deleted
+// by the organizer are kept as cancelled, so calendar apps remove them
+func (cs CalendarService) GetCalendarFeed(token string) ([]byte, *models.ResponseError) {
+	userId, responseErr := cs.calendarFeedTokensRepository.QueryGetCalendarFeedUserId(utils.HashToken(token))
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	events, responseErr := cs.eventsRepository.QueryGetUserCalendarEvents(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	calendarEvents := make([]*utils.CalendarEvent, 0, len(events))
+	for _, event := range events {
+		calendarEvents = append(calendarEvents, toCalendarEvent(event))
+	}
+
+	return utils.BuildCalendar(calendarFeedName, calendarEvents, time.Now()), nil
+}
+
+func toCalendarEvent(event *models.Event) *utils.CalendarEvent {
+	return &utils.CalendarEvent{
+		Uid:          event.ID + "@" + calendarUidDomain,
+		Sequence:     event.Revision,
+		Summary:      event.Name,
+		Description:  event.Description,
+		Location:     event.Location,
+		Url:          utils.BuildAppUrl("/events/"+event.ID, nil),
+		Start:        event.StartsAt,
+		End:          event.EndsAt,
+		LastModified: event.UpdatedAt,
+		Cancelled:    event.DeletedAt != nil,
+	}
+}
+
+var _ CalendarServiceInterface = (*CalendarService)(nil)
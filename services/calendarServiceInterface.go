@@ -0,0 +1,16 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
+
+type CalendarServiceInterface interface {
+	GetEventCalendar(eventId string) ([]byte, *models.ResponseError)
+
+	CreateCalendarFeed(userId string) (*dtos.CalendarFeed, *models.ResponseError)
+
+	DeleteCalendarFeed(userId string) *models.ResponseError
+
+	GetCalendarFeed(token string) ([]byte, *models.ResponseError)
+}
@@ -0,0 +1,151 @@
+package services
+
+import (
+	"context"
+	"eventom-backend/mailers"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/testutils"
+	"eventom-backend/utils"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type CalendarServiceTestSuite struct {
+	suite.Suite
+	ctx                  context.Context
+	calendarService      CalendarServiceInterface
+	eventsService        EventsServiceInterface
+	registrationsService RegistrationsServiceInterface
+	usersRepository      *repositories.UsersRepository
+	eventsRepository     *repositories.EventsRepository
+}
+
+func TestCalendarServiceSuite(t *testing.T) {
+	suite.Run(t, &CalendarServiceTestSuite{})
+}
+
+func (suite *CalendarServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	suite.usersRepository = repositories.NewUsersRepository(testutils.TestContainer.DB)
+	suite.eventsRepository = repositories.NewEventsRepository(testutils.TestContainer.DB)
+	eventSeriesRepository := repositories.NewEventSeriesRepository(testutils.TestContainer.DB)
+	registrationsRepository := repositories.NewRegistrationsRepository(testutils.TestContainer.DB)
+	waitlistRepository := repositories.NewWaitlistRepository(testutils.TestContainer.DB)
+	calendarFeedTokensRepository := repositories.NewCalendarFeedTokensRepository(testutils.TestContainer.DB)
+	transactionHandler := repositories.NewTxHandler(testutils.TestContainer.DB)
+	registrationNotifier := NewMailRegistrationNotifier(suite.usersRepository, suite.eventsRepository, mailers.NewInMemoryMailer(), utils.NewLogger(os.Stdout))
+	suite.calendarService = NewCalendarService(suite.eventsRepository, calendarFeedTokensRepository)
+	suite.eventsService = NewEventsService(suite.eventsRepository, eventSeriesRepository, *transactionHandler, registrationNotifier)
+	suite.registrationsService = NewRegistrationsService(registrationsRepository, waitlistRepository, suite.eventsRepository, *transactionHandler, registrationNotifier)
+}
+
+func (suite *CalendarServiceTestSuite) BeforeTest(suiteName, testName string) {
+	// events do not cascade on users, so they have to be removed before the users
+	queries := []string{
+		`DELETE FROM registrations`,
+		`DELETE FROM events`,
+		`DELETE FROM users`,
+	}
+
+	for _, query := range queries {
+		_, err := testutils.TestContainer.DB.Exec(query)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func (suite *CalendarServiceTestSuite) TestGetEventCalendar() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, "Test event", organizer.ID, 10)
+
+	calendar, responseErr := suite.calendarService.GetEventCalendar(event.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Contains(suite.T(), string(calendar), "UID:"+event.ID+"@eventom\r\n")
+	assert.Contains(suite.T(), string(calendar), "SEQUENCE:0\r\nSTATUS:CONFIRMED\r\n")
+
+	_, responseErr = suite.calendarService.GetEventCalendar("no-uuid")
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusNotFound, responseErr.Status)
+}
+
+func (suite *CalendarServiceTestSuite) TestCalendarFeed() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	updatedEvent := createTestEvent(suite.T(), suite.eventsRepository, "Test event", organizer.ID, 10)
+	deletedEvent := createTestEvent(suite.T(), suite.eventsRepository, "Test event", organizer.ID, 10)
+	otherEvent := createTestEvent(suite.T(), suite.eventsRepository, "Test event", organizer.ID, 10)
+
+	for _, event := range []*models.Event{updatedEvent, deletedEvent} {
+		_, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+		require.Nil(suite.T(), responseErr)
+	}
+
+	calendarFeed, responseErr := suite.calendarService.CreateCalendarFeed(attendee.ID)
+	require.Nil(suite.T(), responseErr)
+	token := suite.feedToken(calendarFeed.Url)
+
+	calendar, responseErr := suite.calendarService.GetCalendarFeed(token)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 2, strings.Count(string(calendar), "BEGIN:VEVENT"))
+	assert.NotContains(suite.T(), string(calendar), otherEvent.ID)
+
+	// updates increase the sequence and deleted events stay in the feed as cancelled
+	updatedEvent.Name = "Updated event"
+	_, responseErr = suite.eventsService.UpdateEvent(organizer.ID, false, updatedEvent, models.EventEditScopeSingle, false)
+	require.Nil(suite.T(), responseErr)
+
+	responseErr = suite.eventsService.DeleteEvent(organizer.ID, false, deletedEvent.ID, "", false)
+	require.Nil(suite.T(), responseErr)
+
+	calendar, responseErr = suite.calendarService.GetCalendarFeed(token)
+	require.Nil(suite.T(), responseErr)
+	assert.Contains(suite.T(), string(calendar), "UID:"+updatedEvent.ID+"@eventom\r\n")
+	assert.Contains(suite.T(), string(calendar), "SUMMARY:Updated event\r\n")
+	assert.Equal(suite.T(), 2, strings.Count(string(calendar), "SEQUENCE:1\r\n"))
+	assert.Equal(suite.T(), 1, strings.Count(string(calendar), "STATUS:CANCELLED\r\n"))
+
+	// a new feed revokes the previous url
+	_, responseErr = suite.calendarService.CreateCalendarFeed(attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	_, responseErr = suite.calendarService.GetCalendarFeed(token)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusNotFound, responseErr.Status)
+
+	responseErr = suite.calendarService.DeleteCalendarFeed(attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	responseErr = suite.calendarService.DeleteCalendarFeed(attendee.ID)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusNotFound, responseErr.Status)
+}
+
+func (suite *CalendarServiceTestSuite) feedToken(feedUrl string) string {
+	token, found := strings.CutSuffix(feedUrl[strings.LastIndex(feedUrl, "/")+1:], ".ics")
+	require.True(suite.T(), found)
+
+	return token
+}
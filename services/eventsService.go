@@ -4,20 +4,56 @@ import (
 	"eventom-backend/dtos"
 	"eventom-backend/models"
 	"eventom-backend/repositories"
+	"eventom-backend/utils"
+	"fmt"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
 )
 
+const maxDeletionReasonLength = 500
+
+// maxFacetValues limits the amount of categories and tags that are counted for the event list
+const maxFacetValues = 20
+
+// maxSeriesOccurrences limits the occurrences that are created for a series, e.g. a daily event for a year
+const maxSeriesOccurrences = 366
+
 type EventsService struct {
-	eventsRepository repositories.EventsRepositoryInterface
+	eventsRepository      repositories.EventsRepositoryInterface
+	eventSeriesRepository repositories.EventSeriesRepositoryInterface
+	transactionHandler    repositories.TransactionHandler
+	registrationNotifier  RegistrationNotifier
 }
 
-func NewEventsService(eventsRepository repositories.EventsRepositoryInterface) *EventsService {
+func NewEventsService(
+	eventsRepository repositories.EventsRepositoryInterface,
+	eventSeriesRepository repositories.EventSeriesRepositoryInterface,
+	transactionHandler repositories.TransactionHandler,
+	registrationNotifier RegistrationNotifier,
+) *EventsService {
 	return &EventsService{
-		eventsRepository: eventsRepository,
+		eventsRepository:      eventsRepository,
+		eventSeriesRepository: eventSeriesRepository,
+		transactionHandler:    transactionHandler,
+		registrationNotifier:  registrationNotifier,
 	}
 }
 
 func (es EventsService) CreateEvent(event *models.Event) (*models.Event, *models.ResponseError) {
+	if event.Timezone == "" {
+		event.Timezone = models.DefaultEventTimezone
+	}
+
+	if event.Language == "" {
+		event.Language = models.DefaultEventLanguage
+	}
+
+	event.Tags = normalizeTags(event.Tags)
+
 	return es.eventsRepository.QueryCreateEvent(event)
 }
 
@@ -26,41 +62,219 @@ func (es EventsService) GetEvent(eventId string) (*models.Event, *models.Respons
 }
 
 func (es EventsService) GetAllEvents(eventFilters *dtos.EventFilterDto) ([]*models.Event, int, *models.ResponseError) {
+	eventFilters.Tags = normalizeTags(eventFilters.Tags)
+
 	return es.eventsRepository.QueryGetAllEvents(eventFilters)
 }
 
-func (es EventsService) UpdateEvent(userId string, event *models.Event) (*models.Event, *models.ResponseError) {
+// GetEventFacets counts the events that match the filters per category and per tag
+func (es EventsService) GetEventFacets(eventFilters *dtos.EventFilterDto) (*dtos.EventFacets, *models.ResponseError) {
+	eventFilters.Tags = normalizeTags(eventFilters.Tags)
+
+	return es.eventsRepository.QueryGetEventFacets(eventFilters, maxFacetValues)
+}
+
+// CreateEventSeries creates a recurring event for the given user with an occurrence for every date of the recurrence rule
+func (es EventsService) CreateEventSeries(userId string, seriesDto *dtos.CreateEventSeriesDto) (*models.EventSeries, *models.ResponseError) {
+	recurrenceRule, err := utils.ParseRecurrenceRule(seriesDto.RecurrenceRule)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	firstOccurrence := &models.Event{
+		Name:        seriesDto.Name,
+		Description: seriesDto.Description,
+		Location:    seriesDto.Location,
+		VenueId:     seriesDto.VenueId,
+		Category:    seriesDto.Category,
+		Tags:        normalizeTags(seriesDto.Tags),
+		StartsAt:    seriesDto.StartsAt,
+		EndsAt:      seriesDto.EndsAt,
+		Timezone:    seriesDto.Timezone,
+		Language:    seriesDto.Language,
+		MaxCapacity: seriesDto.MaxCapacity,
+		UserId:      userId,
+	}
+
+	if firstOccurrence.Timezone == "" {
+		firstOccurrence.Timezone = models.DefaultEventTimezone
+	}
+
+	if firstOccurrence.Language == "" {
+		firstOccurrence.Language = models.DefaultEventLanguage
+	}
+
+	// the rule is expanded on the local dates, so an evening event does not move to another day in UTC
+	dates, err := recurrenceRule.Occurrences(firstOccurrence.LocalDate(), seriesDto.ExceptionDates, maxSeriesOccurrences)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if len(dates) == 0 {
+		return nil, &models.ResponseError{
+			Message: "Recurrence rule does not produce any occurrences",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	series := &models.EventSeries{
+		UserId:         userId,
+		RecurrenceRule: seriesDto.RecurrenceRule,
+		StartDate:      firstOccurrence.LocalDate(),
+		Timezone:       firstOccurrence.Timezone,
+		ExceptionDates: seriesDto.ExceptionDates,
+	}
+
+	occurrences := make([]*models.Event, 0, len(dates))
+	for _, date := range dates {
+		occurrences = append(occurrences, firstOccurrence.AtDate(date))
+	}
+
+	return es.transactionHandler.ExecCreateEventSeriesTx(series, occurrences)
+}
+
+// GetEventSeries returns the series together with its occurrences that were not deleted
+func (es EventsService) GetEventSeries(seriesId string) (*models.EventSeries, *models.ResponseError) {
+	if uuid.Validate(seriesId) != nil {
+		return nil, &models.ResponseError{
+			Message: "Event series not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	series, responseErr := es.eventSeriesRepository.QueryGetEventSeries(seriesId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	series.Occurrences, responseErr = es.eventsRepository.QueryGetSeriesOccurrences(series.ID, time.Time{})
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return series, nil
+}
+
+// UpdateEvent updates the given event if it was created by the given user. manageAny allows to update events of other users.
+// allowOverbooking allows to lower the capacity below the amount of registrations, see ExecUpdateEventTx. For occurrences of
+// a series the scope selects whether only this occurrence, this and the following ones or all occurrences are changed
+func (es EventsService) UpdateEvent(userId string, manageAny bool, event *models.Event, scope string, allowOverbooking bool) (*models.Event, *models.ResponseError) {
 	existingEvent, responseErr := es.eventsRepository.QueryGetEvent(event.ID)
 
 	if responseErr != nil {
 		return nil, responseErr
 	}
 
-	if existingEvent.UserId != userId {
+	if existingEvent.UserId != userId && !manageAny {
 		return nil, &models.ResponseError{
 			Message: "Access denied",
 			Status:  http.StatusUnauthorized,
 		}
 	}
 
-	return es.eventsRepository.QueryUpdateEvent(event)
+	if event.Timezone == "" {
+		event.Timezone = models.DefaultEventTimezone
+	}
+
+	if event.Language == "" {
+		event.Language = models.DefaultEventLanguage
+	}
+
+	event.Tags = normalizeTags(event.Tags)
+
+	var updatedEvent *models.Event
+	var promotedRegistrations []*models.Registration
+
+	switch scope {
+	case models.EventEditScopeSingle:
+		updatedEvent, promotedRegistrations, responseErr = es.transactionHandler.ExecUpdateEventTx(event, allowOverbooking)
+	case models.EventEditScopeFollowing, models.EventEditScopeAll:
+		updatedEvent, promotedRegistrations, responseErr = es.transactionHandler.ExecUpdateEventSeriesTx(event, scope, allowOverbooking)
+	default:
+		responseErr = &models.ResponseError{
+			Message: fmt.Sprintf("Invalid scope %s", scope),
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	notifyPromotions(es.registrationNotifier, promotedRegistrations)
+
+	return updatedEvent, nil
 }
 
-func (es EventsService) DeleteEvent(userId string, eventId string) *models.ResponseError {
+// DeleteEvent deletes the given event if it was created by the given user. manageAny allows to delete events of other users.
+// All registrations are cancelled and the attendees are notified with the given reason. The event is kept as tombstone, only users
+// with manageAny can purge it together with its history
+func (es EventsService) DeleteEvent(userId string, manageAny bool, eventId string, reason string, purge bool) *models.ResponseError {
+	if len(reason) > maxDeletionReasonLength {
+		return &models.ResponseError{
+			Message: fmt.Sprintf("Reason must not be longer than %d characters", maxDeletionReasonLength),
+			Status:  http.StatusBadRequest,
+		}
+	}
+
 	event, responseErr := es.eventsRepository.QueryGetEvent(eventId)
 
 	if responseErr != nil {
 		return responseErr
 	}
 
-	if event.UserId != userId {
+	if event.UserId != userId && !manageAny {
 		return &models.ResponseError{
 			Message: "Access denied",
 			Status:  http.StatusUnauthorized,
 		}
 	}
 
-	return es.eventsRepository.QueryDeleteEvent(event.ID)
+	if purge && !manageAny {
+		return &models.ResponseError{
+			Message: "Access denied",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	deletedEvent, cancelledRegistrations, responseErr := es.transactionHandler.ExecDeleteEventTx(event.ID, reason, purge)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	// the mails are sent in the background, the deletion must not wait for a mail per attendee
+	go func() {
+		for _, registration := range cancelledRegistrations {
+			es.registrationNotifier.NotifyCancellation(registration, deletedEvent)
+		}
+	}()
+
+	return nil
+}
+
+// normalizeTags trims the tags and converts them to lower case, so tags only differing in case are counted together
+func normalizeTags(tags []string) []string {
+	normalizedTags := make([]string, 0, len(tags))
+
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag != "" && !slices.Contains(normalizedTags, tag) {
+			normalizedTags = append(normalizedTags, tag)
+		}
+	}
+
+	return normalizedTags
 }
 
 var _ EventsServiceInterface = (*EventsService)(nil)
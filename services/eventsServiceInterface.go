@@ -12,7 +12,13 @@ type EventsServiceInterface interface {
 
 	GetAllEvents(eventFilters *dtos.EventFilterDto) ([]*models.Event, int, *models.ResponseError)
 
-	UpdateEvent(userId string, event *models.Event) (*models.Event, *models.ResponseError)
+	GetEventFacets(eventFilters *dtos.EventFilterDto) (*dtos.EventFacets, *models.ResponseError)
 
-	DeleteEvent(userId string, eventId string) *models.ResponseError
+	CreateEventSeries(userId string, seriesDto *dtos.CreateEventSeriesDto) (*models.EventSeries, *models.ResponseError)
+
+	GetEventSeries(seriesId string) (*models.EventSeries, *models.ResponseError)
+
+	UpdateEvent(userId string, manageAny bool, event *models.Event, scope string, allowOverbooking bool) (*models.Event, *models.ResponseError)
+
+	DeleteEvent(userId string, manageAny bool, eventId string, reason string, purge bool) *models.ResponseError
 }
@@ -0,0 +1,623 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"eventom-backend/dtos"
+	"eventom-backend/mailers"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/testutils"
+	"eventom-backend/utils"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type EventsServiceTestSuite struct {
+	suite.Suite
+	ctx                  context.Context
+	eventsService        EventsServiceInterface
+	registrationsService RegistrationsServiceInterface
+	usersRepository      *repositories.UsersRepository
+	eventsRepository     *repositories.EventsRepository
+	venuesRepository     *repositories.VenuesRepository
+	mailer               *mailers.InMemoryMailer
+}
+
+func TestEventsServiceSuite(t *testing.T) {
+	suite.Run(t, &EventsServiceTestSuite{})
+}
+
+func (suite *EventsServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	suite.usersRepository = repositories.NewUsersRepository(testutils.TestContainer.DB)
+	suite.eventsRepository = repositories.NewEventsRepository(testutils.TestContainer.DB)
+	suite.venuesRepository = repositories.NewVenuesRepository(testutils.TestContainer.DB)
+	suite.mailer = mailers.NewInMemoryMailer()
+	registrationsRepository := repositories.NewRegistrationsRepository(testutils.TestContainer.DB)
+	waitlistRepository := repositories.NewWaitlistRepository(testutils.TestContainer.DB)
+	eventSeriesRepository := repositories.NewEventSeriesRepository(testutils.TestContainer.DB)
+	transactionHandler := repositories.NewTxHandler(testutils.TestContainer.DB)
+	registrationNotifier := NewMailRegistrationNotifier(suite.usersRepository, suite.eventsRepository, suite.mailer, utils.NewLogger(os.Stdout))
+	suite.eventsService = NewEventsService(suite.eventsRepository, eventSeriesRepository, *transactionHandler, registrationNotifier)
+	suite.registrationsService = NewRegistrationsService(registrationsRepository, waitlistRepository, suite.eventsRepository, *transactionHandler, registrationNotifier)
+}
+
+func (suite *EventsServiceTestSuite) BeforeTest(suiteName, testName string) {
+	// events do not cascade on users, so they have to be removed before the users
+	queries := []string{
+		`DELETE FROM registrations`,
+		`DELETE FROM events`,
+		`DELETE FROM event_series`,
+		`DELETE FROM venues`,
+		`DELETE FROM users`,
+	}
+
+	for _, query := range queries {
+		_, err := testutils.TestContainer.DB.Exec(query)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func (suite *EventsServiceTestSuite) TestDeleteEventWithRegistrations() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, "Test event", organizer.ID, 10)
+
+	registration, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	responseErr = suite.eventsService.DeleteEvent(organizer.ID, false, event.ID, "The venue is closed", false)
+	require.Nil(suite.T(), responseErr)
+
+	_, responseErr = suite.eventsService.GetEvent(event.ID)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusNotFound, responseErr.Status)
+
+	cancelledRegistration, responseErr := suite.registrationsService.GetRegistration(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Nil(suite.T(), cancelledRegistration)
+
+	// the tombstone and the record of the cancelled registration are kept
+	var reason string
+	err := testutils.TestContainer.DB.QueryRow(`SELECT reason FROM cancelled_registrations WHERE id = $1`, registration.ID).Scan(&reason)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), models.CancellationReasonEventDeleted, reason)
+
+	organizerEvents, responseErr := suite.eventsRepository.QueryGetUserEvents(organizer.ID)
+	require.Nil(suite.T(), responseErr)
+	require.Len(suite.T(), organizerEvents, 1)
+	assert.NotNil(suite.T(), organizerEvents[0].DeletedAt)
+	assert.Equal(suite.T(), "The venue is closed", organizerEvents[0].DeletionReason)
+
+	// the attendee is told in the background
+	require.Eventually(suite.T(), func() bool {
+		messages := suite.mailer.Messages()
+		return len(messages) > 0 && messages[len(messages)-1].To == attendee.Email
+	}, 5*time.Second, 10*time.Millisecond)
+
+	messages := suite.mailer.Messages()
+	assert.True(suite.T(), strings.Contains(messages[len(messages)-1].Body, "The venue is closed"))
+
+	_, responseErr = suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusNotFound, responseErr.Status)
+}
+
+func (suite *EventsServiceTestSuite) TestDeleteEventPurge() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, "Test event", organizer.ID, 10)
+
+	_, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	responseErr = suite.eventsService.DeleteEvent(organizer.ID, false, event.ID, "", true)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusForbidden, responseErr.Status)
+
+	responseErr = suite.eventsService.DeleteEvent(organizer.ID, true, event.ID, "", true)
+	require.Nil(suite.T(), responseErr)
+
+	organizerEvents, responseErr := suite.eventsRepository.QueryGetUserEvents(organizer.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Empty(suite.T(), organizerEvents)
+}
+
+func (suite *EventsServiceTestSuite) TestUpdateEventCapacity() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	waiting := createTestUser(suite.T(), suite.usersRepository, "waiting@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, "Test event", organizer.ID, 1)
+
+	_, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	_, responseErr = suite.registrationsService.JoinWaitlist(event.ID, waiting.ID)
+	require.Nil(suite.T(), responseErr)
+
+	// raising the capacity hands the new seat to the waitlist
+	event.MaxCapacity = 2
+	updatedEvent, responseErr := suite.eventsService.UpdateEvent(organizer.ID, false, event, models.EventEditScopeSingle, false)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 2, updatedEvent.MaxCapacity)
+	assert.Equal(suite.T(), 2, updatedEvent.AmountRegistration)
+
+	registration, responseErr := suite.registrationsService.GetRegistration(event.ID, waiting.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.NotNil(suite.T(), registration)
+
+	event.MaxCapacity = 1
+	_, responseErr = suite.eventsService.UpdateEvent(organizer.ID, false, event, models.EventEditScopeSingle, false)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusConflict, responseErr.Status)
+
+	updatedEvent, responseErr = suite.eventsService.UpdateEvent(organizer.ID, false, event, models.EventEditScopeSingle, true)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 1, updatedEvent.MaxCapacity)
+	assert.Equal(suite.T(), 2, updatedEvent.AmountRegistration)
+}
+
+func (suite *EventsServiceTestSuite) TestCreateEventSeries() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.Nil(suite.T(), err)
+	startsAt := time.Date(2030, time.March, 25, 19, 0, 0, 0, berlin)
+
+	series, responseErr := suite.eventsService.CreateEventSeries(organizer.ID, &dtos.CreateEventSeriesDto{
+		Name:           "Weekly meetup",
+		Location:       "Köln",
+		MaxCapacity:    5,
+		StartsAt:       startsAt,
+		EndsAt:         startsAt.Add(2 * time.Hour),
+		Timezone:       "Europe/Berlin",
+		RecurrenceRule: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+		ExceptionDates: []time.Time{time.Date(2030, time.March, 27, 0, 0, 0, 0, time.UTC)},
+	})
+	require.Nil(suite.T(), responseErr)
+	require.Len(suite.T(), series.Occurrences, 3)
+	assert.True(suite.T(), series.Occurrences[0].StartsAt.Equal(time.Date(2030, time.March, 25, 18, 0, 0, 0, time.UTC)))
+	// daylight saving time starts on march 31st, the occurrences stay at 19:00 local time
+	assert.True(suite.T(), series.Occurrences[1].StartsAt.Equal(time.Date(2030, time.April, 1, 17, 0, 0, 0, time.UTC)))
+	assert.True(suite.T(), series.Occurrences[1].EndsAt.Equal(time.Date(2030, time.April, 1, 19, 0, 0, 0, time.UTC)))
+	assert.True(suite.T(), series.Occurrences[2].StartsAt.Equal(time.Date(2030, time.April, 3, 17, 0, 0, 0, time.UTC)))
+
+	storedSeries, responseErr := suite.eventsService.GetEventSeries(series.ID)
+	require.Nil(suite.T(), responseErr)
+	require.Len(suite.T(), storedSeries.ExceptionDates, 1)
+	assert.Equal(suite.T(), "Europe/Berlin", storedSeries.Timezone)
+	assert.Len(suite.T(), storedSeries.Occurrences, 3)
+	for _, occurrence := range storedSeries.Occurrences {
+		require.NotNil(suite.T(), occurrence.SeriesId)
+		assert.Equal(suite.T(), series.ID, *occurrence.SeriesId)
+		assert.Equal(suite.T(), 5, occurrence.MaxCapacity)
+		assert.Equal(suite.T(), 19, occurrence.StartsAt.In(berlin).Hour())
+	}
+
+	_, responseErr = suite.eventsService.CreateEventSeries(organizer.ID, &dtos.CreateEventSeriesDto{
+		Name:           "Endless meetup",
+		Location:       "Köln",
+		MaxCapacity:    5,
+		StartsAt:       startsAt,
+		EndsAt:         startsAt.Add(2 * time.Hour),
+		RecurrenceRule: "FREQ=DAILY",
+	})
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusBadRequest, responseErr.Status)
+}
+
+func (suite *EventsServiceTestSuite) TestUpdateEventSeriesScopes() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	startsAt := time.Date(2030, time.January, 7, 18, 0, 0, 0, time.UTC)
+
+	series, responseErr := suite.eventsService.CreateEventSeries(organizer.ID, &dtos.CreateEventSeriesDto{
+		Name:           "Weekly meetup",
+		Location:       "Köln",
+		MaxCapacity:    5,
+		StartsAt:       startsAt,
+		EndsAt:         startsAt.Add(2 * time.Hour),
+		RecurrenceRule: "FREQ=WEEKLY;COUNT=3",
+	})
+	require.Nil(suite.T(), responseErr)
+	require.Len(suite.T(), series.Occurrences, 3)
+
+	// only the first occurrence is changed
+	single := *series.Occurrences[0]
+	single.Name = "Kick-off"
+	_, responseErr = suite.eventsService.UpdateEvent(organizer.ID, false, &single, models.EventEditScopeSingle, false)
+	require.Nil(suite.T(), responseErr)
+
+	// the second and third occurrence move one day later
+	following := *series.Occurrences[1]
+	following.Location = "Bonn"
+	following.StartsAt = following.StartsAt.AddDate(0, 0, 1)
+	following.EndsAt = following.EndsAt.AddDate(0, 0, 1)
+	updatedEvent, responseErr := suite.eventsService.UpdateEvent(organizer.ID, false, &following, models.EventEditScopeFollowing, false)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), series.Occurrences[1].ID, updatedEvent.ID)
+
+	storedSeries, responseErr := suite.eventsService.GetEventSeries(series.ID)
+	require.Nil(suite.T(), responseErr)
+	require.Len(suite.T(), storedSeries.Occurrences, 3)
+	assert.Equal(suite.T(), "Kick-off", storedSeries.Occurrences[0].Name)
+	assert.Equal(suite.T(), "Köln", storedSeries.Occurrences[0].Location)
+	assert.True(suite.T(), storedSeries.Occurrences[0].StartsAt.Equal(startsAt))
+	assert.Equal(suite.T(), "Bonn", storedSeries.Occurrences[1].Location)
+	assert.True(suite.T(), storedSeries.Occurrences[1].StartsAt.Equal(startsAt.AddDate(0, 0, 8)))
+	assert.Equal(suite.T(), "Bonn", storedSeries.Occurrences[2].Location)
+	assert.True(suite.T(), storedSeries.Occurrences[2].StartsAt.Equal(startsAt.AddDate(0, 0, 15)))
+
+	// every occurrence has its own registrations, so the capacity is checked per occurrence
+	_, responseErr = suite.registrationsService.RegisterUserForEvent(series.Occurrences[2].ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	all := *storedSeries.Occurrences[0]
+	all.MaxCapacity = 0
+	_, responseErr = suite.eventsService.UpdateEvent(organizer.ID, false, &all, models.EventEditScopeAll, false)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusConflict, responseErr.Status)
+
+	all.MaxCapacity = 1
+	_, responseErr = suite.eventsService.UpdateEvent(organizer.ID, false, &all, models.EventEditScopeAll, false)
+	require.Nil(suite.T(), responseErr)
+
+	storedSeries, responseErr = suite.eventsService.GetEventSeries(series.ID)
+	require.Nil(suite.T(), responseErr)
+	for _, occurrence := range storedSeries.Occurrences {
+		assert.Equal(suite.T(), "Kick-off", occurrence.Name)
+		assert.Equal(suite.T(), 1, occurrence.MaxCapacity)
+	}
+
+	standalone := createTestEvent(suite.T(), suite.eventsRepository, "Test event", organizer.ID, 5)
+	_, responseErr = suite.eventsService.UpdateEvent(organizer.ID, false, standalone, models.EventEditScopeAll, false)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusBadRequest, responseErr.Status)
+}
+
+func (suite *EventsServiceTestSuite) TestGetAllEventsTimeRange() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	startsAt := time.Date(2030, time.June, 1, 10, 0, 0, 0, time.UTC)
+
+	for day := 0; day < 3; day++ {
+		_, responseErr := suite.eventsService.CreateEvent(&models.Event{
+			Name:        "Test event",
+			Location:    "Köln",
+			StartsAt:    startsAt.AddDate(0, 0, day),
+			EndsAt:      startsAt.AddDate(0, 0, day).Add(3 * time.Hour),
+			Timezone:    "Europe/Berlin",
+			MaxCapacity: 10,
+			UserId:      organizer.ID,
+		})
+		require.Nil(suite.T(), responseErr)
+	}
+
+	// the first event is still running at the start of the range, the last one starts after its end
+	from := startsAt.Add(time.Hour)
+	to := startsAt.AddDate(0, 0, 2)
+	eventsList, totalCount, responseErr := suite.eventsService.GetAllEvents(&dtos.EventFilterDto{
+		Page:       1,
+		PageSize:   10,
+		From:       &from,
+		To:         &to,
+		SortColumn: "event_start",
+		SortOrder:  "ASC",
+	})
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 2, totalCount)
+	require.Len(suite.T(), eventsList, 2)
+	assert.True(suite.T(), eventsList[0].StartsAt.Equal(startsAt))
+	assert.Equal(suite.T(), "Europe/Berlin", eventsList[0].Timezone)
+
+	responseJson, err := json.Marshal(eventsList[0])
+	require.Nil(suite.T(), err)
+	assert.Contains(suite.T(), string(responseJson), `"starts_at":"2030-06-01T10:00:00Z"`)
+	assert.Contains(suite.T(), string(responseJson), `"starts_at_local":"2030-06-01T12:00:00+02:00"`)
+}
+
+func (suite *EventsServiceTestSuite) TestGetAllEventsByCategoriesAndTags() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	startsAt := time.Now().Add(24 * time.Hour)
+
+	testEvents := []struct {
+		category string
+		tags     []string
+	}{
+		{category: "workshop", tags: []string{"Go", "backend"}},
+		{category: "workshop", tags: []string{"go", "frontend"}},
+		{category: "meetup", tags: []string{"go"}},
+		{category: "concert", tags: []string{}},
+	}
+
+	for _, testEvent := range testEvents {
+		_, responseErr := suite.eventsService.CreateEvent(&models.Event{
+			Name:        "Test event",
+			Location:    "Köln",
+			Category:    testEvent.category,
+			Tags:        testEvent.tags,
+			StartsAt:    startsAt,
+			EndsAt:      startsAt.Add(2 * time.Hour),
+			MaxCapacity: 10,
+			UserId:      organizer.ID,
+		})
+		require.Nil(suite.T(), responseErr)
+	}
+
+	eventFilters := &dtos.EventFilterDto{
+		Page:       1,
+		PageSize:   10,
+		Categories: []string{"workshop", "meetup"},
+		Tags:       []string{"GO"},
+		SortColumn: "id",
+		SortOrder:  "ASC",
+	}
+
+	eventsList, totalCount, responseErr := suite.eventsService.GetAllEvents(eventFilters)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 3, totalCount)
+	assert.Len(suite.T(), eventsList, 3)
+
+	eventFacets, responseErr := suite.eventsService.GetEventFacets(eventFilters)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), []*dtos.FacetCount{{Value: "workshop", Count: 2}, {Value: "meetup", Count: 1}}, eventFacets.Categories)
+	assert.Equal(suite.T(), []*dtos.FacetCount{{Value: "go", Count: 3}, {Value: "backend", Count: 1}, {Value: "frontend", Count: 1}}, eventFacets.Tags)
+
+	// all tags have to match
+	eventFilters.Tags = []string{"go", "backend"}
+	_, totalCount, responseErr = suite.eventsService.GetAllEvents(eventFilters)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 1, totalCount)
+
+	eventFacets, responseErr = suite.eventsService.GetEventFacets(&dtos.EventFilterDto{})
+	require.Nil(suite.T(), responseErr)
+	assert.Len(suite.T(), eventFacets.Categories, 3)
+}
+
+func (suite *EventsServiceTestSuite) TestGetAllEventsNearPoint() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	startsAt := time.Now().Add(24 * time.Hour)
+
+	testVenues := []*models.Venue{
+		{Name: "Cologne Cathedral", Address: "Domkloster 4, Köln", Latitude: 50.9413, Longitude: 6.9583},
+		{Name: "Bonn Minster", Address: "Münsterplatz, Bonn", Latitude: 50.7340, Longitude: 7.0998},
+		{Name: "Brandenburg Gate", Address: "Pariser Platz, Berlin", Latitude: 52.5163, Longitude: 13.3777},
+	}
+
+	// events are created in reverse order, so the result is sorted by distance and not by creation
+	eventIds := make([]string, len(testVenues))
+	for index := len(testVenues) - 1; index >= 0; index-- {
+		venue, responseErr := suite.venuesRepository.QueryCreateVenue(testVenues[index])
+		require.Nil(suite.T(), responseErr)
+
+		event, responseErr := suite.eventsService.CreateEvent(&models.Event{
+			Name:        "Test event",
+			Location:    venue.Address,
+			VenueId:     &venue.ID,
+			StartsAt:    startsAt,
+			EndsAt:      startsAt.Add(2 * time.Hour),
+			MaxCapacity: 10,
+			UserId:      organizer.ID,
+		})
+		require.Nil(suite.T(), responseErr)
+		eventIds[index] = event.ID
+	}
+
+	// events without a venue are never found by a search around a point
+	createTestEvent(suite.T(), suite.eventsRepository, "Test event", organizer.ID, 10)
+
+	latitude := 50.9375
+	longitude := 6.9603
+	eventsList, totalCount, responseErr := suite.eventsService.GetAllEvents(&dtos.EventFilterDto{
+		Page:          1,
+		PageSize:      10,
+		NearLatitude:  &latitude,
+		NearLongitude: &longitude,
+		RadiusKm:      50,
+		SortColumn:    "distance",
+		SortOrder:     "ASC",
+	})
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 2, totalCount)
+	require.Len(suite.T(), eventsList, 2)
+	assert.Equal(suite.T(), eventIds[0], eventsList[0].ID)
+	assert.Equal(suite.T(), eventIds[1], eventsList[1].ID)
+	require.NotNil(suite.T(), eventsList[0].DistanceKm)
+	assert.InDelta(suite.T(), 0.45, *eventsList[0].DistanceKm, 0.05)
+	require.NotNil(suite.T(), eventsList[1].DistanceKm)
+	assert.InDelta(suite.T(), 24.66, *eventsList[1].DistanceKm, 0.05)
+
+	eventsList, totalCount, responseErr = suite.eventsService.GetAllEvents(&dtos.EventFilterDto{
+		Page:       1,
+		PageSize:   10,
+		SortColumn: "id",
+		SortOrder:  "ASC",
+	})
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 4, totalCount)
+	assert.Nil(suite.T(), eventsList[0].DistanceKm)
+
+	unknownVenueId := "00000000-0000-0000-0000-000000000000"
+	_, responseErr = suite.eventsService.CreateEvent(&models.Event{
+		Name:        "Test event",
+		Location:    "Köln",
+		VenueId:     &unknownVenueId,
+		StartsAt:    startsAt,
+		EndsAt:      startsAt.Add(2 * time.Hour),
+		MaxCapacity: 10,
+		UserId:      organizer.ID,
+	})
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusBadRequest, responseErr.Status)
+}
+
+func (suite *EventsServiceTestSuite) TestGetAllEventsSearch() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	startsAt := time.Now().Add(24 * time.Hour)
+
+	testEvents := []struct {
+		name        string
+		description string
+		location    string
+	}{
+		{name: "Rust & Go workshop", description: "Meetups with hands-on exercises", location: "Köln"},
+		{name: "Go meetup", description: "Monthly talks", location: "Köln"},
+		{name: "Jazz concert", description: "Live music", location: "Bonn"},
+	}
+
+	eventIds := make([]string, 0, len(testEvents))
+	for _, testEvent := range testEvents {
+		event, responseErr := suite.eventsService.CreateEvent(&models.Event{
+			Name:        testEvent.name,
+			Description: testEvent.description,
+			Location:    testEvent.location,
+			StartsAt:    startsAt,
+			EndsAt:      startsAt.Add(2 * time.Hour),
+			MaxCapacity: 10,
+			UserId:      organizer.ID,
+		})
+		require.Nil(suite.T(), responseErr)
+		eventIds = append(eventIds, event.ID)
+	}
+
+	// matches in the name rank higher than matches in the description, meetups is found by its stem
+	eventsList, totalCount, responseErr := suite.eventsService.GetAllEvents(&dtos.EventFilterDto{
+		Page:       1,
+		PageSize:   10,
+		Search:     "Meetup",
+		SortColumn: "relevance",
+		SortOrder:  "DESC",
+	})
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 2, totalCount)
+	require.Len(suite.T(), eventsList, 2)
+	assert.Equal(suite.T(), eventIds[1], eventsList[0].ID)
+	assert.Equal(suite.T(), eventIds[0], eventsList[1].ID)
+	require.NotNil(suite.T(), eventsList[0].Highlights)
+	assert.Equal(suite.T(), "Go <mark>meetup</mark>", eventsList[0].Highlights.Name)
+	require.NotNil(suite.T(), eventsList[1].Highlights)
+	assert.Equal(suite.T(), "Rust &amp; Go workshop", eventsList[1].Highlights.Name)
+	assert.Contains(suite.T(), eventsList[1].Highlights.Description, "<mark>Meetups</mark>")
+
+	// the last word matches as prefix
+	eventsList, totalCount, responseErr = suite.eventsService.GetAllEvents(&dtos.EventFilterDto{
+		Page:       1,
+		PageSize:   10,
+		Search:     "go work",
+		SortColumn: "relevance",
+		SortOrder:  "DESC",
+	})
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 1, totalCount)
+	assert.Equal(suite.T(), eventIds[0], eventsList[0].ID)
+
+	// the location is searched as well
+	_, totalCount, responseErr = suite.eventsService.GetAllEvents(&dtos.EventFilterDto{
+		Page:       1,
+		PageSize:   10,
+		Search:     "bonn",
+		SortColumn: "id",
+		SortOrder:  "ASC",
+	})
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 1, totalCount)
+
+	eventsList, totalCount, responseErr = suite.eventsService.GetAllEvents(&dtos.EventFilterDto{
+		Page:       1,
+		PageSize:   10,
+		SortColumn: "id",
+		SortOrder:  "ASC",
+	})
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 3, totalCount)
+	assert.Nil(suite.T(), eventsList[0].Highlights)
+}
+
+func (suite *EventsServiceTestSuite) TestGetAllEventsSearchWhileTyping() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	startsAt := time.Now().Add(24 * time.Hour)
+
+	testEvents := []struct {
+		name     string
+		language string
+	}{
+		{name: "Rock festival", language: ""},
+		{name: "Konzerte im Park", language: "de-DE"},
+	}
+
+	eventIds := make([]string, 0, len(testEvents))
+	for _, testEvent := range testEvents {
+		event, responseErr := suite.eventsService.CreateEvent(&models.Event{
+			Name:        testEvent.name,
+			Location:    "Köln",
+			StartsAt:    startsAt,
+			EndsAt:      startsAt.Add(2 * time.Hour),
+			MaxCapacity: 10,
+			UserId:      organizer.ID,
+			Language:    testEvent.language,
+		})
+		require.Nil(suite.T(), responseErr)
+		eventIds = append(eventIds, event.ID)
+	}
+
+	search := func(term string) []*models.Event {
+		eventsList, _, responseErr := suite.eventsService.GetAllEvents(&dtos.EventFilterDto{
+			Page:       1,
+			PageSize:   10,
+			Search:     term,
+			SortColumn: "relevance",
+			SortOrder:  "DESC",
+		})
+		require.Nil(suite.T(), responseErr)
+
+		return eventsList
+	}
+
+	// the event is found after every typed letter, although the stem of festiva is no prefix of festiv
+	for length := 1; length <= len("festival"); length++ {
+		term := "festival"[:length]
+		eventsList := search(term)
+		require.Len(suite.T(), eventsList, 1, term)
+		assert.Equal(suite.T(), eventIds[0], eventsList[0].ID, term)
+	}
+
+	// the words before the last one are matched by their stem
+	eventsList := search("rocks festiva")
+	require.Len(suite.T(), eventsList, 1)
+	assert.Equal(suite.T(), eventIds[0], eventsList[0].ID)
+	assert.Empty(suite.T(), search("jazz festiva"))
+
+	// events without a language are stemmed in english, the others in their own language
+	eventsList = search("festivals")
+	require.Len(suite.T(), eventsList, 1)
+	assert.Equal(suite.T(), models.DefaultEventLanguage, eventsList[0].Language)
+
+	eventsList = search("Konzert Parks")
+	require.Len(suite.T(), eventsList, 1)
+	assert.Equal(suite.T(), eventIds[1], eventsList[0].ID)
+	assert.Equal(suite.T(), "de-DE", eventsList[0].Language)
+	assert.Contains(suite.T(), eventsList[0].Highlights.Name, "<mark>Konzerte</mark>")
+}
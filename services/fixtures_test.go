@@ -0,0 +1,37 @@
+package services
+
+import (
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// createTestUser signs up a user with the password Test123
+func createTestUser(t *testing.T, usersRepository repositories.UsersRepositoryInterface, email string) *models.User {
+	responseErr := usersRepository.QuerySignupUser(email, "Test123")
+	require.Nil(t, responseErr)
+
+	user, responseErr := usersRepository.QueryGetUser(email)
+	require.Nil(t, responseErr)
+
+	return user
+}
+
+// createTestEvent creates an event of the user that starts tomorrow
+func createTestEvent(t *testing.T, eventsRepository repositories.EventsRepositoryInterface, name string, userId string, maxCapacity int) *models.Event {
+	event, responseErr := eventsRepository.QueryCreateEvent(&models.Event{
+		Name:        name,
+		Location:    "Köln",
+		StartsAt:    time.Now().Add(24 * time.Hour),
+		EndsAt:      time.Now().Add(26 * time.Hour),
+		Timezone:    "UTC",
+		MaxCapacity: maxCapacity,
+		UserId:      userId,
+	})
+	require.Nil(t, responseErr)
+
+	return event
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"eventom-backend/mailers"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/utils"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	maxAccountFailedLogins   = 5
+	maxIpAddressFailedLogins = 20
+	failedLoginsResetAfter   = 15 * time.Minute
+	loginLockDuration        = 15 * time.Minute
+	maxLoginDelay            = 30 * time.Second
+	accountUnlockTokenTTL    = 24 * time.Hour
+)
+
+// LoginAttemptsService protects the login against brute-force attacks. Failed attempts are tracked per email address and per ip address,
+// every failure delays the next attempt progressively and too many failures lock the login temporarily.
+// Email addresses are tracked whether they are registered or not, so the responses do not reveal which accounts exist
+type LoginAttemptsService struct {
+	loginAttemptsRepository repositories.LoginAttemptsRepositoryInterface
+	usersRepository         repositories.UsersRepositoryInterface
+	transactionHandler      repositories.TransactionHandler
+	mailer                  mailers.Mailer
+}
+
+func NewLoginAttemptsService(
+	loginAttemptsRepository repositories.LoginAttemptsRepositoryInterface,
+	usersRepository repositories.UsersRepositoryInterface,
+	transactionHandler repositories.TransactionHandler,
+	mailer mailers.Mailer,
+) *LoginAttemptsService {
+	return &LoginAttemptsService{
+		loginAttemptsRepository: loginAttemptsRepository,
+		usersRepository:         usersRepository,
+		transactionHandler:      transactionHandler,
+		mailer:                  mailer,
+	}
+}
+
+// ReserveLoginAttempt has to be called before the credentials are checked. It counts the attempt as failed right away, so parallel
+// attempts cannot all pass before the failure of the first one is recorded. The attempt is rejected while the login is delayed or locked.
+// Every reserved attempt has to be completed with RecordFailedLogin or RecordSuccessfulLogin
+func (las LoginAttemptsService) ReserveLoginAttempt(email string, ipAddress string) *models.ResponseError {
+	responseErr := las.reserveLoginAttempt(models.LoginAttemptScopeIpAddress, ipAddress, maxIpAddressFailedLogins, 0)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	responseErr = las.reserveLoginAttempt(models.LoginAttemptScopeAccount, strings.ToLower(email), maxAccountFailedLogins, maxLoginDelay)
+
+	if responseErr != nil {
+		// the rejected attempt does not count for the ip address
+		releaseErr := las.loginAttemptsRepository.QueryReleaseLoginAttempt(models.LoginAttemptScopeIpAddress, ipAddress)
+
+		if releaseErr != nil {
+			return releaseErr
+		}
+
+		return responseErr
+	}
+
+	return nil
+}
+
+// RecordFailedLogin locks the login once a reserved attempt fails and the maximum of failed attempts is reached
+func (las LoginAttemptsService) RecordFailedLogin(email string, ipAddress string) *models.ResponseError {
+	ipAddressAttempt, responseErr := las.loginAttemptsRepository.QueryGetLoginAttempt(models.LoginAttemptScopeIpAddress, ipAddress)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	if ipAddressAttempt != nil && ipAddressAttempt.FailedAttempts >= maxIpAddressFailedLogins {
+		_, responseErr = las.loginAttemptsRepository.QueryLockLogin(models.LoginAttemptScopeIpAddress, ipAddress, time.Now().Add(loginLockDuration))
+
+		if responseErr != nil {
+			return responseErr
+		}
+	}
+
+	accountAttempt, responseErr := las.loginAttemptsRepository.QueryGetLoginAttempt(models.LoginAttemptScopeAccount, strings.ToLower(email))
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	if accountAttempt == nil || accountAttempt.FailedAttempts < maxAccountFailedLogins {
+		return nil
+	}
+
+	locked, responseErr := las.loginAttemptsRepository.QueryLockLogin(models.LoginAttemptScopeAccount, strings.ToLower(email), time.Now().Add(loginLockDuration))
+
+	if responseErr != nil || !locked {
+		return responseErr
+	}
+
+	return las.sendUnlockEmail(email)
+}
+
+// RecordSuccessfulLogin resets the failed attempts of the account and takes back the attempt reserved for the ip address
+func (las LoginAttemptsService) RecordSuccessfulLogin(email string, ipAddress string) *models.ResponseError {
+	responseErr := las.loginAttemptsRepository.QueryResetLoginAttempts(models.LoginAttemptScopeAccount, strings.ToLower(email))
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	return las.loginAttemptsRepository.QueryReleaseLoginAttempt(models.LoginAttemptScopeIpAddress, ipAddress)
+}
+
+func (las LoginAttemptsService) UnlockAccount(unlockToken string) *models.ResponseError {
+	return las.transactionHandler.ExecUnlockAccountTx(utils.HashToken(unlockToken))
+}
+
+// UnlockUser resets the failed attempts of the account. Ip addresses stay throttled, they are not linked to accounts and
+// could be used to attack other accounts
+func (las LoginAttemptsService) UnlockUser(userId string) *models.ResponseError {
+	user, responseErr := las.usersRepository.QueryGetUserById(userId)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	return las.loginAttemptsRepository.QueryResetLoginAttempts(models.LoginAttemptScopeAccount, strings.ToLower(user.Email))
+}
+
+// sendUnlockEmail informs the owner of a locked account and sends a link to unlock it right away. Nothing is sent for unregistered addresses
+func (las LoginAttemptsService) sendUnlockEmail(email string) *models.ResponseError {
+	user, responseErr := las.usersRepository.QueryGetUser(email)
+
+	if responseErr != nil {
+		if responseErr.Status == http.StatusNotFound {
+			return nil
+		}
+		return responseErr
+	}
+
+	unlockToken, err := utils.GenerateOpaqueToken()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	responseErr = las.loginAttemptsRepository.QueryCreateAccountUnlockToken(&models.AccountUnlockToken{
+		UserId:    user.ID,
+		TokenHash: utils.HashToken(unlockToken),
+		ExpiresAt: time.Now().Add(accountUnlockTokenTTL),
+	})
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	unlockLink := utils.BuildAppUrl("/unlock-account", url.Values{"token": {unlockToken}})
+
+	err = las.mailer.Send(&mailers.Message{
+		To:      user.Email,
+		Subject: "Your eventom account has been locked",
+		Body:    fmt.Sprintf("Your account has been locked for %d minutes after too many failed login attempts. If it was you, you can unlock your account right away with the following link:\n\n%s\n\nIf it was not you, consider resetting your password.\n", int(loginLockDuration.Minutes()), unlockLink),
+	})
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: fmt.Sprintf("Could not send unlock email: %s", err.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// reserveLoginAttempt reserves an attempt of the subject and explains why a rejected attempt has to wait
+func (las LoginAttemptsService) reserveLoginAttempt(scope string, subject string, maxAttempts int, maxDelay time.Duration) *models.ResponseError {
+	reserved, responseErr := las.loginAttemptsRepository.QueryReserveLoginAttempt(scope, subject, maxAttempts, maxDelay, failedLoginsResetAfter)
+
+	if responseErr != nil || reserved {
+		return responseErr
+	}
+
+	loginAttempt, responseErr := las.loginAttemptsRepository.QueryGetLoginAttempt(scope, subject)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	responseErr = checkLoginAttempt(loginAttempt, maxAttempts, maxDelay > 0)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	// the login attempt has changed in the meantime
+	return tooManyLoginAttempts(time.Second)
+}
+
+// checkLoginAttempt rejects logins while the subject is locked or has maxAttempts attempts that are failed or still checked and, if delayed
+// is set, before the progressive delay after the last attempt has passed. It matches the conditions of QueryReserveLoginAttempt
+func checkLoginAttempt(loginAttempt *models.LoginAttempt, maxAttempts int, delayed bool) *models.ResponseError {
+	if loginAttempt == nil {
+		return nil
+	}
+
+	if loginAttempt.LockedUntil != nil && time.Now().Before(*loginAttempt.LockedUntil) {
+		return tooManyLoginAttempts(time.Until(*loginAttempt.LockedUntil))
+	}
+
+	if time.Since(loginAttempt.LastFailedAt) > failedLoginsResetAfter {
+		return nil
+	}
+
+	if loginAttempt.FailedAttempts >= maxAttempts {
+		return tooManyLoginAttempts(time.Until(loginAttempt.LastFailedAt.Add(failedLoginsResetAfter)))
+	}
+
+	if !delayed {
+		return nil
+	}
+
+	// the delay doubles with every failed attempt: 1s, 2s, 4s, ... up to maxLoginDelay
+	delay := time.Duration(math.Pow(2, float64(loginAttempt.FailedAttempts-1))) * time.Second
+	delay = min(delay, maxLoginDelay)
+
+	nextAttemptAt := loginAttempt.LastFailedAt.Add(delay)
+
+	if time.Now().Before(nextAttemptAt) {
+		return tooManyLoginAttempts(time.Until(nextAttemptAt))
+	}
+
+	return nil
+}
+
+func tooManyLoginAttempts(retryAfter time.Duration) *models.ResponseError {
+	return &models.ResponseError{
+		Message: fmt.Sprintf("Too many failed login attempts, try again in %d seconds", int(math.Ceil(retryAfter.Seconds()))),
+		Status:  http.StatusTooManyRequests,
+	}
+}
+
+var _ LoginAttemptsServiceInterface = (*LoginAttemptsService)(nil)
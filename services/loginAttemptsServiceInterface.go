@@ -0,0 +1,15 @@
+package services
+
+import "eventom-backend/models"
+
+type LoginAttemptsServiceInterface interface {
+	ReserveLoginAttempt(email string, ipAddress string) *models.ResponseError
+
+	RecordFailedLogin(email string, ipAddress string) *models.ResponseError
+
+	RecordSuccessfulLogin(email string, ipAddress string) *models.ResponseError
+
+	UnlockAccount(unlockToken string) *models.ResponseError
+
+	UnlockUser(userId string) *models.ResponseError
+}
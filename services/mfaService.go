@@ -0,0 +1,186 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/utils"
+	"net/http"
+	"time"
+)
+
+const recoveryCodesCount = 10
+
+type MfaService struct {
+	mfaRepository      repositories.MfaRepositoryInterface
+	usersRepository    repositories.UsersRepositoryInterface
+	transactionHandler repositories.TransactionHandler
+}
+
+func NewMfaService(
+	mfaRepository repositories.MfaRepositoryInterface,
+	usersRepository repositories.UsersRepositoryInterface,
+	transactionHandler repositories.TransactionHandler,
+) *MfaService {
+	return &MfaService{
+		mfaRepository:      mfaRepository,
+		usersRepository:    usersRepository,
+		transactionHandler: transactionHandler,
+	}
+}
+
+// EnrollTotp generates a new secret for the user. It is not used for login until it has been confirmed with a valid code
+func (ms MfaService) EnrollTotp(userId string) (*dtos.TotpEnrollment, *models.ResponseError) {
+	user, responseErr := ms.usersRepository.QueryGetUserById(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	secret, err := utils.GenerateTotpSecret()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	responseErr = ms.mfaRepository.QueryCreateUserTotp(userId, secret)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return &dtos.TotpEnrollment{
+		Secret:     secret,
+		OtpauthUri: utils.BuildTotpUri(user.Email, secret),
+	}, nil
+}
+
+// ConfirmTotp enables two-factor authentication and returns the recovery codes. The codes are only stored hashed and cannot be shown again
+func (ms MfaService) ConfirmTotp(userId string, code string) ([]string, *models.ResponseError) {
+	userTotp, responseErr := ms.mfaRepository.QueryGetUserTotp(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if userTotp.ConfirmedAt != nil {
+		return nil, &models.ResponseError{
+			Message: "Two-factor authentication is already enabled",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	step, ok := utils.ValidateTotpCode(userTotp.Secret, code, time.Now())
+
+	if !ok {
+		return nil, &models.ResponseError{
+			Message: "Invalid code",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	recoveryCodes := make([]string, 0, recoveryCodesCount)
+	recoveryCodeHashes := make([]string, 0, recoveryCodesCount)
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		recoveryCode, err := utils.GenerateRecoveryCode()
+
+		if err != nil {
+			return nil, &models.ResponseError{
+				Message: err.Error(),
+				Status:  http.StatusInternalServerError,
+			}
+		}
+
+		recoveryCodes = append(recoveryCodes, recoveryCode)
+		recoveryCodeHashes = append(recoveryCodeHashes, utils.HashToken(utils.NormalizeRecoveryCode(recoveryCode)))
+	}
+
+	responseErr = ms.transactionHandler.ExecConfirmTotpTx(userId, step, recoveryCodeHashes)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return recoveryCodes, nil
+}
+
+// DisableTotp removes the secret and the recovery codes of the user. A valid code is required, so a stolen session cannot turn it off
+func (ms MfaService) DisableTotp(userId string, code string) *models.ResponseError {
+	responseErr := ms.VerifyCode(userId, code)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	return ms.mfaRepository.QueryDeleteUserTotp(userId)
+}
+
+func (ms MfaService) IsTotpEnabled(userId string) (bool, *models.ResponseError) {
+	userTotp, responseErr := ms.mfaRepository.QueryGetUserTotp(userId)
+
+	if responseErr != nil {
+		if responseErr.Status == http.StatusNotFound {
+			return false, nil
+		}
+		return false, responseErr
+	}
+
+	return userTotp.ConfirmedAt != nil, nil
+}
+
+// VerifyCode accepts a current totp code or an unused recovery code. Every code can only be used once
+func (ms MfaService) VerifyCode(userId string, code string) *models.ResponseError {
+	invalidCodeErr := &models.ResponseError{
+		Message: "Invalid code",
+		Status:  http.StatusUnauthorized,
+	}
+
+	userTotp, responseErr := ms.mfaRepository.QueryGetUserTotp(userId)
+
+	if responseErr != nil {
+		if responseErr.Status == http.StatusNotFound {
+			return invalidCodeErr
+		}
+		return responseErr
+	}
+
+	if userTotp.ConfirmedAt == nil {
+		return invalidCodeErr
+	}
+
+	if len(code) == utils.TotpDigits {
+		step, ok := utils.ValidateTotpCode(userTotp.Secret, code, time.Now())
+
+		if !ok {
+			return invalidCodeErr
+		}
+
+		return ms.mfaRepository.QueryUseTotpStep(userId, step)
+	}
+
+	return ms.mfaRepository.QueryUseRecoveryCode(userId, utils.HashToken(utils.NormalizeRecoveryCode(code)))
+}
+
+// createMfaChallenge starts the second step of a login, the user is only logged in after a code has been sent with the challenge token
+func createMfaChallenge(userId string) (*dtos.MfaChallenge, *models.ResponseError) {
+	challengeToken, expiresAt, err := utils.GenerateMfaChallengeJwt(userId)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &dtos.MfaChallenge{
+		MfaRequired: true,
+		MfaToken:    challengeToken,
+		ExpiresAt:   expiresAt,
+	}, nil
+}
+
+var _ MfaServiceInterface = (*MfaService)(nil)
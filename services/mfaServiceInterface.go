@@ -0,0 +1,18 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
+
+type MfaServiceInterface interface {
+	EnrollTotp(userId string) (*dtos.TotpEnrollment, *models.ResponseError)
+
+	ConfirmTotp(userId string, code string) ([]string, *models.ResponseError)
+
+	DisableTotp(userId string, code string) *models.ResponseError
+
+	IsTotpEnabled(userId string) (bool, *models.ResponseError)
+
+	VerifyCode(userId string, code string) *models.ResponseError
+}
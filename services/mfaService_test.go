@@ -0,0 +1,139 @@
+package services
+
+import (
+	"context"
+	"eventom-backend/repositories"
+	"eventom-backend/testutils"
+	"eventom-backend/utils"
+	"log"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type MfaServiceTestSuite struct {
+	suite.Suite
+	ctx             context.Context
+	mfaService      MfaServiceInterface
+	usersRepository *repositories.UsersRepository
+	userId          string
+}
+
+func TestMfaServiceSuite(t *testing.T) {
+	suite.Run(t, &MfaServiceTestSuite{})
+}
+
+func (suite *MfaServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	suite.usersRepository = repositories.NewUsersRepository(testutils.TestContainer.DB)
+	mfaRepository := repositories.NewMfaRepository(testutils.TestContainer.DB)
+	transactionHandler := repositories.NewTxHandler(testutils.TestContainer.DB)
+	suite.mfaService = NewMfaService(mfaRepository, suite.usersRepository, *transactionHandler)
+}
+
+func (suite *MfaServiceTestSuite) BeforeTest(suiteName, testName string) {
+	// clear users table before every test, totp secrets and recovery codes are deleted by the cascade
+	query := `
+		DELETE FROM
+			users`
+	_, err := testutils.TestContainer.DB.Exec(query)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	responseErr := suite.usersRepository.QuerySignupUser("test@test.com", "Test123")
+	require.Nil(suite.T(), responseErr)
+
+	user, responseErr := suite.usersRepository.QueryGetUser("test@test.com")
+	require.Nil(suite.T(), responseErr)
+
+	suite.userId = user.ID
+}
+
+func (suite *MfaServiceTestSuite) TestEnrollTotpNotEnabledBeforeConfirmation() {
+	enrollment, err := suite.mfaService.EnrollTotp(suite.userId)
+	require.Nil(suite.T(), err)
+	assert.NotEmpty(suite.T(), enrollment.Secret)
+	assert.Contains(suite.T(), enrollment.OtpauthUri, "otpauth://totp/")
+
+	enabled, err := suite.mfaService.IsTotpEnabled(suite.userId)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), enabled)
+}
+
+func (suite *MfaServiceTestSuite) TestConfirmTotpFailInvalidCode() {
+	_, err := suite.mfaService.EnrollTotp(suite.userId)
+	require.Nil(suite.T(), err)
+
+	recoveryCodes, err := suite.mfaService.ConfirmTotp(suite.userId, "000000x")
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 400, err.Status)
+	assert.Empty(suite.T(), recoveryCodes)
+}
+
+func (suite *MfaServiceTestSuite) TestVerifyCodeRejectsReusedCodes() {
+	enrollment, err := suite.mfaService.EnrollTotp(suite.userId)
+	require.Nil(suite.T(), err)
+
+	code, codeErr := utils.GenerateTotpCode(enrollment.Secret, time.Now())
+	require.Nil(suite.T(), codeErr)
+
+	recoveryCodes, err := suite.mfaService.ConfirmTotp(suite.userId, code)
+	require.Nil(suite.T(), err)
+	assert.Len(suite.T(), recoveryCodes, recoveryCodesCount)
+
+	enabled, err := suite.mfaService.IsTotpEnabled(suite.userId)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), enabled)
+
+	// the code has been used for the confirmation already
+	err = suite.mfaService.VerifyCode(suite.userId, code)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+
+	err = suite.mfaService.VerifyCode(suite.userId, recoveryCodes[0])
+	assert.Nil(suite.T(), err)
+
+	err = suite.mfaService.VerifyCode(suite.userId, recoveryCodes[0])
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+}
+
+func (suite *MfaServiceTestSuite) TestDisableTotp() {
+	enrollment, err := suite.mfaService.EnrollTotp(suite.userId)
+	require.Nil(suite.T(), err)
+
+	code, codeErr := utils.GenerateTotpCode(enrollment.Secret, time.Now())
+	require.Nil(suite.T(), codeErr)
+
+	recoveryCodes, err := suite.mfaService.ConfirmTotp(suite.userId, code)
+	require.Nil(suite.T(), err)
+
+	err = suite.mfaService.DisableTotp(suite.userId, "wrong-code")
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+
+	err = suite.mfaService.DisableTotp(suite.userId, recoveryCodes[1])
+	assert.Nil(suite.T(), err)
+
+	enabled, err := suite.mfaService.IsTotpEnabled(suite.userId)
+	assert.Nil(suite.T(), err)
+	assert.False(suite.T(), enabled)
+}
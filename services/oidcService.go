@@ -0,0 +1,122 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/utils"
+	"net/http"
+	"time"
+)
+
+// OidcService logs users in with an external openid connect provider as alternative to passwords
+type OidcService struct {
+	oidcProvider             *utils.OidcProvider
+	userIdentitiesRepository repositories.UserIdentitiesRepositoryInterface
+	mfaService               MfaServiceInterface
+	tokensService            TokensServiceInterface
+	transactionHandler       repositories.TransactionHandler
+}
+
+func NewOidcService(
+	oidcProvider *utils.OidcProvider,
+	userIdentitiesRepository repositories.UserIdentitiesRepositoryInterface,
+	mfaService MfaServiceInterface,
+	tokensService TokensServiceInterface,
+	transactionHandler repositories.TransactionHandler,
+) *OidcService {
+	return &OidcService{
+		oidcProvider:             oidcProvider,
+		userIdentitiesRepository: userIdentitiesRepository,
+		mfaService:               mfaService,
+		tokensService:            tokensService,
+		transactionHandler:       transactionHandler,
+	}
+}
+
+// StartLogin stores a pending login and returns the url of the identity provider the user has to be redirected to together with
+// the state, which has to be bound to the browser of the user
+func (ois OidcService) StartLogin() (string, string, *models.ResponseError) {
+	responseErr := ois.userIdentitiesRepository.QueryDeleteExpiredOidcAuthRequests()
+
+	if responseErr != nil {
+		return "", "", responseErr
+	}
+
+	state, err := utils.GenerateOpaqueToken()
+
+	if err != nil {
+		return "", "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	nonce, err := utils.GenerateOpaqueToken()
+
+	if err != nil {
+		return "", "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	codeVerifier, err := utils.GenerateCodeVerifier()
+
+	if err != nil {
+		return "", "", &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	responseErr = ois.userIdentitiesRepository.QueryCreateOidcAuthRequest(utils.HashToken(state), nonce, codeVerifier, time.Now().Add(utils.OidcAuthRequestTTL))
+
+	if responseErr != nil {
+		return "", "", responseErr
+	}
+
+	return ois.oidcProvider.AuthCodeUrl(state, nonce, codeVerifier), state, nil
+}
+
+// CompleteLogin redeems the code of the identity provider and logs in the linked user. Users with two-factor authentication
+// get a mfa challenge like on the password login
+func (ois OidcService) CompleteLogin(state string, code string, client dtos.ClientInfo) (*dtos.AuthTokens, *dtos.MfaChallenge, *models.ResponseError) {
+	authRequest, responseErr := ois.userIdentitiesRepository.QueryConsumeOidcAuthRequest(utils.HashToken(state))
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	claims, err := ois.oidcProvider.ExchangeCode(code, authRequest.CodeVerifier, authRequest.Nonce)
+
+	if err != nil {
+		return nil, nil, &models.ResponseError{
+			Message: "Login at identity provider failed: " + err.Error(),
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	user, responseErr := ois.transactionHandler.ExecOidcLoginTx(ois.oidcProvider.Issuer(), claims, models.DefaultUserRoles, ois.oidcProvider.LinkExistingUsers())
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	mfaEnabled, responseErr := ois.mfaService.IsTotpEnabled(user.ID)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	if mfaEnabled {
+		mfaChallenge, responseErr := createMfaChallenge(user.ID)
+		return nil, mfaChallenge, responseErr
+	}
+
+	authTokens, responseErr := ois.tokensService.IssueTokens(user.ID, client)
+
+	return authTokens, nil, responseErr
+}
+
+var _ OidcServiceInterface = (*OidcService)(nil)
@@ -0,0 +1,12 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
+
+type OidcServiceInterface interface {
+	StartLogin() (string, string, *models.ResponseError)
+
+	CompleteLogin(state string, code string, client dtos.ClientInfo) (*dtos.AuthTokens, *dtos.MfaChallenge, *models.ResponseError)
+}
@@ -0,0 +1,253 @@
+package services
+
+import (
+	"context"
+	"eventom-backend/dtos"
+	"eventom-backend/repositories"
+	"eventom-backend/testutils"
+	"eventom-backend/utils"
+	"log"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+const oidcTestRedirectUrl = "http://localhost:8080/login/oidc/callback"
+
+type OidcServiceTestSuite struct {
+	suite.Suite
+	ctx                context.Context
+	oidcService        OidcServiceInterface
+	linkingOidcService OidcServiceInterface
+	usersRepository    *repositories.UsersRepository
+	identityProvider   *testutils.StubOidcProvider
+}
+
+func TestOidcServiceSuite(t *testing.T) {
+	suite.Run(t, &OidcServiceTestSuite{})
+}
+
+func (suite *OidcServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	keyFile, err := testutils.CreateSigningKeyFile(suite.T().TempDir())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = utils.ReadPrivateKeyFromFile(keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	suite.identityProvider, err = testutils.NewStubOidcProvider("eventom")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	oidcConfig := utils.OidcConfig{
+		Issuer:      suite.identityProvider.URL,
+		ClientId:    "eventom",
+		RedirectUrl: oidcTestRedirectUrl,
+		Scopes:      []string{"openid", "email", "profile"},
+	}
+
+	oidcProvider, err := utils.NewOidcProvider(oidcConfig, suite.identityProvider.Client())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	oidcConfig.LinkExistingUsers = true
+	linkingOidcProvider, err := utils.NewOidcProvider(oidcConfig, suite.identityProvider.Client())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	suite.usersRepository = repositories.NewUsersRepository(testutils.TestContainer.DB)
+	refreshTokensRepository := repositories.NewRefreshTokensRepository(testutils.TestContainer.DB)
+	revokedTokensRepository := repositories.NewRevokedTokensRepository(testutils.TestContainer.DB)
+	sessionsRepository := repositories.NewSessionsRepository(testutils.TestContainer.DB)
+	rolesRepository := repositories.NewRolesRepository(testutils.TestContainer.DB)
+	mfaRepository := repositories.NewMfaRepository(testutils.TestContainer.DB)
+	userIdentitiesRepository := repositories.NewUserIdentitiesRepository(testutils.TestContainer.DB)
+	transactionHandler := repositories.NewTxHandler(testutils.TestContainer.DB)
+	tokensService := NewTokensService(refreshTokensRepository, revokedTokensRepository, sessionsRepository, rolesRepository, *transactionHandler)
+	mfaService := NewMfaService(mfaRepository, suite.usersRepository, *transactionHandler)
+	suite.oidcService = NewOidcService(oidcProvider, userIdentitiesRepository, mfaService, tokensService, *transactionHandler)
+	suite.linkingOidcService = NewOidcService(linkingOidcProvider, userIdentitiesRepository, mfaService, tokensService, *transactionHandler)
+}
+
+func (suite *OidcServiceTestSuite) TearDownSuite() {
+	suite.identityProvider.Close()
+}
+
+func (suite *OidcServiceTestSuite) BeforeTest(suiteName, testName string) {
+	// clear users table before every test, linked identities are deleted by the cascade
+	query := `
+		DELETE FROM
+			users`
+	_, err := testutils.TestContainer.DB.Exec(query)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	suite.identityProvider.SetUser(testutils.StubOidcUser{
+		Subject:       "subject-1",
+		Email:         "sso@test.com",
+		EmailVerified: true,
+		Name:          "Sso User",
+	})
+}
+
+func (suite *OidcServiceTestSuite) TestLoginProvisionsUser() {
+	state, code := suite.loginAtIdentityProvider(suite.oidcService)
+
+	authTokens, mfaChallenge, err := suite.oidcService.CompleteLogin(state, code, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+	assert.Nil(suite.T(), mfaChallenge)
+	assert.NotEmpty(suite.T(), authTokens.AccessToken)
+
+	user, err := suite.usersRepository.QueryGetUser("sso@test.com")
+	require.Nil(suite.T(), err)
+	assert.True(suite.T(), user.Verified)
+	assert.Equal(suite.T(), "Sso User", user.DisplayName)
+
+	// the second login finds the user by the linked identity, even if the email address has changed at the identity provider
+	suite.identityProvider.SetUser(testutils.StubOidcUser{
+		Subject:       "subject-1",
+		Email:         "changed@test.com",
+		EmailVerified: true,
+	})
+
+	state, code = suite.loginAtIdentityProvider(suite.oidcService)
+
+	_, _, err = suite.oidcService.CompleteLogin(state, code, dtos.ClientInfo{})
+	assert.Nil(suite.T(), err)
+
+	_, err = suite.usersRepository.QueryGetUser("changed@test.com")
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 404, err.Status)
+}
+
+func (suite *OidcServiceTestSuite) TestLoginLinksExistingUser() {
+	responseErr := suite.usersRepository.QuerySignupUser("sso@test.com", "Test123")
+	require.Nil(suite.T(), responseErr)
+
+	user, responseErr := suite.usersRepository.QueryGetUser("sso@test.com")
+	require.Nil(suite.T(), responseErr)
+
+	responseErr = suite.usersRepository.QueryVerifyEmail(user.ID, "sso@test.com")
+	require.Nil(suite.T(), responseErr)
+
+	state, code := suite.loginAtIdentityProvider(suite.linkingOidcService)
+
+	_, _, err := suite.linkingOidcService.CompleteLogin(state, code, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	// the password login keeps working
+	linkedUser, err := suite.usersRepository.QueryGetUserById(user.ID)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), user.Password, linkedUser.Password)
+}
+
+func (suite *OidcServiceTestSuite) TestLoginFailExistingUser() {
+	responseErr := suite.usersRepository.QuerySignupUser("sso@test.com", "Test123")
+	require.Nil(suite.T(), responseErr)
+
+	user, responseErr := suite.usersRepository.QueryGetUser("sso@test.com")
+	require.Nil(suite.T(), responseErr)
+
+	responseErr = suite.usersRepository.QueryVerifyEmail(user.ID, "sso@test.com")
+	require.Nil(suite.T(), responseErr)
+
+	// linking is disabled by default
+	state, code := suite.loginAtIdentityProvider(suite.oidcService)
+
+	_, _, err := suite.oidcService.CompleteLogin(state, code, dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 409, err.Status)
+}
+
+func (suite *OidcServiceTestSuite) TestLoginFailExistingUnverifiedUser() {
+	responseErr := suite.usersRepository.QuerySignupUser("sso@test.com", "Test123")
+	require.Nil(suite.T(), responseErr)
+
+	user, responseErr := suite.usersRepository.QueryGetUser("sso@test.com")
+	require.Nil(suite.T(), responseErr)
+
+	state, code := suite.loginAtIdentityProvider(suite.linkingOidcService)
+
+	_, _, err := suite.linkingOidcService.CompleteLogin(state, code, dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 409, err.Status)
+
+	// the account is left unchanged
+	unlinkedUser, err := suite.usersRepository.QueryGetUserById(user.ID)
+	require.Nil(suite.T(), err)
+	assert.False(suite.T(), unlinkedUser.Verified)
+	assert.Equal(suite.T(), user.Password, unlinkedUser.Password)
+}
+
+func (suite *OidcServiceTestSuite) TestLoginFailUnverifiedEmail() {
+	suite.identityProvider.SetUser(testutils.StubOidcUser{
+		Subject: "subject-2",
+		Email:   "unverified@test.com",
+	})
+
+	state, code := suite.loginAtIdentityProvider(suite.oidcService)
+
+	_, _, err := suite.oidcService.CompleteLogin(state, code, dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 403, err.Status)
+}
+
+func (suite *OidcServiceTestSuite) TestLoginFailReusedState() {
+	state, code := suite.loginAtIdentityProvider(suite.oidcService)
+
+	_, _, err := suite.oidcService.CompleteLogin(state, code, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	_, _, err = suite.oidcService.CompleteLogin(state, code, dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+}
+
+// loginAtIdentityProvider starts a login and follows the redirect to the stub identity provider, which redirects back with state and code
+func (suite *OidcServiceTestSuite) loginAtIdentityProvider(oidcService OidcServiceInterface) (string, string) {
+	authUrl, _, err := oidcService.StartLogin()
+	require.Nil(suite.T(), err)
+
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	response, httpErr := httpClient.Get(authUrl)
+	require.NoError(suite.T(), httpErr)
+	defer response.Body.Close()
+	require.Equal(suite.T(), http.StatusFound, response.StatusCode)
+
+	callbackUrl, httpErr := url.Parse(response.Header.Get("Location"))
+	require.NoError(suite.T(), httpErr)
+	require.Equal(suite.T(), oidcTestRedirectUrl, callbackUrl.Scheme+"://"+callbackUrl.Host+callbackUrl.Path)
+
+	return callbackUrl.Query().Get("state"), callbackUrl.Query().Get("code")
+}
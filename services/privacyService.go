@@ -0,0 +1,262 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/utils"
+	"fmt"
+	"time"
+)
+
+// PrivacyService answers data subject requests, it exports and erases the data of users
+type PrivacyService struct {
+	usersRepository              repositories.UsersRepositoryInterface
+	rolesRepository              repositories.RolesRepositoryInterface
+	apiKeysRepository            repositories.ApiKeysRepositoryInterface
+	eventsRepository             repositories.EventsRepositoryInterface
+	registrationsRepository      repositories.RegistrationsRepositoryInterface
+	erasureRequestsRepository    repositories.ErasureRequestsRepositoryInterface
+	sessionsRepository           repositories.SessionsRepositoryInterface
+	userIdentitiesRepository     repositories.UserIdentitiesRepositoryInterface
+	waitlistRepository           repositories.WaitlistRepositoryInterface
+	calendarFeedTokensRepository repositories.CalendarFeedTokensRepositoryInterface
+	eventSeriesRepository        repositories.EventSeriesRepositoryInterface
+	venuesRepository             repositories.VenuesRepositoryInterface
+	mfaService                   MfaServiceInterface
+	tokensService                TokensServiceInterface
+	transactionHandler           repositories.TransactionHandler
+	registrationNotifier         RegistrationNotifier
+}
+
+func NewPrivacyService(
+	usersRepository repositories.UsersRepositoryInterface,
+	rolesRepository repositories.RolesRepositoryInterface,
+	apiKeysRepository repositories.ApiKeysRepositoryInterface,
+	eventsRepository repositories.EventsRepositoryInterface,
+	registrationsRepository repositories.RegistrationsRepositoryInterface,
+	erasureRequestsRepository repositories.ErasureRequestsRepositoryInterface,
+	sessionsRepository repositories.SessionsRepositoryInterface,
+	userIdentitiesRepository repositories.UserIdentitiesRepositoryInterface,
+	waitlistRepository repositories.WaitlistRepositoryInterface,
+	calendarFeedTokensRepository repositories.CalendarFeedTokensRepositoryInterface,
+	eventSeriesRepository repositories.EventSeriesRepositoryInterface,
+	venuesRepository repositories.VenuesRepositoryInterface,
+	mfaService MfaServiceInterface,
+	tokensService TokensServiceInterface,
+	transactionHandler repositories.TransactionHandler,
+	registrationNotifier RegistrationNotifier,
+) *PrivacyService {
+	return &PrivacyService{
+		usersRepository:              usersRepository,
+		rolesRepository:              rolesRepository,
+		apiKeysRepository:            apiKeysRepository,
+		eventsRepository:             eventsRepository,
+		registrationsRepository:      registrationsRepository,
+		erasureRequestsRepository:    erasureRequestsRepository,
+		sessionsRepository:           sessionsRepository,
+		userIdentitiesRepository:     userIdentitiesRepository,
+		waitlistRepository:           waitlistRepository,
+		calendarFeedTokensRepository: calendarFeedTokensRepository,
+		eventSeriesRepository:        eventSeriesRepository,
+		venuesRepository:             venuesRepository,
+		mfaService:                   mfaService,
+		tokensService:                tokensService,
+		transactionHandler:           transactionHandler,
+		registrationNotifier:         registrationNotifier,
+	}
+}
+
+func (ps PrivacyService) ExportUserData(userId string) (*dtos.UserDataExport, *models.ResponseError) {
+	user, responseErr := ps.usersRepository.QueryGetUserById(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	mfaEnabled, responseErr := ps.mfaService.IsTotpEnabled(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	roles, responseErr := ps.rolesRepository.QueryGetUserRoles(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	apiKeys, responseErr := ps.apiKeysRepository.QueryGetUserApiKeys(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	events, responseErr := ps.eventsRepository.QueryGetUserEvents(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	registrations, responseErr := ps.registrationsRepository.QueryGetUserRegistrations(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	identities, responseErr := ps.userIdentitiesRepository.QueryGetUserIdentities(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	calendarFeed, responseErr := ps.calendarFeedTokensRepository.QueryGetUserCalendarFeedToken(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	erasureRequests, responseErr := ps.erasureRequestsRepository.QueryGetUserErasureRequests(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	sessions, responseErr := ps.sessionsRepository.QueryGetAllUserSessions(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	eventSeries, responseErr := ps.eventSeriesRepository.QueryGetUserEventSeries(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	venues, responseErr := ps.venuesRepository.QueryGetUserVenues(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	cancelledRegistrations, responseErr := ps.registrationsRepository.QueryGetUserCancelledRegistrations(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	waitlistEntries, responseErr := ps.waitlistRepository.QueryGetUserWaitlistEntries(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return &dtos.UserDataExport{
+		ExportedAt: time.Now().UTC(),
+		Account: &dtos.UserProfile{
+			ID:          user.ID,
+			Email:       user.Email,
+			DisplayName: user.DisplayName,
+			Timezone:    user.Timezone,
+			Locale:      user.Locale,
+			Verified:    user.Verified,
+			MfaEnabled:  mfaEnabled,
+		},
+		Roles:                  roles,
+		ApiKeys:                apiKeys,
+		Identities:             identities,
+		CalendarFeed:           calendarFeed,
+		ErasureRequests:        erasureRequests,
+		Sessions:               sessions,
+		Events:                 events,
+		EventSeries:            eventSeries,
+		Venues:                 venues,
+		Registrations:          registrations,
+		CancelledRegistrations: cancelledRegistrations,
+		WaitlistEntries:        waitlistEntries,
+	}, nil
+}
+
+// RequestOwnErasure lets users request the erasure of their own data, the request is confirmed like other sensitive changes, see confirmIdentity
+func (ps PrivacyService) RequestOwnErasure(userId string, sessionId string, password string) (*models.ErasureRequest, *models.ResponseError) {
+	user, responseErr := ps.usersRepository.QueryGetUserById(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	responseErr = confirmIdentity(user, sessionId, password, ps.tokensService)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return ps.RequestErasure(userId, userId)
+}
+
+// RequestErasure queues the erasure of the user and ends all sessions right away. The erasure job processes the request
+func (ps PrivacyService) RequestErasure(userId string, requestedBy string) (*models.ErasureRequest, *models.ResponseError) {
+	_, responseErr := ps.usersRepository.QueryGetUserById(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	erasureRequest, responseErr := ps.erasureRequestsRepository.QueryCreateErasureRequest(userId, requestedBy)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	responseErr = ps.tokensService.RevokeAllUserTokens(userId, time.Now())
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return erasureRequest, nil
+}
+
+// ProcessErasureRequests erases the users of all pending requests, each one in its own transaction
+func (ps PrivacyService) ProcessErasureRequests() (int, *models.ResponseError) {
+	processed := 0
+
+	for {
+		erasureRequest, promotedRegistrations, responseErr := ps.transactionHandler.ExecEraseNextUserTx()
+
+		if responseErr != nil {
+			return processed, responseErr
+		}
+
+		if erasureRequest == nil {
+			return processed, nil
+		}
+
+		notifyPromotions(ps.registrationNotifier, promotedRegistrations)
+
+		processed++
+	}
+}
+
+// StartErasureJob processes pending erasure requests in the given interval
+func (ps PrivacyService) StartErasureJob(interval time.Duration, logger *utils.Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			processed, responseErr := ps.ProcessErasureRequests()
+
+			if responseErr != nil {
+				logger.Log(utils.LevelError, fmt.Sprintf("Could not process erasure requests: %s", responseErr.Message), nil)
+			}
+
+			if processed > 0 {
+				logger.Log(utils.LevelInfo, fmt.Sprintf("Erased %d users", processed), nil)
+			}
+		}
+	}()
+}
+
+var _ PrivacyServiceInterface = (*PrivacyService)(nil)
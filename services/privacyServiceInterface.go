@@ -0,0 +1,16 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
+
+type PrivacyServiceInterface interface {
+	ExportUserData(userId string) (*dtos.UserDataExport, *models.ResponseError)
+
+	RequestOwnErasure(userId string, sessionId string, password string) (*models.ErasureRequest, *models.ResponseError)
+
+	RequestErasure(userId string, requestedBy string) (*models.ErasureRequest, *models.ResponseError)
+
+	ProcessErasureRequests() (int, *models.ResponseError)
+}
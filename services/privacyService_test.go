@@ -0,0 +1,152 @@
+package services
+
+import (
+	"context"
+	"eventom-backend/mailers"
+	"eventom-backend/repositories"
+	"eventom-backend/testutils"
+	"eventom-backend/utils"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type PrivacyServiceTestSuite struct {
+	suite.Suite
+	ctx                context.Context
+	privacyService     PrivacyServiceInterface
+	usersRepository    *repositories.UsersRepository
+	eventsRepository   *repositories.EventsRepository
+	transactionHandler *repositories.TransactionHandler
+}
+
+func TestPrivacyServiceSuite(t *testing.T) {
+	suite.Run(t, &PrivacyServiceTestSuite{})
+}
+
+func (suite *PrivacyServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	suite.usersRepository = repositories.NewUsersRepository(testutils.TestContainer.DB)
+	suite.eventsRepository = repositories.NewEventsRepository(testutils.TestContainer.DB)
+	suite.transactionHandler = repositories.NewTxHandler(testutils.TestContainer.DB)
+	registrationsRepository := repositories.NewRegistrationsRepository(testutils.TestContainer.DB)
+	refreshTokensRepository := repositories.NewRefreshTokensRepository(testutils.TestContainer.DB)
+	revokedTokensRepository := repositories.NewRevokedTokensRepository(testutils.TestContainer.DB)
+	sessionsRepository := repositories.NewSessionsRepository(testutils.TestContainer.DB)
+	rolesRepository := repositories.NewRolesRepository(testutils.TestContainer.DB)
+	apiKeysRepository := repositories.NewApiKeysRepository(testutils.TestContainer.DB)
+	mfaRepository := repositories.NewMfaRepository(testutils.TestContainer.DB)
+	erasureRequestsRepository := repositories.NewErasureRequestsRepository(testutils.TestContainer.DB)
+	userIdentitiesRepository := repositories.NewUserIdentitiesRepository(testutils.TestContainer.DB)
+	waitlistRepository := repositories.NewWaitlistRepository(testutils.TestContainer.DB)
+	calendarFeedTokensRepository := repositories.NewCalendarFeedTokensRepository(testutils.TestContainer.DB)
+	eventSeriesRepository := repositories.NewEventSeriesRepository(testutils.TestContainer.DB)
+	venuesRepository := repositories.NewVenuesRepository(testutils.TestContainer.DB)
+	tokensService := NewTokensService(refreshTokensRepository, revokedTokensRepository, sessionsRepository, rolesRepository, *suite.transactionHandler)
+	mfaService := NewMfaService(mfaRepository, suite.usersRepository, *suite.transactionHandler)
+	registrationNotifier := NewMailRegistrationNotifier(suite.usersRepository, suite.eventsRepository, mailers.NewInMemoryMailer(), utils.NewLogger(os.Stdout))
+	suite.privacyService = NewPrivacyService(suite.usersRepository, rolesRepository, apiKeysRepository, suite.eventsRepository, registrationsRepository, erasureRequestsRepository, sessionsRepository, userIdentitiesRepository, waitlistRepository, calendarFeedTokensRepository, eventSeriesRepository, venuesRepository, mfaService, tokensService, *suite.transactionHandler, registrationNotifier)
+}
+
+func (suite *PrivacyServiceTestSuite) BeforeTest(suiteName, testName string) {
+	// events of erased users are kept, so they have to be removed before the users
+	queries := []string{
+		`DELETE FROM registrations`,
+		`DELETE FROM events`,
+		`DELETE FROM users`,
+	}
+
+	for _, query := range queries {
+		_, err := testutils.TestContainer.DB.Exec(query)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func (suite *PrivacyServiceTestSuite) TestExportUserData() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+
+	organizerEvent := createTestEvent(suite.T(), suite.eventsRepository, "Organizer event", organizer.ID, 10)
+	_ = createTestEvent(suite.T(), suite.eventsRepository, "Attendee event", attendee.ID, 10)
+
+	_, err := suite.transactionHandler.ExecTx(organizerEvent.ID, attendee.ID)
+	require.Nil(suite.T(), err)
+
+	userDataExport, err := suite.privacyService.ExportUserData(attendee.ID)
+	require.Nil(suite.T(), err)
+
+	assert.Equal(suite.T(), "attendee@test.com", userDataExport.Account.Email)
+	require.Len(suite.T(), userDataExport.Events, 1)
+	assert.Equal(suite.T(), "Attendee event", userDataExport.Events[0].Name)
+	require.Len(suite.T(), userDataExport.Registrations, 1)
+	require.NotNil(suite.T(), userDataExport.Registrations[0].Event)
+	assert.Equal(suite.T(), organizerEvent.ID, userDataExport.Registrations[0].Event.ID)
+}
+
+func (suite *PrivacyServiceTestSuite) TestEraseUser() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+
+	organizerEvent := createTestEvent(suite.T(), suite.eventsRepository, "Organizer event", organizer.ID, 10)
+	attendeeEvent := createTestEvent(suite.T(), suite.eventsRepository, "Attendee event", attendee.ID, 10)
+
+	_, err := suite.transactionHandler.ExecTx(organizerEvent.ID, attendee.ID)
+	require.Nil(suite.T(), err)
+
+	erasureRequest, err := suite.privacyService.RequestErasure(attendee.ID, organizer.ID)
+	require.Nil(suite.T(), err)
+
+	// a second request for the same user returns the pending one
+	repeatedRequest, err := suite.privacyService.RequestErasure(attendee.ID, attendee.ID)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), erasureRequest.ID, repeatedRequest.ID)
+
+	processed, err := suite.privacyService.ProcessErasureRequests()
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 1, processed)
+
+	erasedUser, err := suite.usersRepository.QueryGetUserById(attendee.ID)
+	require.Nil(suite.T(), err)
+	assert.NotEqual(suite.T(), "attendee@test.com", erasedUser.Email)
+	assert.Empty(suite.T(), erasedUser.DisplayName)
+
+	// the seat of the erased user is freed, the event of the erased user is kept
+	event, err := suite.eventsRepository.QueryGetEvent(organizerEvent.ID)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, event.AmountRegistration)
+
+	_, err = suite.eventsRepository.QueryGetEvent(attendeeEvent.ID)
+	assert.Nil(suite.T(), err)
+
+	processed, err = suite.privacyService.ProcessErasureRequests()
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, processed)
+}
+
+func (suite *PrivacyServiceTestSuite) TestRequestErasureFailUnknownUser() {
+	user := createTestUser(suite.T(), suite.usersRepository, "test@test.com")
+
+	_, err := suite.privacyService.RequestErasure("00000000-0000-0000-0000-000000000000", user.ID)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 404, err.Status)
+}
@@ -0,0 +1,101 @@
+package services
+
+import (
+	"eventom-backend/mailers"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/utils"
+	"fmt"
+)
+
+// RegistrationNotifier is called after registrations have been changed by the system instead of the attendee, e.g. a user has been moved
+// from the waitlist to the registrations of an event. The change is committed already, so implementations handle their own failures
+type RegistrationNotifier interface {
+	NotifyPromotion(registration *models.Registration)
+
+	NotifyCancellation(registration *models.Registration, event *models.Event)
+}
+
+// MailRegistrationNotifier tells attendees by mail that they got a seat or lost it
+type MailRegistrationNotifier struct {
+	usersRepository  repositories.UsersRepositoryInterface
+	eventsRepository repositories.EventsRepositoryInterface
+	mailer           mailers.Mailer
+	logger           *utils.Logger
+}
+
+func NewMailRegistrationNotifier(
+	usersRepository repositories.UsersRepositoryInterface,
+	eventsRepository repositories.EventsRepositoryInterface,
+	mailer mailers.Mailer,
+	logger *utils.Logger,
+) *MailRegistrationNotifier {
+	return &MailRegistrationNotifier{
+		usersRepository:  usersRepository,
+		eventsRepository: eventsRepository,
+		mailer:           mailer,
+		logger:           logger,
+	}
+}
+
+func (mrn MailRegistrationNotifier) NotifyPromotion(registration *models.Registration) {
+	user, responseErr := mrn.usersRepository.QueryGetUserById(registration.UserId)
+
+	if responseErr != nil {
+		mrn.logger.Log(utils.LevelError, fmt.Sprintf("Could not notify user %s about registration %s: %s", registration.UserId, registration.ID, responseErr.Message), nil)
+		return
+	}
+
+	event, responseErr := mrn.eventsRepository.QueryGetEvent(registration.EventId)
+
+	if responseErr != nil {
+		mrn.logger.Log(utils.LevelError, fmt.Sprintf("Could not notify user %s about registration %s: %s", registration.UserId, registration.ID, responseErr.Message), nil)
+		return
+	}
+
+	err := mrn.mailer.Send(&mailers.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("You got a seat for %s", event.Name),
+		Body:    fmt.Sprintf("A seat for %s on %s in %s has become available and you have been moved from the waitlist to the registered attendees.\n\nIf you cannot attend anymore, please cancel your registration so the next person on the waitlist gets the seat.\n", event.Name, event.StartsAt.In(event.TimeLocation()).Format("January 2, 2006 15:04 MST"), event.Location),
+	})
+
+	if err != nil {
+		mrn.logger.Log(utils.LevelError, fmt.Sprintf("Could not send promotion email for registration %s: %s", registration.ID, err.Error()), nil)
+		return
+	}
+
+	mrn.logger.Log(utils.LevelInfo, fmt.Sprintf("User %s promoted from the waitlist with registration %s", registration.UserId, registration.ID), nil)
+}
+
+func (mrn MailRegistrationNotifier) NotifyCancellation(registration *models.Registration, event *models.Event) {
+	user, responseErr := mrn.usersRepository.QueryGetUserById(registration.UserId)
+
+	if responseErr != nil {
+		mrn.logger.Log(utils.LevelError, fmt.Sprintf("Could not notify user %s about cancelled registration %s: %s", registration.UserId, registration.ID, responseErr.Message), nil)
+		return
+	}
+
+	body := fmt.Sprintf("%s on %s in %s has been cancelled by the organizer and your registration has been cancelled with it.\n", event.Name, event.StartsAt.In(event.TimeLocation()).Format("January 2, 2006 15:04 MST"), event.Location)
+	if event.DeletionReason != "" {
+		body += fmt.Sprintf("\nThe organizer left the following message:\n\n%s\n", event.DeletionReason)
+	}
+
+	err := mrn.mailer.Send(&mailers.Message{
+		To:      user.Email,
+		Subject: fmt.Sprintf("%s has been cancelled", event.Name),
+		Body:    body,
+	})
+
+	if err != nil {
+		mrn.logger.Log(utils.LevelError, fmt.Sprintf("Could not send cancellation email for registration %s: %s", registration.ID, err.Error()), nil)
+	}
+}
+
+// notifyPromotions passes all promoted registrations to the notifier
+func notifyPromotions(registrationNotifier RegistrationNotifier, promotedRegistrations []*models.Registration) {
+	for _, registration := range promotedRegistrations {
+		registrationNotifier.NotifyPromotion(registration)
+	}
+}
+
+var _ RegistrationNotifier = (*MailRegistrationNotifier)(nil)
@@ -1,19 +1,35 @@
 package services
 
 import (
+	"eventom-backend/dtos"
 	"eventom-backend/models"
 	"eventom-backend/repositories"
+	"net/http"
+
+	"github.com/google/uuid"
 )
 
 type RegistrationsService struct {
 	registrationsRepository repositories.RegistrationsRepositoryInterface
+	waitlistRepository      repositories.WaitlistRepositoryInterface
+	eventsRepository        repositories.EventsRepositoryInterface
 	transactionHandler      repositories.TransactionHandler
+	registrationNotifier    RegistrationNotifier
 }
 
-func NewRegistrationsService(registrationsRepository repositories.RegistrationsRepositoryInterface, transactionHandler repositories.TransactionHandler) *RegistrationsService {
+func NewRegistrationsService(
+	registrationsRepository repositories.RegistrationsRepositoryInterface,
+	waitlistRepository repositories.WaitlistRepositoryInterface,
+	eventsRepository repositories.EventsRepositoryInterface,
+	transactionHandler repositories.TransactionHandler,
+	registrationNotifier RegistrationNotifier,
+) *RegistrationsService {
 	return &RegistrationsService{
 		registrationsRepository: registrationsRepository,
+		waitlistRepository:      waitlistRepository,
+		eventsRepository:        eventsRepository,
 		transactionHandler:      transactionHandler,
+		registrationNotifier:    registrationNotifier,
 	}
 }
 
@@ -29,8 +45,148 @@ func (rs RegistrationsService) GetAllRegistration() ([]*models.Registration, *mo
 	return rs.registrationsRepository.QueryGetAllRegistrations()
 }
 
-func (rs RegistrationsService) CancelRegistration(eventId string, userId string) (*models.Registration, *models.ResponseError) {
-	return rs.registrationsRepository.QueryCancelRegistration(eventId, userId)
+func (rs RegistrationsService) GetUserRegistrations(userId string, filters *dtos.UserRegistrationFilterDto) ([]*models.Registration, int, *models.ResponseError) {
+	return rs.registrationsRepository.QueryGetUserRegistrationsPage(userId, filters)
+}
+
+// GetEventAttendees lists the attendees of the given event if it was created by the given user. manageAny allows to list the attendees
+// of events of other users
+func (rs RegistrationsService) GetEventAttendees(userId string, manageAny bool, eventId string, filters *dtos.AttendeeFilterDto) ([]*dtos.Attendee, int, *models.ResponseError) {
+	event, responseErr := rs.getOwnEvent(userId, manageAny, eventId)
+
+	if responseErr != nil {
+		return nil, 0, responseErr
+	}
+
+	return rs.registrationsRepository.QueryGetEventAttendees(event.ID, filters)
+}
+
+// ExportEventAttendees streams the attendees of the event into the writer, see GetEventAttendees for the access rules.
+// The writer is only started if the user may export the attendees
+func (rs RegistrationsService) ExportEventAttendees(userId string, manageAny bool, eventId string, writer AttendeeExportWriter) *models.ResponseError {
+	event, responseErr := rs.getOwnEvent(userId, manageAny, eventId)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	err := writer.Begin(event)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	responseErr = rs.registrationsRepository.QueryStreamEventAttendees(event.ID, writer.Write)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	err = writer.End()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// getOwnEvent returns the event if it was created by the given user or manageAny is set
+func (rs RegistrationsService) getOwnEvent(userId string, manageAny bool, eventId string) (*models.Event, *models.ResponseError) {
+	if uuid.Validate(eventId) != nil {
+		return nil, &models.ResponseError{
+			Message: "Event not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	event, responseErr := rs.eventsRepository.QueryGetEvent(eventId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if event.UserId != userId && !manageAny {
+		return nil, &models.ResponseError{
+			Message: "Access denied",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	return event, nil
+}
+
+// CancelRegistration cancels the registration with the given id if it belongs to the given user. manageAny allows to cancel registrations
+// of other users, without it registrations of other users are not found, so their ids can not be probed. The free seat goes to the next
+// user on the waitlist of the event right away
+func (rs RegistrationsService) CancelRegistration(userId string, manageAny bool, registrationId string) (*models.Registration, *models.ResponseError) {
+	if uuid.Validate(registrationId) != nil {
+		return nil, &models.ResponseError{
+			Message: "Registration not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	var registration *models.Registration
+	var responseErr *models.ResponseError
+
+	if manageAny {
+		registration, responseErr = rs.registrationsRepository.QueryGetRegistrationById(registrationId)
+	} else {
+		registration, responseErr = rs.registrationsRepository.QueryGetUserRegistrationById(registrationId, userId)
+	}
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return rs.CancelEventRegistration(userId, manageAny, registration.EventId, registration.UserId)
+}
+
+// CancelEventRegistration cancels the registration of the attendee for the given event. Users can only cancel their own registrations,
+// manageAny allows to cancel registrations of other users
+func (rs RegistrationsService) CancelEventRegistration(userId string, manageAny bool, eventId string, attendeeId string) (*models.Registration, *models.ResponseError) {
+	if attendeeId != userId && !manageAny {
+		return nil, &models.ResponseError{
+			Message: "Access denied",
+			Status:  http.StatusForbidden,
+		}
+	}
+
+	if uuid.Validate(eventId) != nil {
+		return nil, &models.ResponseError{
+			Message: "Event not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	cancelledRegistration, promotedRegistrations, responseErr := rs.transactionHandler.ExecCancelRegistrationTx(eventId, attendeeId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	notifyPromotions(rs.registrationNotifier, promotedRegistrations)
+
+	return cancelledRegistration, nil
+}
+
+func (rs RegistrationsService) JoinWaitlist(eventId string, userId string) (*models.WaitlistEntry, *models.ResponseError) {
+	return rs.transactionHandler.ExecJoinWaitlistTx(eventId, userId)
+}
+
+func (rs RegistrationsService) GetWaitlistEntries(userId string) ([]*models.WaitlistEntry, *models.ResponseError) {
+	return rs.waitlistRepository.QueryGetUserWaitlistEntries(userId)
+}
+
+func (rs RegistrationsService) LeaveWaitlist(eventId string, userId string) *models.ResponseError {
+	return rs.waitlistRepository.QueryDeleteWaitlistEntry(eventId, userId)
 }
 
 var _ RegistrationsServiceInterface = (*RegistrationsService)(nil)
@@ -1,6 +1,19 @@
 package services
 
-import "eventom-backend/models"
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
+
+// AttendeeExportWriter writes the attendees of an event in an export format. Begin is called once before the first attendee
+// and End after the last one
+type AttendeeExportWriter interface {
+	Begin(event *models.Event) error
+
+	Write(attendee *dtos.Attendee) error
+
+	End() error
+}
 
 type RegistrationsServiceInterface interface {
 	RegisterUserForEvent(eventId string, userId string) (*models.Registration, *models.ResponseError)
@@ -9,5 +22,19 @@ type RegistrationsServiceInterface interface {
 
 	GetAllRegistration() ([]*models.Registration, *models.ResponseError)
 
-	CancelRegistration(eventId string, userId string) (*models.Registration, *models.ResponseError)
+	GetUserRegistrations(userId string, filters *dtos.UserRegistrationFilterDto) ([]*models.Registration, int, *models.ResponseError)
+
+	GetEventAttendees(userId string, manageAny bool, eventId string, filters *dtos.AttendeeFilterDto) ([]*dtos.Attendee, int, *models.ResponseError)
+
+	ExportEventAttendees(userId string, manageAny bool, eventId string, writer AttendeeExportWriter) *models.ResponseError
+
+	CancelRegistration(userId string, manageAny bool, registrationId string) (*models.Registration, *models.ResponseError)
+
+	CancelEventRegistration(userId string, manageAny bool, eventId string, attendeeId string) (*models.Registration, *models.ResponseError)
+
+	JoinWaitlist(eventId string, userId string) (*models.WaitlistEntry, *models.ResponseError)
+
+	GetWaitlistEntries(userId string) ([]*models.WaitlistEntry, *models.ResponseError)
+
+	LeaveWaitlist(eventId string, userId string) *models.ResponseError
 }
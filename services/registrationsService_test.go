@@ -0,0 +1,305 @@
+package services
+
+import (
+	"context"
+	"eventom-backend/dtos"
+	"eventom-backend/mailers"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/testutils"
+	"eventom-backend/utils"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type RegistrationsServiceTestSuite struct {
+	suite.Suite
+	ctx                  context.Context
+	registrationsService RegistrationsServiceInterface
+	usersRepository      *repositories.UsersRepository
+	eventsRepository     *repositories.EventsRepository
+	mailer               *mailers.InMemoryMailer
+}
+
+func TestRegistrationsServiceSuite(t *testing.T) {
+	suite.Run(t, &RegistrationsServiceTestSuite{})
+}
+
+func (suite *RegistrationsServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	suite.usersRepository = repositories.NewUsersRepository(testutils.TestContainer.DB)
+	suite.eventsRepository = repositories.NewEventsRepository(testutils.TestContainer.DB)
+	suite.mailer = mailers.NewInMemoryMailer()
+	registrationsRepository := repositories.NewRegistrationsRepository(testutils.TestContainer.DB)
+	waitlistRepository := repositories.NewWaitlistRepository(testutils.TestContainer.DB)
+	transactionHandler := repositories.NewTxHandler(testutils.TestContainer.DB)
+	registrationNotifier := NewMailRegistrationNotifier(suite.usersRepository, suite.eventsRepository, suite.mailer, utils.NewLogger(os.Stdout))
+	suite.registrationsService = NewRegistrationsService(registrationsRepository, waitlistRepository, suite.eventsRepository, *transactionHandler, registrationNotifier)
+}
+
+func (suite *RegistrationsServiceTestSuite) BeforeTest(suiteName, testName string) {
+	// events do not cascade on users, so they have to be removed before the users
+	queries := []string{
+		`DELETE FROM registrations`,
+		`DELETE FROM events`,
+		`DELETE FROM users`,
+	}
+
+	for _, query := range queries {
+		_, err := testutils.TestContainer.DB.Exec(query)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func (suite *RegistrationsServiceTestSuite) TestCancelRegistrationPromotesFromWaitlist() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	first := createTestUser(suite.T(), suite.usersRepository, "first@test.com")
+	second := createTestUser(suite.T(), suite.usersRepository, "second@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, fmt.Sprintf("Event for %d", 1), organizer.ID, 1)
+
+	_, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	_, responseErr = suite.registrationsService.RegisterUserForEvent(event.ID, first.ID)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusConflict, responseErr.Status)
+
+	firstEntry, responseErr := suite.registrationsService.JoinWaitlist(event.ID, first.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 1, firstEntry.Position)
+
+	secondEntry, responseErr := suite.registrationsService.JoinWaitlist(event.ID, second.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 2, secondEntry.Position)
+
+	_, responseErr = suite.registrationsService.CancelEventRegistration(attendee.ID, false, event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	registration, responseErr := suite.registrationsService.GetRegistration(event.ID, first.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.NotNil(suite.T(), registration)
+
+	updatedEvent, responseErr := suite.eventsRepository.QueryGetEvent(event.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 1, updatedEvent.AmountRegistration)
+
+	secondEntries, responseErr := suite.registrationsService.GetWaitlistEntries(second.ID)
+	require.Nil(suite.T(), responseErr)
+	require.Len(suite.T(), secondEntries, 1)
+	assert.Equal(suite.T(), 1, secondEntries[0].Position)
+
+	firstEntries, responseErr := suite.registrationsService.GetWaitlistEntries(first.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Empty(suite.T(), firstEntries)
+
+	messages := suite.mailer.Messages()
+	require.NotEmpty(suite.T(), messages)
+	assert.Equal(suite.T(), first.Email, messages[len(messages)-1].To)
+}
+
+func (suite *RegistrationsServiceTestSuite) TestCancelRegistration() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, fmt.Sprintf("Event for %d", 2), organizer.ID, 2)
+
+	registration, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	cancelledRegistration, responseErr := suite.registrationsService.CancelRegistration(attendee.ID, false, registration.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), registration.ID, cancelledRegistration.ID)
+	assert.Equal(suite.T(), event.ID, cancelledRegistration.EventId)
+
+	updatedEvent, responseErr := suite.eventsRepository.QueryGetEvent(event.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 0, updatedEvent.AmountRegistration)
+
+	_, responseErr = suite.registrationsService.CancelRegistration(attendee.ID, false, registration.ID)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusNotFound, responseErr.Status)
+}
+
+func (suite *RegistrationsServiceTestSuite) TestCancelRegistrationFailForeignRegistration() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, fmt.Sprintf("Event for %d", 2), organizer.ID, 2)
+
+	registration, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	// foreign registrations are answered like unknown ones
+	_, responseErr = suite.registrationsService.CancelRegistration(organizer.ID, false, registration.ID)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusNotFound, responseErr.Status)
+
+	_, responseErr = suite.registrationsService.CancelEventRegistration(organizer.ID, false, event.ID, attendee.ID)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusForbidden, responseErr.Status)
+
+	cancelledRegistration, responseErr := suite.registrationsService.CancelRegistration(organizer.ID, true, registration.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), attendee.ID, cancelledRegistration.UserId)
+}
+
+func (suite *RegistrationsServiceTestSuite) TestGetUserRegistrations() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+
+	for maxCapacity := 1; maxCapacity <= 3; maxCapacity++ {
+		event := createTestEvent(suite.T(), suite.eventsRepository, fmt.Sprintf("Event for %d", maxCapacity), organizer.ID, maxCapacity)
+		_, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+		require.Nil(suite.T(), responseErr)
+	}
+
+	registrationsList, totalCount, responseErr := suite.registrationsService.GetUserRegistrations(attendee.ID, &dtos.UserRegistrationFilterDto{
+		Page:       1,
+		PageSize:   2,
+		SortColumn: "event_name",
+		SortOrder:  "DESC",
+	})
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 3, totalCount)
+	require.Len(suite.T(), registrationsList, 2)
+	require.NotNil(suite.T(), registrationsList[0].Event)
+	assert.Equal(suite.T(), "Event for 3", registrationsList[0].Event.Name)
+
+	registrationsList, _, responseErr = suite.registrationsService.GetUserRegistrations(organizer.ID, &dtos.UserRegistrationFilterDto{
+		Page:       1,
+		PageSize:   10,
+		SortColumn: "event_start",
+		SortOrder:  "ASC",
+	})
+	require.Nil(suite.T(), responseErr)
+	assert.Empty(suite.T(), registrationsList)
+}
+
+func (suite *RegistrationsServiceTestSuite) TestGetEventAttendees() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, fmt.Sprintf("Event for %d", 2), organizer.ID, 2)
+
+	_, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	filters := &dtos.AttendeeFilterDto{
+		Page:       1,
+		PageSize:   10,
+		SortColumn: "email",
+		SortOrder:  "ASC",
+	}
+
+	attendeesList, totalCount, responseErr := suite.registrationsService.GetEventAttendees(organizer.ID, false, event.ID, filters)
+	require.Nil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 1, totalCount)
+	require.Len(suite.T(), attendeesList, 1)
+	assert.Equal(suite.T(), attendee.Email, attendeesList[0].Email)
+
+	_, _, responseErr = suite.registrationsService.GetEventAttendees(attendee.ID, false, event.ID, filters)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusForbidden, responseErr.Status)
+}
+
+func (suite *RegistrationsServiceTestSuite) TestExportEventAttendees() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, fmt.Sprintf("Event for %d", 2), organizer.ID, 2)
+
+	_, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	writer := &recordingAttendeeWriter{}
+	responseErr = suite.registrationsService.ExportEventAttendees(attendee.ID, false, event.ID, writer)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusForbidden, responseErr.Status)
+	assert.False(suite.T(), writer.begun)
+
+	responseErr = suite.registrationsService.ExportEventAttendees(organizer.ID, false, event.ID, writer)
+	require.Nil(suite.T(), responseErr)
+	assert.True(suite.T(), writer.begun)
+	assert.True(suite.T(), writer.ended)
+	require.Len(suite.T(), writer.attendees, 1)
+	assert.Equal(suite.T(), attendee.Email, writer.attendees[0].Email)
+}
+
+func (suite *RegistrationsServiceTestSuite) TestJoinWaitlistFailEventNotFull() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, fmt.Sprintf("Event for %d", 2), organizer.ID, 2)
+
+	_, responseErr := suite.registrationsService.JoinWaitlist(event.ID, attendee.ID)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusConflict, responseErr.Status)
+}
+
+func (suite *RegistrationsServiceTestSuite) TestLeaveWaitlist() {
+	organizer := createTestUser(suite.T(), suite.usersRepository, "organizer@test.com")
+	attendee := createTestUser(suite.T(), suite.usersRepository, "attendee@test.com")
+	waiting := createTestUser(suite.T(), suite.usersRepository, "waiting@test.com")
+	event := createTestEvent(suite.T(), suite.eventsRepository, fmt.Sprintf("Event for %d", 1), organizer.ID, 1)
+
+	_, responseErr := suite.registrationsService.RegisterUserForEvent(event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	_, responseErr = suite.registrationsService.JoinWaitlist(event.ID, waiting.ID)
+	require.Nil(suite.T(), responseErr)
+
+	responseErr = suite.registrationsService.LeaveWaitlist(event.ID, waiting.ID)
+	require.Nil(suite.T(), responseErr)
+
+	_, responseErr = suite.registrationsService.CancelEventRegistration(attendee.ID, false, event.ID, attendee.ID)
+	require.Nil(suite.T(), responseErr)
+
+	registration, responseErr := suite.registrationsService.GetRegistration(event.ID, waiting.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Nil(suite.T(), registration)
+
+	responseErr = suite.registrationsService.LeaveWaitlist(event.ID, waiting.ID)
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), http.StatusNotFound, responseErr.Status)
+}
+
+// recordingAttendeeWriter keeps the exported attendees in memory
+type recordingAttendeeWriter struct {
+	begun     bool
+	ended     bool
+	attendees []*dtos.Attendee
+}
+
+func (raw *recordingAttendeeWriter) Begin(event *models.Event) error {
+	raw.begun = true
+	return nil
+}
+
+func (raw *recordingAttendeeWriter) Write(attendee *dtos.Attendee) error {
+	raw.attendees = append(raw.attendees, attendee)
+	return nil
+}
+
+func (raw *recordingAttendeeWriter) End() error {
+	raw.ended = true
+	return nil
+}
@@ -0,0 +1,104 @@
+package services
+
+import (
+	"eventom-backend/models"
+	"sync"
+	"time"
+)
+
+// revocationCache keeps revoked access tokens in memory, so the auth middleware does not have to query the database on every request
+type revocationCache struct {
+	mutex           sync.RWMutex
+	revokedTokens   map[string]time.Time
+	revokedBefore   map[string]time.Time
+	revokedSessions map[string]time.Time
+}
+
+func newRevocationCache() *revocationCache {
+	return &revocationCache{
+		revokedTokens:   make(map[string]time.Time),
+		revokedBefore:   make(map[string]time.Time),
+		revokedSessions: make(map[string]time.Time),
+	}
+}
+
+func (rc *revocationCache) addToken(tokenId string, expiresAt time.Time) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.revokedTokens[tokenId] = expiresAt
+}
+
+func (rc *revocationCache) addUser(userId string, revokedBefore time.Time) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	if revokedBefore.After(rc.revokedBefore[userId]) {
+		rc.revokedBefore[userId] = revokedBefore
+	}
+}
+
+func (rc *revocationCache) addSession(sessionId string, revokedAt time.Time) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.revokedSessions[sessionId] = revokedAt
+}
+
+func (rc *revocationCache) isRevoked(tokenId string, userId string, sessionId string, issuedAt time.Time) bool {
+	rc.mutex.RLock()
+	defer rc.mutex.RUnlock()
+
+	if _, found := rc.revokedTokens[tokenId]; found {
+		return true
+	}
+
+	// revoking all tokens of a user ends the sessions, so tokens of a session are only revoked by it. New sessions that are started
+	// in the same second, e.g. after a password change, stay valid
+	if sessionId != "" {
+		_, found := rc.revokedSessions[sessionId]
+		return found
+	}
+
+	revokedBefore, found := rc.revokedBefore[userId]
+
+	// tokens issued before sessions have been tracked only carry the issue time in seconds, so all tokens of the second of the
+	// revocation are revoked. Otherwise a token issued right before the revocation in the same second would stay valid
+	return found && !issuedAt.Truncate(time.Second).After(revokedBefore.Truncate(time.Second))
+}
+
+// merge adds revocations persisted by other instances of the app
+func (rc *revocationCache) merge(revokedTokens []*models.RevokedToken, revocations []*models.UserTokenRevocation, revokedSessions []*models.Session) {
+	for _, revokedToken := range revokedTokens {
+		rc.addToken(revokedToken.TokenId, revokedToken.ExpiresAt)
+	}
+
+	for _, revocation := range revocations {
+		rc.addUser(revocation.UserId, revocation.RevokedBefore)
+	}
+
+	for _, revokedSession := range revokedSessions {
+		rc.addSession(revokedSession.ID, *revokedSession.RevokedAt)
+	}
+}
+
+// sweep drops all entries that cannot match a valid access token anymore
+func (rc *revocationCache) sweep(accessTokenTTL time.Duration) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+
+	for tokenId, expiresAt := range rc.revokedTokens {
+		if time.Now().After(expiresAt) {
+			delete(rc.revokedTokens, tokenId)
+		}
+	}
+
+	for userId, revokedBefore := range rc.revokedBefore {
+		if time.Since(revokedBefore) > accessTokenTTL {
+			delete(rc.revokedBefore, userId)
+		}
+	}
+
+	for sessionId, revokedAt := range rc.revokedSessions {
+		if time.Since(revokedAt) > accessTokenTTL {
+			delete(rc.revokedSessions, sessionId)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRevocationCacheRevokesTokensOfTheSameSecond(t *testing.T) {
+	cache := newRevocationCache()
+	revokedBefore := time.Date(2024, time.May, 1, 12, 0, 0, 500_000_000, time.UTC)
+	cache.addUser("user", revokedBefore)
+
+	// access tokens without a session only carry whole seconds
+	assert.True(t, cache.isRevoked("token", "user", "", time.Date(2024, time.May, 1, 11, 59, 59, 0, time.UTC)))
+	assert.True(t, cache.isRevoked("token", "user", "", time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)))
+	assert.False(t, cache.isRevoked("token", "user", "", time.Date(2024, time.May, 1, 12, 0, 1, 0, time.UTC)))
+	assert.False(t, cache.isRevoked("token", "other", "", time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)))
+}
+
+func TestRevocationCacheRevokesTokensBySession(t *testing.T) {
+	cache := newRevocationCache()
+	revokedAt := time.Date(2024, time.May, 1, 12, 0, 0, 500_000_000, time.UTC)
+	cache.addUser("user", revokedAt)
+	cache.addSession("old-session", revokedAt)
+
+	// tokens of the ended session are revoked, a session started in the same second keeps its real issue time and stays valid
+	assert.True(t, cache.isRevoked("token", "user", "old-session", time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)))
+	assert.False(t, cache.isRevoked("token", "user", "new-session", time.Date(2024, time.May, 1, 12, 0, 0, 0, time.UTC)))
+}
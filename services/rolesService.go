@@ -0,0 +1,64 @@
+package services
+
+import (
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"net/http"
+)
+
+type RolesService struct {
+	rolesRepository repositories.RolesRepositoryInterface
+	usersRepository repositories.UsersRepositoryInterface
+}
+
+func NewRolesService(rolesRepository repositories.RolesRepositoryInterface, usersRepository repositories.UsersRepositoryInterface) *RolesService {
+	return &RolesService{
+		rolesRepository: rolesRepository,
+		usersRepository: usersRepository,
+	}
+}
+
+func (rs RolesService) GetRoles() ([]*models.Role, *models.ResponseError) {
+	return rs.rolesRepository.QueryGetRoles()
+}
+
+func (rs RolesService) GetUserRoles(userId string) ([]string, *models.ResponseError) {
+	return rs.rolesRepository.QueryGetUserRoles(userId)
+}
+
+func (rs RolesService) AssignRole(userId string, roleName string) *models.ResponseError {
+	return rs.rolesRepository.QueryAssignRole(userId, roleName)
+}
+
+func (rs RolesService) RemoveRole(actingUserId string, userId string, roleName string) *models.ResponseError {
+	// prevent admins from locking themselves out of role management
+	if actingUserId == userId && roleName == models.RoleAdmin {
+		return &models.ResponseError{
+			Message: "Admins cannot remove their own admin role",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	return rs.rolesRepository.QueryRemoveRole(userId, roleName)
+}
+
+// BootstrapAdmin grants the admin role to the user with the given email, so there is someone who can assign roles to the others.
+// The address has to be verified, otherwise anyone could sign up with it before its owner does
+func (rs RolesService) BootstrapAdmin(email string) *models.ResponseError {
+	user, responseErr := rs.usersRepository.QueryGetUser(email)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	if !user.Verified {
+		return &models.ResponseError{
+			Message: "Email address of the admin has not been verified yet",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	return rs.rolesRepository.QueryAssignRole(user.ID, models.RoleAdmin)
+}
+
+var _ RolesServiceInterface = (*RolesService)(nil)
@@ -0,0 +1,15 @@
+package services
+
+import "eventom-backend/models"
+
+type RolesServiceInterface interface {
+	GetRoles() ([]*models.Role, *models.ResponseError)
+
+	GetUserRoles(userId string) ([]string, *models.ResponseError)
+
+	AssignRole(userId string, roleName string) *models.ResponseError
+
+	RemoveRole(actingUserId string, userId string, roleName string) *models.ResponseError
+
+	BootstrapAdmin(email string) *models.ResponseError
+}
@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/testutils"
+	"log"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type RolesServiceTestSuite struct {
+	suite.Suite
+	ctx                context.Context
+	rolesService       RolesServiceInterface
+	transactionHandler *repositories.TransactionHandler
+}
+
+func TestRolesServiceSuite(t *testing.T) {
+	suite.Run(t, &RolesServiceTestSuite{})
+}
+
+func (suite *RolesServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	rolesRepository := repositories.NewRolesRepository(testutils.TestContainer.DB)
+	usersRepository := repositories.NewUsersRepository(testutils.TestContainer.DB)
+	suite.transactionHandler = repositories.NewTxHandler(testutils.TestContainer.DB)
+	suite.rolesService = NewRolesService(rolesRepository, usersRepository)
+}
+
+func (suite *RolesServiceTestSuite) BeforeTest(suiteName, testName string) {
+	// clear users table before every test, roles of users are deleted by the cascade
+	query := `
+		DELETE FROM
+			users`
+	_, err := testutils.TestContainer.DB.Exec(query)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func (suite *RolesServiceTestSuite) TestBootstrapAdmin() {
+	user, responseErr := suite.transactionHandler.ExecSignupUserTx("admin@test.com", "Test123", models.DefaultUserRoles)
+	require.Nil(suite.T(), responseErr)
+
+	// unverified addresses are not promoted
+	responseErr = suite.rolesService.BootstrapAdmin("admin@test.com")
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 409, responseErr.Status)
+
+	_, err := testutils.TestContainer.DB.Exec(`UPDATE users SET verified = true WHERE id = $1`, user.ID)
+	require.Nil(suite.T(), err)
+
+	responseErr = suite.rolesService.BootstrapAdmin("admin@test.com")
+	require.Nil(suite.T(), responseErr)
+
+	roles, responseErr := suite.rolesService.GetUserRoles(user.ID)
+	require.Nil(suite.T(), responseErr)
+	assert.Contains(suite.T(), roles, models.RoleAdmin)
+
+	// promoting again on the next start is a no-op
+	responseErr = suite.rolesService.BootstrapAdmin("admin@test.com")
+	assert.Nil(suite.T(), responseErr)
+}
+
+func (suite *RolesServiceTestSuite) TestBootstrapAdminFailUnknownUser() {
+	responseErr := suite.rolesService.BootstrapAdmin("unknown@test.com")
+	require.NotNil(suite.T(), responseErr)
+	assert.Equal(suite.T(), 404, responseErr.Status)
+}
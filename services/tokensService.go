@@ -0,0 +1,280 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"eventom-backend/utils"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokensService struct {
+	refreshTokensRepository repositories.RefreshTokensRepositoryInterface
+	revokedTokensRepository repositories.RevokedTokensRepositoryInterface
+	sessionsRepository      repositories.SessionsRepositoryInterface
+	rolesRepository         repositories.RolesRepositoryInterface
+	transactionHandler      repositories.TransactionHandler
+	revocationCache         *revocationCache
+}
+
+func NewTokensService(
+	refreshTokensRepository repositories.RefreshTokensRepositoryInterface,
+	revokedTokensRepository repositories.RevokedTokensRepositoryInterface,
+	sessionsRepository repositories.SessionsRepositoryInterface,
+	rolesRepository repositories.RolesRepositoryInterface,
+	transactionHandler repositories.TransactionHandler,
+) *TokensService {
+	return &TokensService{
+		refreshTokensRepository: refreshTokensRepository,
+		revokedTokensRepository: revokedTokensRepository,
+		sessionsRepository:      sessionsRepository,
+		rolesRepository:         rolesRepository,
+		transactionHandler:      transactionHandler,
+		revocationCache:         newRevocationCache(),
+	}
+}
+
+// IssueTokens starts a new session on the device of the client by creating a new refresh token family for the given user
+func (ts TokensService) IssueTokens(userId string, client dtos.ClientInfo) (*dtos.AuthTokens, *models.ResponseError) {
+	refreshToken, err := utils.GenerateOpaqueToken()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	expiresAt := time.Now().Add(utils.RefreshTokenTTL)
+	sessionId := uuid.NewString()
+
+	createdToken, responseErr := ts.transactionHandler.ExecCreateSessionTx(&models.Session{
+		ID:        sessionId,
+		UserId:    userId,
+		UserAgent: client.UserAgent,
+		IpAddress: client.IpAddress,
+		ExpiresAt: expiresAt,
+	}, &models.RefreshToken{
+		UserId:    userId,
+		FamilyId:  sessionId,
+		TokenHash: utils.HashToken(refreshToken),
+		ExpiresAt: expiresAt,
+	})
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return ts.generateAuthTokens(createdToken, refreshToken)
+}
+
+// RefreshTokens rotates the given refresh token and issues a new access token for the same session
+func (ts TokensService) RefreshTokens(refreshToken string, client dtos.ClientInfo) (*dtos.AuthTokens, *models.ResponseError) {
+	newRefreshToken, err := utils.GenerateOpaqueToken()
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	rotatedToken, responseErr := ts.transactionHandler.ExecRotateRefreshTokenTx(
+		utils.HashToken(refreshToken),
+		utils.HashToken(newRefreshToken),
+		time.Now().Add(utils.RefreshTokenTTL),
+		client,
+	)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return ts.generateAuthTokens(rotatedToken, newRefreshToken)
+}
+
+// RevokeRefreshToken ends the session the given refresh token belongs to
+func (ts TokensService) RevokeRefreshToken(refreshToken string) *models.ResponseError {
+	storedToken, responseErr := ts.refreshTokensRepository.QueryGetRefreshToken(utils.HashToken(refreshToken))
+
+	if responseErr != nil {
+		// nothing to revoke if the token is unknown
+		if responseErr.Status == http.StatusNotFound {
+			return nil
+		}
+		return responseErr
+	}
+
+	return ts.refreshTokensRepository.QueryRevokeRefreshTokenFamily(storedToken.FamilyId)
+}
+
+// RevokeAccessToken revokes the given access token until it expires
+func (ts TokensService) RevokeAccessToken(accessToken string) *models.ResponseError {
+	verifiedToken, err := utils.VerifyJwt(accessToken)
+
+	if err != nil {
+		// expired or invalid tokens are rejected by the auth middleware anyway
+		return nil
+	}
+
+	claims, err := utils.ParseAccessTokenClaims(verifiedToken)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	responseErr := ts.revokedTokensRepository.QueryRevokeToken(&models.RevokedToken{
+		TokenId:   claims.TokenId,
+		UserId:    claims.UserId,
+		ExpiresAt: claims.ExpiresAt,
+	})
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	ts.revocationCache.addToken(claims.TokenId, claims.ExpiresAt)
+
+	return nil
+}
+
+// RevokeAllUserTokens ends all sessions of the user, so their access tokens are rejected by the session id. Access tokens without
+// a session are revoked if they were issued before the given time
+func (ts TokensService) RevokeAllUserTokens(userId string, revokedBefore time.Time) *models.ResponseError {
+	revokedSessions, responseErr := ts.transactionHandler.ExecRevokeUserTokensTx(userId, revokedBefore)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	ts.revocationCache.addUser(userId, revokedBefore)
+
+	for _, revokedSession := range revokedSessions {
+		ts.revocationCache.addSession(revokedSession.ID, *revokedSession.RevokedAt)
+	}
+
+	return nil
+}
+
+// GetSessions returns the active sessions of the user
+func (ts TokensService) GetSessions(userId string) ([]*models.Session, *models.ResponseError) {
+	return ts.sessionsRepository.QueryGetUserSessions(userId)
+}
+
+// RevokeSession ends a single session of the user. Its refresh tokens are revoked and its access tokens are rejected from now on
+func (ts TokensService) RevokeSession(userId string, sessionId string) *models.ResponseError {
+	if uuid.Validate(sessionId) != nil {
+		return &models.ResponseError{
+			Message: "Session not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	revokedSession, responseErr := ts.transactionHandler.ExecRevokeSessionTx(userId, sessionId)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	ts.revocationCache.addSession(revokedSession.ID, *revokedSession.RevokedAt)
+
+	return nil
+}
+
+func (ts TokensService) IsAccessTokenRevoked(claims *dtos.AccessTokenClaims) bool {
+	return ts.revocationCache.isRevoked(claims.TokenId, claims.UserId, claims.SessionId, claims.IssuedAt)
+}
+
+// LoadRevocations fills the revocation cache with the revocations persisted in the database
+func (ts TokensService) LoadRevocations() *models.ResponseError {
+	revokedTokens, responseErr := ts.revokedTokensRepository.QueryGetRevokedTokens()
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	revocations, responseErr := ts.revokedTokensRepository.QueryGetUserTokenRevocations(time.Now().Add(-utils.AccessTokenTTL))
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	revokedSessions, responseErr := ts.sessionsRepository.QueryGetRevokedSessions(time.Now().Add(-utils.AccessTokenTTL))
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	ts.revocationCache.merge(revokedTokens, revocations, revokedSessions)
+
+	return nil
+}
+
+// StartRevocationSweeper periodically removes expired revocations and picks up revocations of other app instances
+func (ts TokensService) StartRevocationSweeper(interval time.Duration, logger *utils.Logger) {
+	go func() {
+		for {
+			time.Sleep(interval)
+
+			responseErr := ts.revokedTokensRepository.QueryDeleteExpiredRevokedTokens()
+
+			if responseErr != nil {
+				logger.Log(utils.LevelError, fmt.Sprintf("Failed to delete expired revoked tokens: %s", responseErr.Message), nil)
+			}
+
+			responseErr = ts.LoadRevocations()
+
+			if responseErr != nil {
+				logger.Log(utils.LevelError, fmt.Sprintf("Failed to load revoked tokens: %s", responseErr.Message), nil)
+			}
+
+			ts.revocationCache.sweep(utils.AccessTokenTTL)
+		}
+	}()
+}
+
+func (ts TokensService) generateAuthTokens(storedToken *models.RefreshToken, refreshToken string) (*dtos.AuthTokens, *models.ResponseError) {
+	// roles are read on every refresh, so role changes take effect with the next access token
+	roles, responseErr := ts.rolesRepository.QueryGetUserRoles(storedToken.UserId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	permissions, responseErr := ts.rolesRepository.QueryGetUserPermissions(storedToken.UserId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	accessToken, err := utils.GenerateJwt(storedToken.UserId, storedToken.FamilyId, roles, permissions)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return &dtos.AuthTokens{
+		UserId:                storedToken.UserId,
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  time.Now().Add(utils.AccessTokenTTL),
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: storedToken.ExpiresAt,
+	}, nil
+}
+
+// GetJwks returns the public keys of the key ring, including keys that are not used for signing anymore but still verify tokens
+func (ts TokensService) GetJwks() *dtos.JwkSet {
+	return utils.GetJwks()
+}
+
+var _ TokensServiceInterface = (*TokensService)(nil)
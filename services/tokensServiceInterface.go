@@ -0,0 +1,27 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"time"
+)
+
+type TokensServiceInterface interface {
+	IssueTokens(userId string, client dtos.ClientInfo) (*dtos.AuthTokens, *models.ResponseError)
+
+	RefreshTokens(refreshToken string, client dtos.ClientInfo) (*dtos.AuthTokens, *models.ResponseError)
+
+	RevokeRefreshToken(refreshToken string) *models.ResponseError
+
+	RevokeAccessToken(accessToken string) *models.ResponseError
+
+	RevokeAllUserTokens(userId string, revokedBefore time.Time) *models.ResponseError
+
+	GetSessions(userId string) ([]*models.Session, *models.ResponseError)
+
+	RevokeSession(userId string, sessionId string) *models.ResponseError
+
+	IsAccessTokenRevoked(claims *dtos.AccessTokenClaims) bool
+
+	GetJwks() *dtos.JwkSet
+}
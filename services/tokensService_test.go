@@ -0,0 +1,286 @@
+package services
+
+import (
+	"context"
+	"eventom-backend/dtos"
+	"eventom-backend/repositories"
+	"eventom-backend/testutils"
+	"eventom-backend/utils"
+	"log"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+
+	_ "github.com/lib/pq"
+)
+
+type TokensServiceTestSuite struct {
+	suite.Suite
+	ctx             context.Context
+	tokensService   TokensServiceInterface
+	usersRepository repositories.UsersRepositoryInterface
+	userId          string
+}
+
+func TestTokensServiceSuite(t *testing.T) {
+	suite.Run(t, &TokensServiceTestSuite{})
+}
+
+func (suite *TokensServiceTestSuite) SetupSuite() {
+	suite.ctx = context.Background()
+
+	if testutils.TestContainer == nil {
+		pgContainer, err := testutils.CreatePostgresContainer(suite.ctx)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		testutils.TestContainer = pgContainer
+	}
+
+	// generate a throwaway private key so access tokens can be signed
+	keyFile, err := testutils.CreateSigningKeyFile(suite.T().TempDir())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	err = utils.ReadPrivateKeyFromFile(keyFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	suite.usersRepository = repositories.NewUsersRepository(testutils.TestContainer.DB)
+	refreshTokensRepository := repositories.NewRefreshTokensRepository(testutils.TestContainer.DB)
+	revokedTokensRepository := repositories.NewRevokedTokensRepository(testutils.TestContainer.DB)
+	sessionsRepository := repositories.NewSessionsRepository(testutils.TestContainer.DB)
+	rolesRepository := repositories.NewRolesRepository(testutils.TestContainer.DB)
+	transactionHandler := repositories.NewTxHandler(testutils.TestContainer.DB)
+	suite.tokensService = NewTokensService(refreshTokensRepository, revokedTokensRepository, sessionsRepository, rolesRepository, *transactionHandler)
+}
+
+func (suite *TokensServiceTestSuite) BeforeTest(suiteName, testName string) {
+	// clear users table before every test, refresh tokens are deleted by the cascade
+	query := `
+		DELETE FROM
+			users`
+	_, err := testutils.TestContainer.DB.Exec(query)
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	responseErr := suite.usersRepository.QuerySignupUser("test@test.com", "Test123")
+	require.Nil(suite.T(), responseErr)
+
+	user, responseErr := suite.usersRepository.QueryGetUser("test@test.com")
+	require.Nil(suite.T(), responseErr)
+
+	suite.userId = user.ID
+}
+
+func (suite *TokensServiceTestSuite) TestRefreshTokensRotatesToken() {
+	authTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	refreshedTokens, err := suite.tokensService.RefreshTokens(authTokens.RefreshToken, dtos.ClientInfo{})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), suite.userId, refreshedTokens.UserId)
+	assert.NotEqual(suite.T(), authTokens.RefreshToken, refreshedTokens.RefreshToken)
+	assert.NotEmpty(suite.T(), refreshedTokens.AccessToken)
+}
+
+func (suite *TokensServiceTestSuite) TestRefreshTokensReuseRevokesFamily() {
+	authTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	refreshedTokens, err := suite.tokensService.RefreshTokens(authTokens.RefreshToken, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	// move the rotation out of the grace period for parallel refreshes
+	_, dbErr := testutils.TestContainer.DB.Exec(`UPDATE refresh_tokens SET revoked_at = revoked_at - interval '1 minute' WHERE revoked_at IS NOT NULL`)
+	require.Nil(suite.T(), dbErr)
+
+	// presenting the rotated token again must fail and end the session
+	_, err = suite.tokensService.RefreshTokens(authTokens.RefreshToken, dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+
+	_, err = suite.tokensService.RefreshTokens(refreshedTokens.RefreshToken, dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+}
+
+func (suite *TokensServiceTestSuite) TestRefreshTokensConcurrently() {
+	authTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	// parallel requests of the same client refresh with the same token, only one of them rotates it
+	const parallelRefreshes = 5
+	results := make(chan *dtos.AuthTokens, parallelRefreshes)
+	statuses := make(chan int, parallelRefreshes)
+	var waitGroup sync.WaitGroup
+
+	for i := 0; i < parallelRefreshes; i++ {
+		waitGroup.Add(1)
+		go func() {
+			defer waitGroup.Done()
+			refreshedTokens, responseErr := suite.tokensService.RefreshTokens(authTokens.RefreshToken, dtos.ClientInfo{})
+			if responseErr != nil {
+				statuses <- responseErr.Status
+				return
+			}
+			results <- refreshedTokens
+		}()
+	}
+
+	waitGroup.Wait()
+	close(results)
+	close(statuses)
+
+	require.Len(suite.T(), results, 1)
+	for status := range statuses {
+		assert.Equal(suite.T(), http.StatusConflict, status)
+	}
+
+	// the session survives, the successor can still be rotated
+	refreshedTokens := <-results
+	_, err = suite.tokensService.RefreshTokens(refreshedTokens.RefreshToken, dtos.ClientInfo{})
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *TokensServiceTestSuite) TestRefreshTokensFailUnknownToken() {
+	authTokens, err := suite.tokensService.RefreshTokens("unknown", dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+	assert.Nil(suite.T(), authTokens)
+}
+
+func (suite *TokensServiceTestSuite) TestRevokeRefreshToken() {
+	authTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	err = suite.tokensService.RevokeRefreshToken(authTokens.RefreshToken)
+	assert.Nil(suite.T(), err)
+
+	_, err = suite.tokensService.RefreshTokens(authTokens.RefreshToken, dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+}
+
+func (suite *TokensServiceTestSuite) TestRevokeAccessToken() {
+	authTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	claims := suite.parseClaims(authTokens.AccessToken)
+	assert.False(suite.T(), suite.tokensService.IsAccessTokenRevoked(claims))
+
+	err = suite.tokensService.RevokeAccessToken(authTokens.AccessToken)
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), suite.tokensService.IsAccessTokenRevoked(claims))
+}
+
+func (suite *TokensServiceTestSuite) TestRevokeAllUserTokens() {
+	authTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	claims := suite.parseClaims(authTokens.AccessToken)
+
+	// the token was issued in the same second, it is revoked by its session
+	err = suite.tokensService.RevokeAllUserTokens(suite.userId, time.Now())
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), suite.tokensService.IsAccessTokenRevoked(claims))
+
+	sessions, err := suite.tokensService.GetSessions(suite.userId)
+	require.Nil(suite.T(), err)
+	assert.Empty(suite.T(), sessions)
+
+	// tokens issued after the revocation stay valid even within the same second and keep their real issue time
+	newTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+	newClaims := suite.parseClaims(newTokens.AccessToken)
+	assert.False(suite.T(), suite.tokensService.IsAccessTokenRevoked(newClaims))
+	assert.False(suite.T(), newClaims.IssuedAt.After(time.Now()))
+
+	// the session cannot be renewed either
+	_, err = suite.tokensService.RefreshTokens(authTokens.RefreshToken, dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+}
+
+func (suite *TokensServiceTestSuite) TestRevokeSession() {
+	laptopTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{IpAddress: "127.0.0.1", UserAgent: "laptop"})
+	require.Nil(suite.T(), err)
+	phoneTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{IpAddress: "127.0.0.2", UserAgent: "phone"})
+	require.Nil(suite.T(), err)
+
+	sessions, err := suite.tokensService.GetSessions(suite.userId)
+	require.Nil(suite.T(), err)
+	assert.Len(suite.T(), sessions, 2)
+
+	phoneClaims := suite.parseClaims(phoneTokens.AccessToken)
+	laptopClaims := suite.parseClaims(laptopTokens.AccessToken)
+
+	err = suite.tokensService.RevokeSession(suite.userId, phoneClaims.SessionId)
+	assert.Nil(suite.T(), err)
+
+	// access and refresh tokens of the revoked session are rejected, the other session is not affected
+	assert.True(suite.T(), suite.tokensService.IsAccessTokenRevoked(phoneClaims))
+	assert.False(suite.T(), suite.tokensService.IsAccessTokenRevoked(laptopClaims))
+
+	_, err = suite.tokensService.RefreshTokens(phoneTokens.RefreshToken, dtos.ClientInfo{})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+
+	sessions, err = suite.tokensService.GetSessions(suite.userId)
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), sessions, 1)
+	assert.Equal(suite.T(), laptopClaims.SessionId, sessions[0].ID)
+	assert.Equal(suite.T(), "laptop", sessions[0].UserAgent)
+}
+
+func (suite *TokensServiceTestSuite) TestRevokeSessionFailForeignSession() {
+	responseErr := suite.usersRepository.QuerySignupUser("other@test.com", "Test123")
+	require.Nil(suite.T(), responseErr)
+
+	otherUser, responseErr := suite.usersRepository.QueryGetUser("other@test.com")
+	require.Nil(suite.T(), responseErr)
+
+	authTokens, err := suite.tokensService.IssueTokens(otherUser.ID, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	claims := suite.parseClaims(authTokens.AccessToken)
+
+	err = suite.tokensService.RevokeSession(suite.userId, claims.SessionId)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 404, err.Status)
+	assert.False(suite.T(), suite.tokensService.IsAccessTokenRevoked(claims))
+}
+
+func (suite *TokensServiceTestSuite) TestAccessTokenSignedWithPublishedKey() {
+	authTokens, err := suite.tokensService.IssueTokens(suite.userId, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+
+	verifiedToken, verifyErr := utils.VerifyJwt(authTokens.AccessToken)
+	require.Nil(suite.T(), verifyErr)
+
+	jwks := suite.tokensService.GetJwks()
+	require.Len(suite.T(), jwks.Keys, 1)
+	assert.Equal(suite.T(), jwks.Keys[0].Kid, verifiedToken.Header["kid"])
+	assert.Equal(suite.T(), "RS256", jwks.Keys[0].Alg)
+}
+
+func (suite *TokensServiceTestSuite) parseClaims(accessToken string) *dtos.AccessTokenClaims {
+	verifiedToken, err := utils.VerifyJwt(accessToken)
+	require.Nil(suite.T(), err)
+
+	claims, err := utils.ParseAccessTokenClaims(verifiedToken)
+	require.Nil(suite.T(), err)
+
+	return claims
+}
@@ -1,21 +1,62 @@
 package services
 
 import (
+	"eventom-backend/dtos"
+	"eventom-backend/mailers"
 	"eventom-backend/models"
 	"eventom-backend/repositories"
 	"eventom-backend/utils"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
+	"time"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
+const emailVerificationTokenTTL = 24 * time.Hour
+const passwordResetTokenTTL = time.Hour
+
+// dummyPasswordHash is a valid bcrypt hash that no user password is compared against successfully
+const dummyPasswordHash = "$2a$10$Ei1BKfvo7cva9l9acTNRCu3msxdH4ZGVgc/L5n/90aaPmeJF7lfEe"
+
+// deletedOrganizerReason is the deletion reason of the events of users that delete their account
+const deletedOrganizerReason = "The organizer has deleted their account."
+
 type UsersService struct {
-	usersRepository repositories.UsersRepositoryInterface
+	usersRepository              repositories.UsersRepositoryInterface
+	emailVerificationsRepository repositories.EmailVerificationsRepositoryInterface
+	passwordResetsRepository     repositories.PasswordResetsRepositoryInterface
+	tokensService                TokensServiceInterface
+	loginAttemptsService         LoginAttemptsServiceInterface
+	mfaService                   MfaServiceInterface
+	transactionHandler           repositories.TransactionHandler
+	mailer                       mailers.Mailer
+	registrationNotifier         RegistrationNotifier
 }
 
-func NewUsersService(usersRepository repositories.UsersRepositoryInterface) *UsersService {
+func NewUsersService(
+	usersRepository repositories.UsersRepositoryInterface,
+	emailVerificationsRepository repositories.EmailVerificationsRepositoryInterface,
+	passwordResetsRepository repositories.PasswordResetsRepositoryInterface,
+	tokensService TokensServiceInterface,
+	loginAttemptsService LoginAttemptsServiceInterface,
+	mfaService MfaServiceInterface,
+	transactionHandler repositories.TransactionHandler,
+	mailer mailers.Mailer,
+	registrationNotifier RegistrationNotifier,
+) *UsersService {
 	return &UsersService{
-		usersRepository: usersRepository,
+		usersRepository:              usersRepository,
+		emailVerificationsRepository: emailVerificationsRepository,
+		passwordResetsRepository:     passwordResetsRepository,
+		tokensService:                tokensService,
+		loginAttemptsService:         loginAttemptsService,
+		mfaService:                   mfaService,
+		transactionHandler:           transactionHandler,
+		mailer:                       mailer,
+		registrationNotifier:         registrationNotifier,
 	}
 }
 
@@ -30,41 +71,519 @@ func (us UsersService) SignupUser(user *models.User) *models.ResponseError {
 		}
 	}
 
-	return us.usersRepository.QuerySignupUser(user.Email, hashedPassword)
+	createdUser, responseErr := us.transactionHandler.ExecSignupUserTx(user.Email, hashedPassword, models.DefaultUserRoles)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	user.ID = createdUser.ID
+
+	return us.sendVerificationEmail(createdUser.ID, createdUser.Email)
+}
+
+func (us UsersService) VerifyEmail(verificationToken string) *models.ResponseError {
+	_, responseErr := us.transactionHandler.ExecVerifyEmailTx(utils.HashToken(verificationToken))
+	return responseErr
+}
+
+// ResendVerificationEmail sends a new verification mail. To not reveal which addresses are registered, unknown or already verified addresses are ignored silently
+func (us UsersService) ResendVerificationEmail(email string) *models.ResponseError {
+	user, responseErr := us.usersRepository.QueryGetUser(email)
+
+	if responseErr != nil {
+		if responseErr.Status == http.StatusNotFound {
+			return nil
+		}
+		return responseErr
+	}
+
+	if user.Verified {
+		return nil
+	}
+
+	return us.sendVerificationEmail(user.ID, user.Email)
 }
 
 func (us UsersService) GetUser(email string) (*models.User, *models.ResponseError) {
 	return us.usersRepository.QueryGetUser(email)
 }
 
-func (us UsersService) LoginUser(user *models.User) (string, *models.ResponseError) {
+// ForgotPassword mails a password reset link to the given address. Unknown addresses are ignored silently to not reveal which addresses are registered
+func (us UsersService) ForgotPassword(email string) *models.ResponseError {
+	user, responseErr := us.usersRepository.QueryGetUser(email)
+
+	if responseErr != nil {
+		if responseErr.Status == http.StatusNotFound {
+			return nil
+		}
+		return responseErr
+	}
+
+	resetToken, err := utils.GenerateOpaqueToken()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	responseErr = us.passwordResetsRepository.QueryCreatePasswordResetToken(&models.PasswordResetToken{
+		UserId:    user.ID,
+		TokenHash: utils.HashToken(resetToken),
+		ExpiresAt: time.Now().Add(passwordResetTokenTTL),
+	})
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	resetLink := utils.BuildAppUrl("/password/reset", url.Values{"token": {resetToken}})
+
+	err = us.mailer.Send(&mailers.Message{
+		To:      user.Email,
+		Subject: "Reset your eventom password",
+		Body:    fmt.Sprintf("A password reset has been requested for your account. Use the following link within the next hour to choose a new password:\n\n%s\n\nIf you did not request a password reset, you can ignore this mail.\n", resetLink),
+	})
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: fmt.Sprintf("Could not send password reset email: %s", err.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// ResetPassword sets a new password with a reset token and ends all sessions of the user
+func (us UsersService) ResetPassword(resetToken string, password string) *models.ResponseError {
+	hashedPassword, err := utils.HashPassword(password)
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	userId, responseErr := us.transactionHandler.ExecResetPasswordTx(utils.HashToken(resetToken), hashedPassword)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	return us.tokensService.RevokeAllUserTokens(userId, time.Now())
+}
+
+// LoginUser checks the credentials of the user. Users with two-factor authentication enabled get a mfa challenge instead of tokens
+func (us UsersService) LoginUser(user *models.User, client dtos.ClientInfo) (*dtos.AuthTokens, *dtos.MfaChallenge, *models.ResponseError) {
+	responseErr := us.loginAttemptsService.ReserveLoginAttempt(user.Email, client.IpAddress)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
 	userInDb, responseErr := us.usersRepository.QueryGetUser(user.Email)
 
 	if responseErr != nil {
-		return "", responseErr
+		if responseErr.Status != http.StatusNotFound {
+			return nil, nil, responseErr
+		}
+		// compare against a dummy hash, so unknown addresses take as long as wrong passwords
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(user.Password))
+		return nil, nil, us.failLogin(user.Email, client.IpAddress)
+	}
+
+	// accounts without a password are rejected before bcrypt is asked, they can only log in with openid connect
+	if !userInDb.HasPassword() {
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(user.Password))
+		return nil, nil, us.failLogin(user.Email, client.IpAddress)
 	}
 
 	err := bcrypt.CompareHashAndPassword([]byte(userInDb.Password), []byte(user.Password))
 
 	if err != nil {
-		return "", &models.ResponseError{
-			Message: err.Error(),
-			Status:  http.StatusUnauthorized,
+		return nil, nil, us.failLogin(user.Email, client.IpAddress)
+	}
+
+	responseErr = us.loginAttemptsService.RecordSuccessfulLogin(user.Email, client.IpAddress)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	if !userInDb.Verified {
+		return nil, nil, &models.ResponseError{
+			Message: "Email address has not been verified yet",
+			Status:  http.StatusForbidden,
 		}
 	}
 
 	user.ID = userInDb.ID
 
-	token, err := utils.GenerateJwt(user.ID)
+	mfaEnabled, responseErr := us.mfaService.IsTotpEnabled(user.ID)
+
+	if responseErr != nil {
+		return nil, nil, responseErr
+	}
+
+	if mfaEnabled {
+		mfaChallenge, responseErr := createMfaChallenge(user.ID)
+		return nil, mfaChallenge, responseErr
+	}
+
+	authTokens, responseErr := us.tokensService.IssueTokens(user.ID, client)
+
+	return authTokens, nil, responseErr
+}
+
+// LoginUserWithMfa completes the login of a mfa challenge with a totp or recovery code. Wrong codes count as failed logins
+func (us UsersService) LoginUserWithMfa(challengeToken string, code string, client dtos.ClientInfo) (*dtos.AuthTokens, *models.ResponseError) {
+	userId, err := utils.VerifyMfaChallengeJwt(challengeToken)
+
+	if err != nil {
+		return nil, &models.ResponseError{
+			Message: "Invalid or expired mfa token",
+			Status:  http.StatusUnauthorized,
+		}
+	}
+
+	user, responseErr := us.usersRepository.QueryGetUserById(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	responseErr = us.loginAttemptsService.ReserveLoginAttempt(user.Email, client.IpAddress)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	responseErr = us.mfaService.VerifyCode(user.ID, code)
+
+	if responseErr != nil {
+		if responseErr.Status != http.StatusUnauthorized {
+			return nil, responseErr
+		}
+		return nil, us.failLogin(user.Email, client.IpAddress)
+	}
+
+	responseErr = us.loginAttemptsService.RecordSuccessfulLogin(user.Email, client.IpAddress)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return us.tokensService.IssueTokens(user.ID, client)
+}
+
+func (us UsersService) UnlockAccount(unlockToken string) *models.ResponseError {
+	return us.loginAttemptsService.UnlockAccount(unlockToken)
+}
+
+func (us UsersService) UnlockUser(userId string) *models.ResponseError {
+	return us.loginAttemptsService.UnlockUser(userId)
+}
+
+func (us UsersService) LogoutUser(refreshToken string, accessToken string) *models.ResponseError {
+	if refreshToken != "" {
+		responseErr := us.tokensService.RevokeRefreshToken(refreshToken)
+
+		if responseErr != nil {
+			return responseErr
+		}
+	}
+
+	if accessToken != "" {
+		return us.tokensService.RevokeAccessToken(accessToken)
+	}
+
+	return nil
+}
+
+func (us UsersService) LogoutUserEverywhere(userId string) *models.ResponseError {
+	return us.tokensService.RevokeAllUserTokens(userId, time.Now())
+}
+
+func (us UsersService) GetProfile(userId string) (*dtos.UserProfile, *models.ResponseError) {
+	user, responseErr := us.usersRepository.QueryGetUserById(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return us.buildProfile(user)
+}
+
+func (us UsersService) UpdateProfile(userId string, profileUpdate *dtos.UserProfileUpdateDto) (*dtos.UserProfile, *models.ResponseError) {
+	user, responseErr := us.usersRepository.QueryGetUserById(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	if profileUpdate.DisplayName != nil {
+		user.DisplayName = *profileUpdate.DisplayName
+	}
+
+	if profileUpdate.Timezone != nil {
+		user.Timezone = *profileUpdate.Timezone
+	}
+
+	if profileUpdate.Locale != nil {
+		user.Locale = *profileUpdate.Locale
+	}
+
+	updatedUser, responseErr := us.usersRepository.QueryUpdateProfile(user)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return us.buildProfile(updatedUser)
+}
+
+// ChangePassword sets a new password and ends all other sessions of the user. The returned tokens keep the current session alive.
+// Users without a password set their first one this way, see confirmIdentity
+func (us UsersService) ChangePassword(userId string, sessionId string, currentPassword string, newPassword string, client dtos.ClientInfo) (*dtos.AuthTokens, *models.ResponseError) {
+	_, responseErr := us.checkIdentity(userId, sessionId, currentPassword)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
 
 	if err != nil {
-		return "", &models.ResponseError{
+		return nil, &models.ResponseError{
 			Message: err.Error(),
 			Status:  http.StatusInternalServerError,
 		}
 	}
 
-	return token, nil
+	responseErr = us.usersRepository.QueryUpdatePassword(userId, hashedPassword)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	responseErr = us.tokensService.RevokeAllUserTokens(userId, time.Now())
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return us.tokensService.IssueTokens(userId, client)
+}
+
+// ChangeEmail sends a verification mail to the new address. The email of the user is only changed once the new address has been verified
+func (us UsersService) ChangeEmail(userId string, sessionId string, email string, password string) *models.ResponseError {
+	user, responseErr := us.checkIdentity(userId, sessionId, password)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	if strings.EqualFold(user.Email, email) {
+		return &models.ResponseError{
+			Message: "New email address is the same as the current one",
+			Status:  http.StatusBadRequest,
+		}
+	}
+
+	_, responseErr = us.usersRepository.QueryGetUser(email)
+
+	if responseErr == nil {
+		return &models.ResponseError{
+			Message: "Email already registered",
+			Status:  http.StatusConflict,
+		}
+	}
+
+	if responseErr.Status != http.StatusNotFound {
+		return responseErr
+	}
+
+	responseErr = us.sendVerificationEmail(user.ID, email)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	// let the owner of the current address know, in case the session has been taken over
+	err := us.mailer.Send(&mailers.Message{
+		To:      user.Email,
+		Subject: "Your eventom email address is about to change",
+		Body:    fmt.Sprintf("A change of the email address of your account to %s has been requested. The change takes effect once the new address has been verified.\n\nIf you did not request this change, reset your password right away.\n", email),
+	})
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: fmt.Sprintf("Could not send email change notification: %s", err.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
+}
+
+// DeleteUser deletes the account of the user, see ExecDeleteUserTx for what happens to events and registrations
+func (us UsersService) DeleteUser(userId string, sessionId string, password string) *models.ResponseError {
+	_, responseErr := us.checkIdentity(userId, sessionId, password)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	// revoke before deleting, access tokens of the deleted user must not be accepted until they expire
+	responseErr = us.tokensService.RevokeAllUserTokens(userId, time.Now())
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	cancelledRegistrations, promotedRegistrations, responseErr := us.transactionHandler.ExecDeleteUserTx(userId, deletedOrganizerReason)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	// attendees are told the same way as if the organizer had deleted the events one by one. The mails are sent in the background,
+	// the deletion must not wait for a mail per attendee
+	go func() {
+		for _, registration := range cancelledRegistrations {
+			us.registrationNotifier.NotifyCancellation(registration, registration.Event)
+		}
+
+		notifyPromotions(us.registrationNotifier, promotedRegistrations)
+	}()
+
+	return nil
+}
+
+// failLogin records the failed attempt and returns the same error for unknown addresses and wrong passwords
+func (us UsersService) failLogin(email string, ipAddress string) *models.ResponseError {
+	responseErr := us.loginAttemptsService.RecordFailedLogin(email, ipAddress)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	return &models.ResponseError{
+		Message: "Invalid credentials",
+		Status:  http.StatusUnauthorized,
+	}
+}
+
+// checkIdentity loads the user and confirms that the request comes from the user, see confirmIdentity
+func (us UsersService) checkIdentity(userId string, sessionId string, password string) (*models.User, *models.ResponseError) {
+	user, responseErr := us.usersRepository.QueryGetUserById(userId)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	responseErr = confirmIdentity(user, sessionId, password, us.tokensService)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return user, nil
+}
+
+// confirmIdentity guards sensitive changes of an account. Users with a password have to enter it. Users without a password
+// have signed up with openid connect, they confirm with a session that has been logged in less than utils.ReauthenticationWindow ago
+func confirmIdentity(user *models.User, sessionId string, password string, tokensService TokensServiceInterface) *models.ResponseError {
+	if user.HasPassword() {
+		err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password))
+
+		if err != nil {
+			return &models.ResponseError{
+				Message: "Invalid password",
+				Status:  http.StatusUnauthorized,
+			}
+		}
+
+		return nil
+	}
+
+	sessions, responseErr := tokensService.GetSessions(user.ID)
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	for _, session := range sessions {
+		if session.ID == sessionId && time.Since(session.CreatedAt) < utils.ReauthenticationWindow {
+			return nil
+		}
+	}
+
+	return &models.ResponseError{
+		Message: "Login is too old, log in at the identity provider again to confirm",
+		Status:  http.StatusForbidden,
+	}
+}
+
+func (us UsersService) buildProfile(user *models.User) (*dtos.UserProfile, *models.ResponseError) {
+	mfaEnabled, responseErr := us.mfaService.IsTotpEnabled(user.ID)
+
+	if responseErr != nil {
+		return nil, responseErr
+	}
+
+	return &dtos.UserProfile{
+		ID:          user.ID,
+		Email:       user.Email,
+		DisplayName: user.DisplayName,
+		Timezone:    user.Timezone,
+		Locale:      user.Locale,
+		Verified:    user.Verified,
+		MfaEnabled:  mfaEnabled,
+	}, nil
+}
+
+func (us UsersService) sendVerificationEmail(userId string, email string) *models.ResponseError {
+	verificationToken, err := utils.GenerateOpaqueToken()
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: err.Error(),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	responseErr := us.emailVerificationsRepository.QueryCreateEmailVerificationToken(&models.EmailVerificationToken{
+		UserId:    userId,
+		Email:     email,
+		TokenHash: utils.HashToken(verificationToken),
+		ExpiresAt: time.Now().Add(emailVerificationTokenTTL),
+	})
+
+	if responseErr != nil {
+		return responseErr
+	}
+
+	verificationLink := utils.BuildAppUrl("/verify-email", url.Values{"token": {verificationToken}})
+
+	err = us.mailer.Send(&mailers.Message{
+		To:      email,
+		Subject: "Verify your eventom email address",
+		Body:    fmt.Sprintf("Please verify your email address by opening the following link within the next 24 hours:\n\n%s\n", verificationLink),
+	})
+
+	if err != nil {
+		return &models.ResponseError{
+			Message: fmt.Sprintf("Could not send verification email: %s", err.Error()),
+			Status:  http.StatusInternalServerError,
+		}
+	}
+
+	return nil
 }
 
 var _ UsersServiceInterface = (*UsersService)(nil)
@@ -1,11 +1,42 @@
 package services
 
-import "eventom-backend/models"
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
 
 type UsersServiceInterface interface {
 	SignupUser(user *models.User) *models.ResponseError
 
 	GetUser(email string) (*models.User, *models.ResponseError)
 
-	LoginUser(user *models.User) (string, *models.ResponseError)
+	VerifyEmail(verificationToken string) *models.ResponseError
+
+	ResendVerificationEmail(email string) *models.ResponseError
+
+	ForgotPassword(email string) *models.ResponseError
+
+	ResetPassword(resetToken string, password string) *models.ResponseError
+
+	LoginUser(user *models.User, client dtos.ClientInfo) (*dtos.AuthTokens, *dtos.MfaChallenge, *models.ResponseError)
+
+	LoginUserWithMfa(challengeToken string, code string, client dtos.ClientInfo) (*dtos.AuthTokens, *models.ResponseError)
+
+	UnlockAccount(unlockToken string) *models.ResponseError
+
+	UnlockUser(userId string) *models.ResponseError
+
+	LogoutUser(refreshToken string, accessToken string) *models.ResponseError
+
+	LogoutUserEverywhere(userId string) *models.ResponseError
+
+	GetProfile(userId string) (*dtos.UserProfile, *models.ResponseError)
+
+	UpdateProfile(userId string, profileUpdate *dtos.UserProfileUpdateDto) (*dtos.UserProfile, *models.ResponseError)
+
+	ChangePassword(userId string, sessionId string, currentPassword string, newPassword string, client dtos.ClientInfo) (*dtos.AuthTokens, *models.ResponseError)
+
+	ChangeEmail(userId string, sessionId string, email string, password string) *models.ResponseError
+
+	DeleteUser(userId string, sessionId string, password string) *models.ResponseError
 }
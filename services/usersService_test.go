@@ -2,22 +2,34 @@ package services
 
 import (
 	"context"
+	"eventom-backend/dtos"
+	"eventom-backend/mailers"
 	"eventom-backend/models"
 	"eventom-backend/repositories"
 	"eventom-backend/testutils"
+	"eventom-backend/utils"
 	"log"
+	"os"
+	"slices"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"golang.org/x/crypto/bcrypt"
 
 	_ "github.com/lib/pq"
 )
 
 type UsersServiceTestSuite struct {
 	suite.Suite
-	ctx          context.Context
-	usersService UsersServiceInterface
+	ctx           context.Context
+	usersService  UsersServiceInterface
+	tokensService TokensServiceInterface
+	mailer        *mailers.InMemoryMailer
 }
 
 func TestUsersServiceSuite(t *testing.T) {
@@ -38,7 +50,23 @@ func (suite *UsersServiceTestSuite) SetupSuite() {
 	}
 
 	usersRepository := repositories.NewUsersRepository(testutils.TestContainer.DB)
-	suite.usersService = NewUsersService(usersRepository)
+	eventsRepository := repositories.NewEventsRepository(testutils.TestContainer.DB)
+	refreshTokensRepository := repositories.NewRefreshTokensRepository(testutils.TestContainer.DB)
+	revokedTokensRepository := repositories.NewRevokedTokensRepository(testutils.TestContainer.DB)
+	sessionsRepository := repositories.NewSessionsRepository(testutils.TestContainer.DB)
+	rolesRepository := repositories.NewRolesRepository(testutils.TestContainer.DB)
+	emailVerificationsRepository := repositories.NewEmailVerificationsRepository(testutils.TestContainer.DB)
+	passwordResetsRepository := repositories.NewPasswordResetsRepository(testutils.TestContainer.DB)
+	loginAttemptsRepository := repositories.NewLoginAttemptsRepository(testutils.TestContainer.DB)
+	mfaRepository := repositories.NewMfaRepository(testutils.TestContainer.DB)
+	transactionHandler := repositories.NewTxHandler(testutils.TestContainer.DB)
+	tokensService := NewTokensService(refreshTokensRepository, revokedTokensRepository, sessionsRepository, rolesRepository, *transactionHandler)
+	suite.tokensService = tokensService
+	suite.mailer = mailers.NewInMemoryMailer()
+	mfaService := NewMfaService(mfaRepository, usersRepository, *transactionHandler)
+	loginAttemptsService := NewLoginAttemptsService(loginAttemptsRepository, usersRepository, *transactionHandler, suite.mailer)
+	registrationNotifier := NewMailRegistrationNotifier(usersRepository, eventsRepository, suite.mailer, utils.NewLogger(os.Stdout))
+	suite.usersService = NewUsersService(usersRepository, emailVerificationsRepository, passwordResetsRepository, tokensService, loginAttemptsService, mfaService, *transactionHandler, suite.mailer, registrationNotifier)
 }
 
 func (suite *UsersServiceTestSuite) BeforeTest(suiteName, testName string) {
@@ -51,6 +79,16 @@ func (suite *UsersServiceTestSuite) BeforeTest(suiteName, testName string) {
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	// failed logins of previous tests would delay or lock the next login attempts
+	query = `
+		DELETE FROM
+			login_attempts`
+	_, err = testutils.TestContainer.DB.Exec(query)
+
+	if err != nil {
+		log.Fatal(err)
+	}
 }
 
 func (suite *UsersServiceTestSuite) TestSignupFailUniqueEmail() {
@@ -102,7 +140,7 @@ func (suite *UsersServiceTestSuite) TestLoginUserNotFound() {
 	err := suite.usersService.SignupUser(user)
 	assert.Nil(suite.T(), err)
 
-	token, err := suite.usersService.LoginUser(wrongEmail)
+	token, _, err := suite.usersService.LoginUser(wrongEmail, dtos.ClientInfo{IpAddress: "127.0.0.1"})
 	assert.NotNil(suite.T(), err)
 	assert.Empty(suite.T(), token)
 }
@@ -121,8 +159,361 @@ func (suite *UsersServiceTestSuite) TestLogindWrongPassword() {
 	err := suite.usersService.SignupUser(user)
 	assert.Nil(suite.T(), err)
 
-	token, err := suite.usersService.LoginUser(wrongPw)
+	token, _, err := suite.usersService.LoginUser(wrongPw, dtos.ClientInfo{IpAddress: "127.0.0.1"})
 	assert.NotNil(suite.T(), err)
 	assert.Equal(suite.T(), 401, err.Status)
 	assert.Empty(suite.T(), token)
 }
+
+func (suite *UsersServiceTestSuite) TestLoginUserNotVerified() {
+	user := &models.User{
+		Email:    "test@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(user)
+	assert.Nil(suite.T(), err)
+
+	token, _, err := suite.usersService.LoginUser(&models.User{
+		Email:    "test@test.com",
+		Password: "Test123",
+	}, dtos.ClientInfo{IpAddress: "127.0.0.1"})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 403, err.Status)
+	assert.Empty(suite.T(), token)
+}
+
+func (suite *UsersServiceTestSuite) TestVerifyEmailSuccess() {
+	user := &models.User{
+		Email:    "verify@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(user)
+	assert.Nil(suite.T(), err)
+
+	verificationToken := suite.lastMailToken("verify@test.com")
+	require.NotEmpty(suite.T(), verificationToken)
+
+	err = suite.usersService.VerifyEmail(verificationToken)
+	assert.Nil(suite.T(), err)
+
+	verifiedUser, err := suite.usersService.GetUser("verify@test.com")
+	assert.Nil(suite.T(), err)
+	assert.True(suite.T(), verifiedUser.Verified)
+
+	// verification tokens can only be used once
+	err = suite.usersService.VerifyEmail(verificationToken)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 400, err.Status)
+}
+
+func (suite *UsersServiceTestSuite) TestVerifyEmailFailInvalidToken() {
+	err := suite.usersService.VerifyEmail("invalid")
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 400, err.Status)
+}
+
+func (suite *UsersServiceTestSuite) TestForgotPasswordUnknownEmail() {
+	mailsBefore := len(suite.mailer.Messages())
+
+	err := suite.usersService.ForgotPassword("unknown@test.com")
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), mailsBefore, len(suite.mailer.Messages()))
+}
+
+func (suite *UsersServiceTestSuite) TestResetPasswordSuccess() {
+	user := &models.User{
+		Email:    "reset@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(user)
+	require.Nil(suite.T(), err)
+
+	err = suite.usersService.ForgotPassword("reset@test.com")
+	require.Nil(suite.T(), err)
+
+	resetToken := suite.lastMailToken("reset@test.com")
+	require.NotEmpty(suite.T(), resetToken)
+
+	err = suite.usersService.ResetPassword(resetToken, "NewPassword123")
+	assert.Nil(suite.T(), err)
+
+	updatedUser, err := suite.usersService.GetUser("reset@test.com")
+	require.Nil(suite.T(), err)
+	assert.Nil(suite.T(), bcrypt.CompareHashAndPassword([]byte(updatedUser.Password), []byte("NewPassword123")))
+
+	// reset tokens can only be used once
+	err = suite.usersService.ResetPassword(resetToken, "AnotherPassword123")
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 400, err.Status)
+}
+
+func (suite *UsersServiceTestSuite) TestLoginUserUniformErrors() {
+	user := &models.User{
+		Email:    "test@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(user)
+	require.Nil(suite.T(), err)
+
+	_, _, errWrongPassword := suite.usersService.LoginUser(&models.User{Email: "test@test.com", Password: "Wrong123"}, dtos.ClientInfo{IpAddress: "127.0.0.1"})
+	_, _, errUnknownEmail := suite.usersService.LoginUser(&models.User{Email: "unknown@test.com", Password: "Wrong123"}, dtos.ClientInfo{IpAddress: "127.0.0.2"})
+
+	require.NotNil(suite.T(), errWrongPassword)
+	require.NotNil(suite.T(), errUnknownEmail)
+	assert.Equal(suite.T(), errWrongPassword, errUnknownEmail)
+}
+
+func (suite *UsersServiceTestSuite) TestLoginUserDelayedAfterFailure() {
+	user := &models.User{
+		Email:    "test@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(user)
+	require.Nil(suite.T(), err)
+
+	_, _, err = suite.usersService.LoginUser(&models.User{Email: "test@test.com", Password: "Wrong123"}, dtos.ClientInfo{IpAddress: "127.0.0.1"})
+	assert.Equal(suite.T(), 401, err.Status)
+
+	// the next attempt right after a failure is delayed, even with the correct password
+	_, _, err = suite.usersService.LoginUser(user, dtos.ClientInfo{IpAddress: "127.0.0.1"})
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 429, err.Status)
+}
+
+func (suite *UsersServiceTestSuite) TestLoginUserParallelAttempts() {
+	user := &models.User{
+		Email:    "test@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(user)
+	require.Nil(suite.T(), err)
+
+	statuses := make(chan int, 5)
+	var wg sync.WaitGroup
+
+	for range 5 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, err := suite.usersService.LoginUser(&models.User{Email: "test@test.com", Password: "Wrong123"}, dtos.ClientInfo{IpAddress: "127.0.0.1"})
+			statuses <- err.Status
+		}()
+	}
+
+	wg.Wait()
+	close(statuses)
+
+	// parallel attempts cannot pass the delay before the first failure is recorded
+	counts := map[int]int{}
+	for status := range statuses {
+		counts[status]++
+	}
+	assert.Equal(suite.T(), map[int]int{401: 1, 429: 4}, counts)
+}
+
+func (suite *UsersServiceTestSuite) TestUpdateProfile() {
+	user := &models.User{
+		Email:    "profile@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(user)
+	require.Nil(suite.T(), err)
+
+	displayName := "Test User"
+	timezone := "Europe/Berlin"
+
+	userProfile, err := suite.usersService.UpdateProfile(user.ID, &dtos.UserProfileUpdateDto{
+		DisplayName: &displayName,
+		Timezone:    &timezone,
+	})
+	assert.Nil(suite.T(), err)
+	assert.Equal(suite.T(), displayName, userProfile.DisplayName)
+	assert.Equal(suite.T(), timezone, userProfile.Timezone)
+	// fields missing in the update are kept
+	assert.Equal(suite.T(), "en", userProfile.Locale)
+}
+
+func (suite *UsersServiceTestSuite) TestChangeEmailAfterVerification() {
+	user := &models.User{
+		Email:    "old@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(user)
+	require.Nil(suite.T(), err)
+
+	err = suite.usersService.ChangeEmail(user.ID, "", "new@test.com", "Wrong123")
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 401, err.Status)
+
+	err = suite.usersService.ChangeEmail(user.ID, "", "new@test.com", "Test123")
+	require.Nil(suite.T(), err)
+
+	// the address is only changed once the new address has been verified
+	userProfile, err := suite.usersService.GetProfile(user.ID)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "old@test.com", userProfile.Email)
+
+	err = suite.usersService.VerifyEmail(suite.lastMailToken("new@test.com"))
+	require.Nil(suite.T(), err)
+
+	userProfile, err = suite.usersService.GetProfile(user.ID)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), "new@test.com", userProfile.Email)
+}
+
+func (suite *UsersServiceTestSuite) TestDeleteUserWithEventsAndRegistrations() {
+	organizer := &models.User{
+		Email:    "organizer@test.com",
+		Password: "Test123",
+	}
+	attendee := &models.User{
+		Email:    "attendee@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(organizer)
+	require.Nil(suite.T(), err)
+	err = suite.usersService.SignupUser(attendee)
+	require.Nil(suite.T(), err)
+
+	eventsRepository := repositories.NewEventsRepository(testutils.TestContainer.DB)
+	transactionHandler := repositories.NewTxHandler(testutils.TestContainer.DB)
+
+	organizerEvent, err := eventsRepository.QueryCreateEvent(&models.Event{
+		Name:        "Organizer event",
+		Location:    "Köln",
+		StartsAt:    time.Now().Add(24 * time.Hour),
+		EndsAt:      time.Now().Add(26 * time.Hour),
+		MaxCapacity: 10,
+		UserId:      organizer.ID,
+	})
+	require.Nil(suite.T(), err)
+
+	attendeeEvent, err := eventsRepository.QueryCreateEvent(&models.Event{
+		Name:        "Attendee event",
+		Location:    "Köln",
+		StartsAt:    time.Now().Add(24 * time.Hour),
+		EndsAt:      time.Now().Add(26 * time.Hour),
+		MaxCapacity: 10,
+		UserId:      attendee.ID,
+	})
+	require.Nil(suite.T(), err)
+
+	_, err = transactionHandler.ExecTx(organizerEvent.ID, attendee.ID)
+	require.Nil(suite.T(), err)
+	_, err = transactionHandler.ExecTx(attendeeEvent.ID, organizer.ID)
+	require.Nil(suite.T(), err)
+
+	err = suite.usersService.DeleteUser(organizer.ID, "", "Test123")
+	assert.Nil(suite.T(), err)
+
+	// events of the deleted user are gone, the seat of the deleted user is freed
+	_, err = eventsRepository.QueryGetEvent(organizerEvent.ID)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 404, err.Status)
+
+	// the attendee is told about the cancellation in the background and the calendar feed of the attendee shows the cancelled event
+	require.Eventually(suite.T(), func() bool {
+		messages := suite.mailer.Messages()
+		return len(messages) > 0 && messages[len(messages)-1].To == "attendee@test.com"
+	}, 5*time.Second, 10*time.Millisecond)
+
+	messages := suite.mailer.Messages()
+	assert.Equal(suite.T(), "Organizer event has been cancelled", messages[len(messages)-1].Subject)
+	assert.Contains(suite.T(), messages[len(messages)-1].Body, deletedOrganizerReason)
+
+	calendarEvents, err := eventsRepository.QueryGetUserCalendarEvents(attendee.ID)
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), calendarEvents, 1)
+	assert.Equal(suite.T(), organizerEvent.ID, calendarEvents[0].ID)
+	assert.NotNil(suite.T(), calendarEvents[0].DeletedAt)
+	assert.Equal(suite.T(), "", calendarEvents[0].UserId)
+
+	remainingEvent, err := eventsRepository.QueryGetEvent(attendeeEvent.ID)
+	require.Nil(suite.T(), err)
+	assert.Equal(suite.T(), 0, remainingEvent.AmountRegistration)
+
+	_, err = suite.usersService.GetUser("organizer@test.com")
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 404, err.Status)
+
+	// deleting the attendee keeps the remaining event as tombstone as well
+	err = suite.usersService.DeleteUser(attendee.ID, "", "Test123")
+	assert.Nil(suite.T(), err)
+}
+
+func (suite *UsersServiceTestSuite) TestDeleteUserWithoutPasswordRequiresRecentLogin() {
+	user := &models.User{
+		Email:    "sso@test.com",
+		Password: "Test123",
+	}
+
+	err := suite.usersService.SignupUser(user)
+	require.Nil(suite.T(), err)
+
+	// users that signed up with openid connect have no password
+	usersRepository := repositories.NewUsersRepository(testutils.TestContainer.DB)
+	err = usersRepository.QueryUpdatePassword(user.ID, models.NoPassword)
+	require.Nil(suite.T(), err)
+
+	_, err = suite.tokensService.IssueTokens(user.ID, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+	sessions, err := suite.tokensService.GetSessions(user.ID)
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), sessions, 1)
+
+	// the placeholder never works as password
+	err = suite.usersService.DeleteUser(user.ID, "", models.NoPassword)
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 403, err.Status)
+
+	// a session of an old login is not enough
+	_, dbErr := testutils.TestContainer.DB.Exec(`UPDATE sessions SET created_at = now() - interval '1 hour' WHERE id = $1`, sessions[0].ID)
+	require.NoError(suite.T(), dbErr)
+
+	err = suite.usersService.DeleteUser(user.ID, sessions[0].ID, "")
+	assert.NotNil(suite.T(), err)
+	assert.Equal(suite.T(), 403, err.Status)
+
+	// a fresh login confirms the deletion
+	oldSessionId := sessions[0].ID
+	_, err = suite.tokensService.IssueTokens(user.ID, dtos.ClientInfo{})
+	require.Nil(suite.T(), err)
+	sessions, err = suite.tokensService.GetSessions(user.ID)
+	require.Nil(suite.T(), err)
+	require.Len(suite.T(), sessions, 2)
+
+	freshSessionIndex := slices.IndexFunc(sessions, func(session *models.Session) bool {
+		return session.ID != oldSessionId
+	})
+	err = suite.usersService.DeleteUser(user.ID, sessions[freshSessionIndex].ID, "")
+	assert.Nil(suite.T(), err)
+}
+
+// lastMailToken extracts the token query parameter from the last link that has been mailed to the given address
+func (suite *UsersServiceTestSuite) lastMailToken(email string) string {
+	messages := suite.mailer.Messages()
+
+	for i := len(messages) - 1; i >= 0; i-- {
+		if messages[i].To != email {
+			continue
+		}
+
+		_, link, found := strings.Cut(messages[i].Body, "token=")
+		if !found {
+			return ""
+		}
+
+		return strings.Fields(link)[0]
+	}
+
+	return ""
+}
@@ -0,0 +1,51 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+	"eventom-backend/repositories"
+	"net/http"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type VenuesService struct {
+	venuesRepository repositories.VenuesRepositoryInterface
+}
+
+func NewVenuesService(venuesRepository repositories.VenuesRepositoryInterface) *VenuesService {
+	return &VenuesService{
+		venuesRepository: venuesRepository,
+	}
+}
+
+func (vs VenuesService) CreateVenue(userId string, venueDto *dtos.CreateVenueDto) (*models.Venue, *models.ResponseError) {
+	venue := &models.Venue{
+		Name:      strings.TrimSpace(venueDto.Name),
+		Address:   strings.TrimSpace(venueDto.Address),
+		Latitude:  *venueDto.Latitude,
+		Longitude: *venueDto.Longitude,
+		UserId:    &userId,
+	}
+
+	return vs.venuesRepository.QueryCreateVenue(venue)
+}
+
+func (vs VenuesService) GetVenue(venueId string) (*models.Venue, *models.ResponseError) {
+	if uuid.Validate(venueId) != nil {
+		return nil, &models.ResponseError{
+			Message: "Venue not found",
+			Status:  http.StatusNotFound,
+		}
+	}
+
+	return vs.venuesRepository.QueryGetVenue(venueId)
+}
+
+// GetVenues returns a page of the venues whose name or address contains the search term, all venues match an empty search term
+func (vs VenuesService) GetVenues(filters *dtos.VenueFilterDto) ([]*models.Venue, int, *models.ResponseError) {
+	filters.Search = strings.TrimSpace(filters.Search)
+
+	return vs.venuesRepository.QueryGetVenues(filters)
+}
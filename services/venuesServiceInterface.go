@@ -0,0 +1,14 @@
+package services
+
+import (
+	"eventom-backend/dtos"
+	"eventom-backend/models"
+)
+
+type VenuesServiceInterface interface {
+	CreateVenue(userId string, venueDto *dtos.CreateVenueDto) (*models.Venue, *models.ResponseError)
+
+	GetVenue(venueId string) (*models.Venue, *models.ResponseError)
+
+	GetVenues(filters *dtos.VenueFilterDto) ([]*models.Venue, int, *models.ResponseError)
+}
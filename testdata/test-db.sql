@@ -5,18 +5,95 @@ CREATE EXTENSION IF NOT EXISTS "uuid-ossp" WITH SCHEMA pg_catalog;
 CREATE TABLE IF NOT EXISTS users (
   id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
   email TEXT NOT NULL UNIQUE,
-  password TEXT NOT NULL
+  password TEXT NOT NULL,
+  verified boolean NOT NULL DEFAULT false,
+  display_name text NOT NULL DEFAULT '',
+  timezone text NOT NULL DEFAULT 'UTC',
+  locale text NOT NULL DEFAULT 'en',
+  erased_at timestamptz
 );
 
+--venues
+CREATE TABLE IF NOT EXISTS venues (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  venue_name text NOT NULL,
+  address text NOT NULL,
+  latitude double precision NOT NULL CHECK(latitude BETWEEN -90 AND 90),
+  longitude double precision NOT NULL CHECK(longitude BETWEEN -180 AND 180),
+  user_id uuid,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+--event series
+CREATE TABLE IF NOT EXISTS event_series (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  recurrence_rule text NOT NULL,
+  start_date date NOT NULL,
+  timezone text NOT NULL DEFAULT 'UTC',
+  exception_dates date[] NOT NULL DEFAULT '{}',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--text search configuration of a language
+CREATE OR REPLACE FUNCTION text_search_configuration(language text) RETURNS regconfig AS $$
+  SELECT (CASE split_part(lower(language), '-', 1)
+    WHEN 'da' THEN 'danish'
+    WHEN 'de' THEN 'german'
+    WHEN 'en' THEN 'english'
+    WHEN 'es' THEN 'spanish'
+    WHEN 'fi' THEN 'finnish'
+    WHEN 'fr' THEN 'french'
+    WHEN 'hu' THEN 'hungarian'
+    WHEN 'it' THEN 'italian'
+    WHEN 'nb' THEN 'norwegian'
+    WHEN 'nl' THEN 'dutch'
+    WHEN 'nn' THEN 'norwegian'
+    WHEN 'no' THEN 'norwegian'
+    WHEN 'pt' THEN 'portuguese'
+    WHEN 'ro' THEN 'romanian'
+    WHEN 'ru' THEN 'russian'
+    WHEN 'sv' THEN 'swedish'
+    WHEN 'tr' THEN 'turkish'
+    ELSE 'simple'
+  END)::regconfig
+$$ LANGUAGE sql IMMUTABLE;
+
 --events
 CREATE TABLE IF NOT EXISTS events (
   id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
   event_name text NOT NULL,
   event_description text NOT NULL,
   event_location text NOT NULL,
-  event_date date NOT NULL,
-  user_id uuid NOT NULL,
-  FOREIGN KEY(user_id) REFERENCES users(id)
+  category text NOT NULL DEFAULT '',
+  tags text[] NOT NULL DEFAULT '{}',
+  event_start timestamptz NOT NULL,
+  event_end timestamptz NOT NULL,
+  event_timezone text NOT NULL DEFAULT 'UTC',
+  max_capacity integer NOT NULL,
+  amount_registrations integer DEFAULT 0,
+  user_id uuid,
+  deleted_at timestamptz,
+  deletion_reason text NOT NULL DEFAULT '',
+  series_id uuid,
+  venue_id uuid,
+  updated_at timestamptz NOT NULL DEFAULT now(),
+  revision integer NOT NULL DEFAULT 0,
+  event_language text NOT NULL DEFAULT 'en',
+  search_vector tsvector GENERATED ALWAYS AS (
+    setweight(to_tsvector(text_search_configuration(event_language), event_name), 'A') ||
+    setweight(to_tsvector(text_search_configuration(event_language), event_description), 'B') ||
+    setweight(to_tsvector(text_search_configuration(event_language), event_location), 'C')
+  ) STORED,
+  prefix_vector tsvector GENERATED ALWAYS AS (
+    to_tsvector('simple', event_name || ' ' || event_description || ' ' || event_location)
+  ) STORED,
+  CHECK(event_end > event_start),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL,
+  FOREIGN KEY(series_id) REFERENCES event_series(id) ON DELETE SET NULL,
+  FOREIGN KEY(venue_id) REFERENCES venues(id) ON DELETE SET NULL
 );
 
 --registrations
@@ -25,6 +102,244 @@ CREATE TABLE IF NOT EXISTS registrations (
   event_id uuid,
   user_id uuid,
   FOREIGN KEY(event_id) REFERENCES events(id),
-  FOREIGN KEY(user_id) REFERENCES users(id)
+  FOREIGN KEY(user_id) REFERENCES users(id),
+  UNIQUE(event_id, user_id)
+);
+
+--refresh tokens
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  family_id uuid NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  revoked_at timestamptz,
+  replaced_by uuid,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--revoked tokens
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+  jti uuid PRIMARY KEY,
+  user_id uuid NOT NULL,
+  expires_at timestamptz NOT NULL,
+  revoked_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--user token revocations
+CREATE TABLE IF NOT EXISTS user_token_revocations (
+  user_id uuid PRIMARY KEY,
+  revoked_before timestamptz NOT NULL,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--roles and permissions
+CREATE TABLE IF NOT EXISTS roles (
+  role_name text PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+  permission_name text PRIMARY KEY
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+  role_name text NOT NULL,
+  permission_name text NOT NULL,
+  PRIMARY KEY(role_name, permission_name),
+  FOREIGN KEY(role_name) REFERENCES roles(role_name) ON DELETE CASCADE,
+  FOREIGN KEY(permission_name) REFERENCES permissions(permission_name) ON DELETE CASCADE
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+  user_id uuid NOT NULL,
+  role_name text NOT NULL,
+  PRIMARY KEY(user_id, role_name),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+  FOREIGN KEY(role_name) REFERENCES roles(role_name) ON DELETE CASCADE
+);
+
+INSERT INTO roles(role_name) VALUES
+  ('admin'),
+  ('organizer'),
+  ('attendee')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO permissions(permission_name) VALUES
+  ('events:write'),
+  ('events:manage_any'),
+  ('registrations:read'),
+  ('registrations:write'),
+  ('registrations:manage_any'),
+  ('users:manage')
+ON CONFLICT DO NOTHING;
+
+INSERT INTO role_permissions(role_name, permission_name) VALUES
+  ('admin', 'events:write'),
+  ('admin', 'events:manage_any'),
+  ('admin', 'registrations:read'),
+  ('admin', 'registrations:write'),
+  ('admin', 'registrations:manage_any'),
+  ('admin', 'users:manage'),
+  ('organizer', 'events:write'),
+  ('attendee', 'registrations:read'),
+  ('attendee', 'registrations:write')
+ON CONFLICT DO NOTHING;
+
+--email verification tokens
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  email text NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  used_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--password reset tokens
+CREATE TABLE IF NOT EXISTS password_reset_tokens (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  used_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
 );
 
+--login attempts
+CREATE TABLE IF NOT EXISTS login_attempts (
+  scope text NOT NULL,
+  subject text NOT NULL,
+  failed_attempts integer NOT NULL DEFAULT 0,
+  last_failed_at timestamptz NOT NULL DEFAULT now(),
+  locked_until timestamptz,
+  PRIMARY KEY(scope, subject)
+);
+
+--account unlock tokens
+CREATE TABLE IF NOT EXISTS account_unlock_tokens (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  token_hash text NOT NULL UNIQUE,
+  expires_at timestamptz NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  used_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--user totp
+CREATE TABLE IF NOT EXISTS user_totp (
+  user_id uuid PRIMARY KEY,
+  secret text NOT NULL,
+  confirmed_at timestamptz,
+  last_used_step bigint NOT NULL DEFAULT 0,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--totp recovery codes
+CREATE TABLE IF NOT EXISTS totp_recovery_codes (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  code_hash text NOT NULL,
+  used_at timestamptz,
+  UNIQUE(user_id, code_hash),
+  FOREIGN KEY(user_id) REFERENCES user_totp(user_id) ON DELETE CASCADE
+);
+
+--api keys
+CREATE TABLE IF NOT EXISTS api_keys (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  key_name text NOT NULL,
+  key_prefix text NOT NULL,
+  key_hash text NOT NULL UNIQUE,
+  scopes text[] NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  expires_at timestamptz,
+  last_used_at timestamptz,
+  revoked_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--erasure requests
+CREATE TABLE IF NOT EXISTS erasure_requests (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  requested_by uuid NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  completed_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS erasure_requests_pending_index ON erasure_requests(user_id) WHERE completed_at IS NULL;
+
+--user identities
+CREATE TABLE IF NOT EXISTS user_identities (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  user_id uuid NOT NULL,
+  issuer text NOT NULL,
+  subject text NOT NULL,
+  email text NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  last_login_at timestamptz,
+  UNIQUE(issuer, subject),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--oidc auth requests
+CREATE TABLE IF NOT EXISTS oidc_auth_requests (
+  state_hash text PRIMARY KEY,
+  nonce text NOT NULL,
+  code_verifier text NOT NULL,
+  expires_at timestamptz NOT NULL
+);
+
+--sessions
+CREATE TABLE IF NOT EXISTS sessions (
+  id uuid PRIMARY KEY,
+  user_id uuid NOT NULL,
+  user_agent text NOT NULL DEFAULT '',
+  ip_address text NOT NULL DEFAULT '',
+  created_at timestamptz NOT NULL DEFAULT now(),
+  last_seen_at timestamptz NOT NULL DEFAULT now(),
+  expires_at timestamptz NOT NULL,
+  revoked_at timestamptz,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--waitlist entries
+CREATE TABLE IF NOT EXISTS waitlist_entries (
+  id uuid PRIMARY KEY DEFAULT uuid_generate_v1mc(),
+  event_id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  created_at timestamptz NOT NULL DEFAULT clock_timestamp(),
+  UNIQUE(event_id, user_id),
+  FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS waitlist_entries_order_index ON waitlist_entries(event_id, created_at, id);
+
+--cancelled registrations
+CREATE TABLE IF NOT EXISTS cancelled_registrations (
+  id uuid PRIMARY KEY,
+  event_id uuid NOT NULL,
+  user_id uuid NOT NULL,
+  reason text NOT NULL,
+  cancelled_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(event_id) REFERENCES events(id) ON DELETE CASCADE,
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+--calendar feed tokens
+CREATE TABLE IF NOT EXISTS calendar_feed_tokens (
+  user_id uuid PRIMARY KEY,
+  token_hash text NOT NULL UNIQUE,
+  created_at timestamptz NOT NULL DEFAULT now(),
+  FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,205 @@
+package testutils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const stubOidcKeyId = "stub-key"
+
+// StubOidcUser is the user that is logged in at the stub identity provider
+type StubOidcUser struct {
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type stubAuthorization struct {
+	clientId      string
+	redirectUri   string
+	nonce         string
+	codeChallenge string
+	user          StubOidcUser
+}
+
+// StubOidcProvider is a local openid provider for tests. Its authorization endpoint logs in the current user without
+// asking and redirects back right away, the token endpoint checks the PKCE code verifier like a real provider
+type StubOidcProvider struct {
+	*httptest.Server
+	ClientId       string
+	User           StubOidcUser
+	privateKey     *rsa.PrivateKey
+	mu             sync.Mutex
+	authorizations map[string]stubAuthorization
+}
+
+func NewStubOidcProvider(clientId string) (*StubOidcProvider, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+
+	if err != nil {
+		return nil, err
+	}
+
+	stub := &StubOidcProvider{
+		ClientId:       clientId,
+		privateKey:     privateKey,
+		authorizations: make(map[string]stubAuthorization),
+	}
+
+	router := http.NewServeMux()
+	router.HandleFunc("GET /.well-known/openid-configuration", stub.handleDiscovery)
+	router.HandleFunc("GET /authorize", stub.handleAuthorize)
+	router.HandleFunc("POST /token", stub.handleToken)
+	router.HandleFunc("GET /jwks", stub.handleJwks)
+
+	stub.Server = httptest.NewServer(router)
+
+	return stub, nil
+}
+
+func (sop *StubOidcProvider) SetUser(user StubOidcUser) {
+	sop.mu.Lock()
+	defer sop.mu.Unlock()
+
+	sop.User = user
+}
+
+func (sop *StubOidcProvider) handleDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJson(w, map[string]string{
+		"issuer":                 sop.URL,
+		"authorization_endpoint": sop.URL + "/authorize",
+		"token_endpoint":         sop.URL + "/token",
+		"jwks_uri":               sop.URL + "/jwks",
+	})
+}
+
+func (sop *StubOidcProvider) handleAuthorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	if query.Get("response_type") != "code" || query.Get("code_challenge_method") != "S256" || query.Get("client_id") != sop.ClientId {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	code := randomString()
+
+	sop.mu.Lock()
+	sop.authorizations[code] = stubAuthorization{
+		clientId:      query.Get("client_id"),
+		redirectUri:   query.Get("redirect_uri"),
+		nonce:         query.Get("nonce"),
+		codeChallenge: query.Get("code_challenge"),
+		user:          sop.User,
+	}
+	sop.mu.Unlock()
+
+	redirectUrl, err := url.Parse(query.Get("redirect_uri"))
+
+	if err != nil {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	redirectQuery := redirectUrl.Query()
+	redirectQuery.Set("code", code)
+	redirectQuery.Set("state", query.Get("state"))
+	redirectUrl.RawQuery = redirectQuery.Encode()
+
+	http.Redirect(w, r, redirectUrl.String(), http.StatusFound)
+}
+
+func (sop *StubOidcProvider) handleToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+
+	if err != nil {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	// codes can only be redeemed once
+	sop.mu.Lock()
+	authorization, ok := sop.authorizations[r.PostForm.Get("code")]
+	delete(sop.authorizations, r.PostForm.Get("code"))
+	sop.mu.Unlock()
+
+	hash := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	codeChallenge := base64.RawURLEncoding.EncodeToString(hash[:])
+
+	if !ok ||
+		r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != authorization.clientId ||
+		r.PostForm.Get("redirect_uri") != authorization.redirectUri ||
+		codeChallenge != authorization.codeChallenge {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":            sop.URL,
+		"sub":            authorization.user.Subject,
+		"aud":            authorization.clientId,
+		"iat":            now.Unix(),
+		"exp":            now.Add(5 * time.Minute).Unix(),
+		"nonce":          authorization.nonce,
+		"email":          authorization.user.Email,
+		"email_verified": authorization.user.EmailVerified,
+		"name":           authorization.user.Name,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = stubOidcKeyId
+
+	idToken, err := token.SignedString(sop.privateKey)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	writeJson(w, map[string]string{
+		"access_token": randomString(),
+		"token_type":   "Bearer",
+		"id_token":     idToken,
+	})
+}
+
+func (sop *StubOidcProvider) handleJwks(w http.ResponseWriter, r *http.Request) {
+	publicKey := sop.privateKey.PublicKey
+
+	writeJson(w, map[string]any{
+		"keys": []map[string]string{
+			{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": stubOidcKeyId,
+				"n":   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+			},
+		},
+	})
+}
+
+func writeJson(w http.ResponseWriter, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(value)
+}
+
+func randomString() string {
+	buffer := make([]byte, 16)
+	_, _ = rand.Read(buffer)
+	return base64.RawURLEncoding.EncodeToString(buffer)
+}
@@ -0,0 +1,31 @@
+package testutils
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+)
+
+// CreateSigningKeyFile writes a throwaway private key to the directory, so tests can sign access tokens
+func CreateSigningKeyFile(directory string) (string, error) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return "", err
+	}
+
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return "", err
+	}
+
+	keyFile := filepath.Join(directory, "private-key.pem")
+	err = os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}), 0600)
+	if err != nil {
+		return "", err
+	}
+
+	return keyFile, nil
+}
@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"crypto/subtle"
+	"eventom-backend/dtos"
+	"net/http"
+	"time"
+)
+
+const AccessTokenCookieName = "jwt"
+const RefreshTokenCookieName = "refresh_token"
+
+// OidcStateCookieName binds a login at the identity provider to the browser that started it. Without it an attacker could
+// send a victim the callback url of the attacker's own login and log the victim into the attacker's account
+const OidcStateCookieName = "oidc_state"
+
+// oidcCookiePath covers the login and the callback route
+const oidcCookiePath = "/login/oidc"
+
+// SetAuthCookies sets the access and the refresh token. The access token is Lax, so other sites cannot send requests that change data
+// with it but links to this app still open logged in. The refresh token is only needed by requests of this app itself
+func SetAuthCookies(w http.ResponseWriter, tokens *dtos.AuthTokens) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     AccessTokenCookieName,
+		Value:    tokens.AccessToken,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  tokens.AccessTokenExpiresAt,
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    tokens.RefreshToken,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  tokens.RefreshTokenExpiresAt,
+	})
+}
+
+// ClearAuthCookies overwrites the cookies of SetAuthCookies with the same attributes, so browsers replace them
+func ClearAuthCookies(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     AccessTokenCookieName,
+		Value:    "",
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now(),
+	})
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     RefreshTokenCookieName,
+		Value:    "",
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteStrictMode,
+		Expires:  time.Now(),
+	})
+}
+
+// SetOidcStateCookie stores the hash of the state of a started login. Lax, so the cookie is sent on the redirect back from the identity provider
+func SetOidcStateCookie(w http.ResponseWriter, state string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OidcStateCookieName,
+		Value:    HashToken(state),
+		Path:     oidcCookiePath,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now().Add(OidcAuthRequestTTL),
+	})
+}
+
+// VerifyOidcStateCookie checks that the state of the callback belongs to the login that has been started by this browser
+func VerifyOidcStateCookie(r *http.Request, state string) bool {
+	cookie, err := r.Cookie(OidcStateCookieName)
+
+	if err != nil {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(HashToken(state))) == 1
+}
+
+func ClearOidcStateCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     OidcStateCookieName,
+		Value:    "",
+		Path:     oidcCookiePath,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  time.Now(),
+	})
+}
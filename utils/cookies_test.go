@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"eventom-backend/dtos"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOidcStateCookie(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	SetOidcStateCookie(recorder, "state")
+
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, OidcStateCookieName, cookies[0].Name)
+	assert.NotEqual(t, "state", cookies[0].Value)
+	assert.True(t, cookies[0].HttpOnly)
+	assert.True(t, cookies[0].Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+
+	request := httptest.NewRequest("GET", "/login/oidc/callback?state=state", nil)
+	request.AddCookie(cookies[0])
+	assert.True(t, VerifyOidcStateCookie(request, "state"))
+	assert.False(t, VerifyOidcStateCookie(request, "other-state"))
+
+	// a callback url that has been sent to another browser is rejected
+	assert.False(t, VerifyOidcStateCookie(httptest.NewRequest("GET", "/login/oidc/callback?state=state", nil), "state"))
+}
+
+func TestAuthCookies(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	SetAuthCookies(recorder, &dtos.AuthTokens{
+		AccessToken:           "access",
+		AccessTokenExpiresAt:  time.Now().Add(time.Minute),
+		RefreshToken:          "refresh",
+		RefreshTokenExpiresAt: time.Now().Add(time.Hour),
+	})
+	ClearAuthCookies(recorder)
+
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 4)
+
+	// the cleared cookies have the attributes of the set ones, otherwise browsers keep the set ones
+	for index, cookie := range cookies {
+		assert.True(t, cookie.HttpOnly, cookie.Name)
+		assert.True(t, cookie.Secure, cookie.Name)
+		assert.Equal(t, cookies[index%2].SameSite, cookie.SameSite, cookie.Name)
+	}
+	assert.Equal(t, http.SameSiteLaxMode, cookies[0].SameSite)
+	assert.Equal(t, http.SameSiteStrictMode, cookies[1].SameSite)
+}
@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const calendarProductId = "-//eventom//eventom-backend//EN"
+
+// calendarTimeLayout is the UTC form of DATE-TIME values, times are converted to UTC so no VTIMEZONE components are needed
+const calendarTimeLayout = "20060102T150405Z"
+
+// maxCalendarLineLength is the maximum length of a content line in octets without the line break
+const maxCalendarLineLength = 75
+
+// CalendarEvent is a VEVENT of an iCalendar file as defined by RFC 5545. Uid has to stay the same for the lifetime of the event
+// and Sequence has to be increased on every change, so calendar apps update the event instead of adding it again
+type CalendarEvent struct {
+	Uid          string
+	Sequence     int
+	Summary      string
+	Description  string
+	Location     string
+	Url          string
+	Start        time.Time
+	End          time.Time
+	LastModified time.Time
+	Cancelled    bool
+}
+
+// BuildCalendar returns a VCALENDAR with the given events. name is shown by calendar apps that subscribe to the calendar
+func BuildCalendar(name string, events []*CalendarEvent, stamp time.Time) []byte {
+	var builder strings.Builder
+
+	writeCalendarLine(&builder, "BEGIN", "VCALENDAR")
+	writeCalendarLine(&builder, "VERSION", "2.0")
+	writeCalendarLine(&builder, "PRODID", calendarProductId)
+	writeCalendarLine(&builder, "CALSCALE", "GREGORIAN")
+	writeCalendarLine(&builder, "METHOD", "PUBLISH")
+	if name != "" {
+		writeCalendarLine(&builder, "X-WR-CALNAME", escapeCalendarText(name))
+	}
+
+	for _, event := range events {
+		status := "CONFIRMED"
+		if event.Cancelled {
+			status = "CANCELLED"
+		}
+
+		writeCalendarLine(&builder, "BEGIN", "VEVENT")
+		writeCalendarLine(&builder, "UID", event.Uid)
+		writeCalendarLine(&builder, "DTSTAMP", formatCalendarTime(stamp))
+		writeCalendarLine(&builder, "DTSTART", formatCalendarTime(event.Start))
+		writeCalendarLine(&builder, "DTEND", formatCalendarTime(event.End))
+		writeCalendarLine(&builder, "LAST-MODIFIED", formatCalendarTime(event.LastModified))
+		writeCalendarLine(&builder, "SEQUENCE", fmt.Sprint(event.Sequence))
+		writeCalendarLine(&builder, "STATUS", status)
+		writeCalendarLine(&builder, "SUMMARY", escapeCalendarText(event.Summary))
+		if event.Description != "" {
+			writeCalendarLine(&builder, "DESCRIPTION", escapeCalendarText(event.Description))
+		}
+		if event.Location != "" {
+			writeCalendarLine(&builder, "LOCATION", escapeCalendarText(event.Location))
+		}
+		if event.Url != "" {
+			writeCalendarLine(&builder, "URL", event.Url)
+		}
+		writeCalendarLine(&builder, "END", "VEVENT")
+	}
+
+	writeCalendarLine(&builder, "END", "VCALENDAR")
+
+	return []byte(builder.String())
+}
+
+// writeCalendarLine writes a content line ending with CRLF. Lines longer than 75 octets are folded without splitting characters
+func writeCalendarLine(builder *strings.Builder, name string, value string) {
+	line := name + ":" + value
+	lineLength := 0
+
+	for _, character := range line {
+		characterLength := utf8.RuneLen(character)
+		if lineLength+characterLength > maxCalendarLineLength {
+			builder.WriteString("\r\n ")
+			// the leading space of the continuation line counts towards its length
+			lineLength = 1
+		}
+		builder.WriteRune(character)
+		lineLength += characterLength
+	}
+
+	builder.WriteString("\r\n")
+}
+
+// escapeCalendarText escapes TEXT values, backslashes, semicolons and commas are escaped and line breaks are written as \n
+func escapeCalendarText(value string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(value)
+}
+
+func formatCalendarTime(value time.Time) string {
+	return value.UTC().Format(calendarTimeLayout)
+}
@@ -0,0 +1,74 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCalendar(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.Nil(t, err)
+
+	calendar := string(BuildCalendar("My events", []*CalendarEvent{
+		{
+			Uid:          "1@eventom",
+			Sequence:     2,
+			Summary:      "Meetup; with, special\\characters",
+			Description:  "First line\nSecond line",
+			Location:     "Köln",
+			Start:        time.Date(2024, time.May, 7, 19, 0, 0, 0, berlin),
+			End:          time.Date(2024, time.May, 7, 21, 0, 0, 0, berlin),
+			LastModified: time.Date(2024, time.May, 1, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			Uid:          "2@eventom",
+			Summary:      "Cancelled meetup",
+			Start:        time.Date(2024, time.May, 8, 19, 0, 0, 0, time.UTC),
+			End:          time.Date(2024, time.May, 8, 21, 0, 0, 0, time.UTC),
+			LastModified: time.Date(2024, time.May, 2, 8, 0, 0, 0, time.UTC),
+			Cancelled:    true,
+		},
+	}, time.Date(2024, time.May, 3, 12, 0, 0, 0, time.UTC)))
+
+	assert.True(t, strings.HasPrefix(calendar, "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.True(t, strings.HasSuffix(calendar, "END:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.Contains(t, calendar, "X-WR-CALNAME:My events\r\n")
+	assert.Contains(t, calendar, "UID:1@eventom\r\nDTSTAMP:20240503T120000Z\r\nDTSTART:20240507T170000Z\r\nDTEND:20240507T190000Z\r\n")
+	assert.Contains(t, calendar, "SEQUENCE:2\r\nSTATUS:CONFIRMED\r\n")
+	assert.Contains(t, calendar, `SUMMARY:Meetup\; with\, special\\characters`+"\r\n")
+	assert.Contains(t, calendar, `DESCRIPTION:First line\nSecond line`+"\r\n")
+	assert.Contains(t, calendar, "UID:2@eventom\r\n")
+	assert.Contains(t, calendar, "STATUS:CANCELLED\r\n")
+	assert.Equal(t, 2, strings.Count(calendar, "BEGIN:VEVENT\r\n"))
+}
+
+func TestBuildCalendarFoldsLongLines(t *testing.T) {
+	calendar := string(BuildCalendar("", []*CalendarEvent{
+		{
+			Uid:     "1@eventom",
+			Summary: strings.Repeat("ä", 100),
+		},
+	}, time.Now()))
+
+	lines := strings.Split(strings.TrimSuffix(calendar, "\r\n"), "\r\n")
+	summary := ""
+	for index, line := range lines {
+		assert.LessOrEqual(t, len(line), 75)
+		if strings.HasPrefix(line, "SUMMARY:") {
+			summary = line
+			for _, continuation := range lines[index+1:] {
+				if !strings.HasPrefix(continuation, " ") {
+					break
+				}
+				summary += strings.TrimPrefix(continuation, " ")
+			}
+		}
+	}
+
+	assert.Equal(t, "SUMMARY:"+strings.Repeat("ä", 100), summary)
+	assert.NotContains(t, calendar, "X-WR-CALNAME")
+}
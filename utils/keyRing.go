@@ -0,0 +1,310 @@
+package utils
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"eventom-backend/dtos"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// retiredKeyRetention is how long a key that has been removed from the key directory still verifies tokens.
+// It has to cover the lifetime of every jwt we sign, otherwise removing a key would log users out
+const retiredKeyRetention = AccessTokenTTL
+
+type ringKey struct {
+	id         string
+	privateKey *rsa.PrivateKey
+	addedAt    time.Time
+	retiredAt  *time.Time
+}
+
+// keyRing holds the key that signs new tokens and all keys that are still accepted to verify tokens
+type keyRing struct {
+	mu              sync.RWMutex
+	directory       string
+	activationDelay time.Duration
+	signingKey      *ringKey
+	keys            map[string]*ringKey
+}
+
+var signingKeys = &keyRing{
+	keys: make(map[string]*ringKey),
+}
+
+// LoadKeyDirectory loads all pem encoded rsa keys of the directory into the key ring.
+// New keys are published in the jwks right away, but only sign tokens after the activation delay,
+// so other services have time to fetch them before they see the first token signed with them
+func LoadKeyDirectory(directory string, activationDelay time.Duration) error {
+	signingKeys.mu.Lock()
+	signingKeys.directory = directory
+	signingKeys.activationDelay = activationDelay
+	signingKeys.mu.Unlock()
+
+	return ReloadKeyRing()
+}
+
+// ReloadKeyRing rescans the key directory. Keys that have been removed from the directory are retired and
+// kept for verification until every token signed with them has expired
+func ReloadKeyRing() error {
+	signingKeys.mu.RLock()
+	directory := signingKeys.directory
+	signingKeys.mu.RUnlock()
+
+	if directory == "" {
+		return errors.New("no key directory configured")
+	}
+
+	keyFiles, err := filepath.Glob(filepath.Join(directory, "*.pem"))
+
+	if err != nil {
+		return err
+	}
+
+	loadedKeys := make(map[string]*ringKey, len(keyFiles))
+
+	for _, keyFile := range keyFiles {
+		fileInfo, err := os.Stat(keyFile)
+
+		if err != nil {
+			return err
+		}
+
+		privateKey, err := readPrivateKey(keyFile)
+
+		if err != nil {
+			return fmt.Errorf("error while reading key %s: %w", keyFile, err)
+		}
+
+		keyId := keyThumbprint(&privateKey.PublicKey)
+		loadedKeys[keyId] = &ringKey{
+			id:         keyId,
+			privateKey: privateKey,
+			addedAt:    fileInfo.ModTime(),
+		}
+	}
+
+	if len(loadedKeys) == 0 {
+		return fmt.Errorf("no keys found in key directory %s", directory)
+	}
+
+	signingKeys.mu.Lock()
+	defer signingKeys.mu.Unlock()
+
+	now := time.Now()
+
+	for keyId, key := range signingKeys.keys {
+		if _, ok := loadedKeys[keyId]; ok {
+			continue
+		}
+
+		if key.retiredAt == nil {
+			key.retiredAt = &now
+		}
+
+		if now.Sub(*key.retiredAt) < retiredKeyRetention {
+			loadedKeys[keyId] = key
+		}
+	}
+
+	signingKeys.keys = loadedKeys
+	signingKeys.signingKey = selectSigningKey(loadedKeys, now, signingKeys.activationDelay)
+
+	return nil
+}
+
+// StartKeyRingReloader rescans the key directory in the given interval, so keys can be rotated without a restart
+func StartKeyRingReloader(interval time.Duration, logger *Logger) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			previousKeyId := currentSigningKeyId()
+
+			err := ReloadKeyRing()
+
+			if err != nil {
+				logger.Log(LevelError, fmt.Sprintf("Could not reload signing keys: %v", err), nil)
+				continue
+			}
+
+			if keyId := currentSigningKeyId(); keyId != previousKeyId {
+				logger.Log(LevelInfo, fmt.Sprintf("Rotated jwt signing key to %s", keyId), nil)
+			}
+		}
+	}()
+}
+
+// ReadPrivateKeyFromFile loads a single key that signs and verifies all tokens
+func ReadPrivateKeyFromFile(filename string) error {
+	privateKey, err := readPrivateKey(filename)
+
+	if err != nil {
+		return err
+	}
+
+	key := &ringKey{
+		id:         keyThumbprint(&privateKey.PublicKey),
+		privateKey: privateKey,
+		addedAt:    time.Now(),
+	}
+
+	signingKeys.mu.Lock()
+	defer signingKeys.mu.Unlock()
+
+	signingKeys.keys = map[string]*ringKey{key.id: key}
+	signingKeys.signingKey = key
+
+	return nil
+}
+
+// GetJwks returns the public keys of all keys that verify tokens in the json web key set format of RFC 7517
+func GetJwks() *dtos.JwkSet {
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+
+	jwkSet := &dtos.JwkSet{
+		Keys: make([]dtos.Jwk, 0, len(signingKeys.keys)),
+	}
+
+	for _, key := range signingKeys.keys {
+		jwkSet.Keys = append(jwkSet.Keys, publicJwk(key.id, &key.privateKey.PublicKey))
+	}
+
+	// keep the order stable for caches
+	sort.Slice(jwkSet.Keys, func(i, j int) bool {
+		return jwkSet.Keys[i].Kid < jwkSet.Keys[j].Kid
+	})
+
+	return jwkSet
+}
+
+func currentSigningKey() (*ringKey, error) {
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+
+	if signingKeys.signingKey == nil {
+		return nil, errors.New("no signing key loaded")
+	}
+
+	return signingKeys.signingKey, nil
+}
+
+func currentSigningKeyId() string {
+	key, err := currentSigningKey()
+
+	if err != nil {
+		return ""
+	}
+
+	return key.id
+}
+
+// verificationKeys returns the key with the given id. Tokens without key id have been signed before keys had ids, all keys are tried for them
+func verificationKeys(keyId string) []*rsa.PublicKey {
+	signingKeys.mu.RLock()
+	defer signingKeys.mu.RUnlock()
+
+	if keyId != "" {
+		key, ok := signingKeys.keys[keyId]
+		if !ok {
+			return nil
+		}
+		return []*rsa.PublicKey{&key.privateKey.PublicKey}
+	}
+
+	publicKeys := make([]*rsa.PublicKey, 0, len(signingKeys.keys))
+	for _, key := range signingKeys.keys {
+		publicKeys = append(publicKeys, &key.privateKey.PublicKey)
+	}
+
+	return publicKeys
+}
+
+// selectSigningKey picks the newest key whose activation delay has passed. If no key is active yet, e.g. on the
+// first start, the oldest key is used
+func selectSigningKey(keys map[string]*ringKey, now time.Time, activationDelay time.Duration) *ringKey {
+	var newestActive, oldest *ringKey
+
+	for _, key := range keys {
+		if key.retiredAt != nil {
+			continue
+		}
+
+		if oldest == nil || key.addedAt.Before(oldest.addedAt) {
+			oldest = key
+		}
+
+		if !key.addedAt.Add(activationDelay).After(now) && (newestActive == nil || key.addedAt.After(newestActive.addedAt)) {
+			newestActive = key
+		}
+	}
+
+	if newestActive != nil {
+		return newestActive
+	}
+
+	return oldest
+}
+
+func readPrivateKey(filename string) (*rsa.PrivateKey, error) {
+	buffer, err := os.ReadFile(filename)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, _ := pem.Decode(buffer)
+
+	if data == nil {
+		return nil, errors.New("no pem data found")
+	}
+
+	// openssl writes pkcs1 or pkcs8 depending on the version
+	if strings.Contains(data.Type, "RSA") {
+		return x509.ParsePKCS1PrivateKey(data.Bytes)
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(data.Bytes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if key, ok := key.(*rsa.PrivateKey); ok {
+		return key, nil
+	}
+
+	return nil, errors.New("error while reading private key")
+}
+
+// keyThumbprint derives the key id from the public key as described in RFC 7638, so every instance computes the same id
+func keyThumbprint(publicKey *rsa.PublicKey) string {
+	jwk := publicJwk("", publicKey)
+	canonicalJwk := fmt.Sprintf(`{"e":"%s","kty":"%s","n":"%s"}`, jwk.E, jwk.Kty, jwk.N)
+	hash := sha256.Sum256([]byte(canonicalJwk))
+
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func publicJwk(keyId string, publicKey *rsa.PublicKey) dtos.Jwk {
+	return dtos.Jwk{
+		Kty: "RSA",
+		Use: "sig",
+		Alg: "RS256",
+		Kid: keyId,
+		N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(publicKey.E)).Bytes()),
+	}
+}
@@ -0,0 +1,273 @@
+package utils
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"eventom-backend/dtos"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// OidcAuthRequestTTL is how long a user has to log in at the identity provider after the login has been started
+const OidcAuthRequestTTL = 10 * time.Minute
+
+// ReauthenticationWindow is how long after a login users without a password may confirm sensitive changes with their session,
+// afterwards they have to log in at the identity provider again
+const ReauthenticationWindow = 10 * time.Minute
+
+type OidcConfig struct {
+	Issuer       string
+	ClientId     string
+	ClientSecret string
+	RedirectUrl  string
+	Scopes       []string
+	// LinkExistingUsers allows the first login to link the identity to an existing user with the same verified email address
+	LinkExistingUsers bool
+}
+
+// OidcProvider implements the client side of the authorization code flow with PKCE (RFC 7636) against an openid provider
+type OidcProvider struct {
+	config     OidcConfig
+	metadata   *dtos.OidcProviderMetadata
+	httpClient *http.Client
+	keysMu     sync.RWMutex
+	keys       map[string]*rsa.PublicKey
+}
+
+// NewOidcProvider loads the discovery document of the issuer, so endpoints do not have to be configured one by one
+func NewOidcProvider(config OidcConfig, httpClient *http.Client) (*OidcProvider, error) {
+	config.Issuer = strings.TrimSuffix(config.Issuer, "/")
+
+	var metadata dtos.OidcProviderMetadata
+	err := getJson(httpClient, config.Issuer+"/.well-known/openid-configuration", &metadata)
+
+	if err != nil {
+		return nil, fmt.Errorf("error while loading openid configuration: %w", err)
+	}
+
+	// the issuer of the discovery document has to match exactly, otherwise id tokens of another issuer could be accepted
+	if metadata.Issuer != config.Issuer {
+		return nil, fmt.Errorf("issuer mismatch: expected %s, got %s", config.Issuer, metadata.Issuer)
+	}
+
+	return &OidcProvider{
+		config:     config,
+		metadata:   &metadata,
+		httpClient: httpClient,
+		keys:       make(map[string]*rsa.PublicKey),
+	}, nil
+}
+
+func (op *OidcProvider) Issuer() string {
+	return op.config.Issuer
+}
+
+func (op *OidcProvider) LinkExistingUsers() bool {
+	return op.config.LinkExistingUsers
+}
+
+// AuthCodeUrl builds the url of the identity provider the user is redirected to
+func (op *OidcProvider) AuthCodeUrl(state string, nonce string, codeVerifier string) string {
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {op.config.ClientId},
+		"redirect_uri":          {op.config.RedirectUrl},
+		"scope":                 {strings.Join(op.config.Scopes, " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {CodeChallengeS256(codeVerifier)},
+		"code_challenge_method": {"S256"},
+	}
+
+	separator := "?"
+	if strings.Contains(op.metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+
+	return op.metadata.AuthorizationEndpoint + separator + query.Encode()
+}
+
+// ExchangeCode redeems the authorization code at the token endpoint and returns the verified claims of the id token
+func (op *OidcProvider) ExchangeCode(code string, codeVerifier string, nonce string) (*dtos.OidcIdTokenClaims, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {op.config.RedirectUrl},
+		"client_id":     {op.config.ClientId},
+		"code_verifier": {codeVerifier},
+	}
+
+	// public clients only prove themselves with the code verifier
+	if op.config.ClientSecret != "" {
+		form.Set("client_secret", op.config.ClientSecret)
+	}
+
+	response, err := op.httpClient.PostForm(op.metadata.TokenEndpoint, form)
+
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint answered with status %d", response.StatusCode)
+	}
+
+	var tokenResponse dtos.OidcTokenResponse
+	err = json.NewDecoder(response.Body).Decode(&tokenResponse)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenResponse.IdToken == "" {
+		return nil, errors.New("token response contains no id token")
+	}
+
+	return op.verifyIdToken(tokenResponse.IdToken, nonce)
+}
+
+func (op *OidcProvider) verifyIdToken(idToken string, nonce string) (*dtos.OidcIdTokenClaims, error) {
+	var claims dtos.OidcIdTokenClaims
+
+	_, err := jwt.ParseWithClaims(idToken, &claims, op.keyFunc,
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(op.config.Issuer),
+		jwt.WithAudience(op.config.ClientId),
+		jwt.WithExpirationRequired(),
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if claims.Subject == "" {
+		return nil, errors.New("id token has no subject")
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errors.New("nonce mismatch")
+	}
+
+	return &claims, nil
+}
+
+// keyFunc returns the key named by the kid header. Unknown keys trigger a reload of the jwks, since providers rotate their keys
+func (op *OidcProvider) keyFunc(token *jwt.Token) (interface{}, error) {
+	keyId, _ := token.Header["kid"].(string)
+
+	if key := op.cachedKey(keyId); key != nil {
+		return key, nil
+	}
+
+	err := op.loadKeys()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if key := op.cachedKey(keyId); key != nil {
+		return key, nil
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", keyId)
+}
+
+func (op *OidcProvider) cachedKey(keyId string) *rsa.PublicKey {
+	op.keysMu.RLock()
+	defer op.keysMu.RUnlock()
+
+	if keyId == "" && len(op.keys) == 1 {
+		for _, key := range op.keys {
+			return key
+		}
+	}
+
+	return op.keys[keyId]
+}
+
+func (op *OidcProvider) loadKeys() error {
+	var jwkSet dtos.JwkSet
+	err := getJson(op.httpClient, op.metadata.JwksUri, &jwkSet)
+
+	if err != nil {
+		return fmt.Errorf("error while loading jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(jwkSet.Keys))
+
+	for _, jwk := range jwkSet.Keys {
+		if jwk.Kty != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+
+		publicKey, err := parseRsaJwk(jwk)
+
+		if err != nil {
+			return err
+		}
+
+		keys[jwk.Kid] = publicKey
+	}
+
+	op.keysMu.Lock()
+	op.keys = keys
+	op.keysMu.Unlock()
+
+	return nil
+}
+
+// GenerateCodeVerifier returns a random PKCE code verifier
+func GenerateCodeVerifier() (string, error) {
+	return GenerateOpaqueToken()
+}
+
+// CodeChallengeS256 derives the PKCE code challenge that is sent to the identity provider from the code verifier
+func CodeChallengeS256(codeVerifier string) string {
+	hash := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(hash[:])
+}
+
+func parseRsaJwk(jwk dtos.Jwk) (*rsa.PublicKey, error) {
+	modulus, err := base64.RawURLEncoding.DecodeString(jwk.N)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus of key %s: %w", jwk.Kid, err)
+	}
+
+	exponent, err := base64.RawURLEncoding.DecodeString(jwk.E)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent of key %s: %w", jwk.Kid, err)
+	}
+
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(modulus),
+		E: int(new(big.Int).SetBytes(exponent).Int64()),
+	}, nil
+}
+
+func getJson(httpClient *http.Client, url string, target any) error {
+	response, err := httpClient.Get(url)
+
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s answered with status %d", url, response.StatusCode)
+	}
+
+	return json.NewDecoder(response.Body).Decode(target)
+}
@@ -0,0 +1,341 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Frequencies of recurrence rules as defined by RFC 5545, only date based frequencies are supported because events are whole days
+const (
+	RecurrenceDaily   = "DAILY"
+	RecurrenceWeekly  = "WEEKLY"
+	RecurrenceMonthly = "MONTHLY"
+	RecurrenceYearly  = "YEARLY"
+)
+
+// maxRecurrencePeriods stops the expansion of rules that never produce an occurrence, e.g. the 31st of every second february
+const maxRecurrencePeriods = 10000
+
+var recurrenceWeekdays = map[string]time.Weekday{
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+	"SU": time.Sunday,
+}
+
+// RecurrenceWeekday is an entry of BYDAY. Ordinal selects the nth weekday of the month, negative values count from the end
+// of the month and 0 selects every matching weekday
+type RecurrenceWeekday struct {
+	Weekday time.Weekday
+	Ordinal int
+}
+
+// RecurrenceRule is the subset of an iCalendar RRULE that is needed to repeat events, e.g. FREQ=WEEKLY;BYDAY=TU,TH;COUNT=10
+type RecurrenceRule struct {
+	Frequency  string
+	Interval   int
+	Count      int
+	Until      *time.Time
+	ByDay      []RecurrenceWeekday
+	ByMonthDay []int
+}
+
+// ParseRecurrenceRule parses an RRULE with or without the RRULE: prefix. Rules have to be limited by COUNT or UNTIL,
+// parts that are not supported are rejected instead of being ignored
+func ParseRecurrenceRule(value string) (*RecurrenceRule, error) {
+	rule := &RecurrenceRule{
+		Interval: 1,
+	}
+
+	value = strings.TrimPrefix(strings.TrimSpace(value), "RRULE:")
+	if value == "" {
+		return nil, errors.New("recurrence rule is empty")
+	}
+
+	for _, part := range strings.Split(value, ";") {
+		name, partValue, found := strings.Cut(part, "=")
+		if !found || partValue == "" {
+			return nil, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+
+		var err error
+		switch strings.ToUpper(name) {
+		case "FREQ":
+			rule.Frequency = strings.ToUpper(partValue)
+			if !slices.Contains([]string{RecurrenceDaily, RecurrenceWeekly, RecurrenceMonthly, RecurrenceYearly}, rule.Frequency) {
+				err = fmt.Errorf("frequency %s is not supported", partValue)
+			}
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(partValue)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("interval must be greater or equal 1")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(partValue)
+			if err == nil && rule.Count < 1 {
+				err = errors.New("count must be greater or equal 1")
+			}
+		case "UNTIL":
+			var until time.Time
+			until, err = parseRecurrenceDate(partValue)
+			rule.Until = &until
+		case "BYDAY":
+			rule.ByDay, err = parseRecurrenceWeekdays(partValue)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseRecurrenceMonthDays(partValue)
+		case "WKST":
+			if strings.ToUpper(partValue) != "MO" {
+				err = errors.New("only weeks starting on monday are supported")
+			}
+		default:
+			err = fmt.Errorf("recurrence rule part %s is not supported", name)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if rule.Frequency == "" {
+		return nil, errors.New("recurrence rule has no frequency")
+	}
+
+	if rule.Count == 0 && rule.Until == nil {
+		return nil, errors.New("recurrence rule must be limited by COUNT or UNTIL")
+	}
+
+	if rule.Count != 0 && rule.Until != nil {
+		return nil, errors.New("recurrence rule must not contain both COUNT and UNTIL")
+	}
+
+	if rule.Frequency == RecurrenceYearly && (len(rule.ByDay) > 0 || len(rule.ByMonthDay) > 0) {
+		return nil, errors.New("yearly rules do not support BYDAY or BYMONTHDAY")
+	}
+
+	for _, weekday := range rule.ByDay {
+		if weekday.Ordinal != 0 && rule.Frequency != RecurrenceMonthly {
+			return nil, errors.New("BYDAY with ordinal is only supported for monthly rules")
+		}
+	}
+
+	return rule, nil
+}
+
+// Occurrences returns the dates of the rule starting at start without the exception dates. Only the date part of the times is used.
+// Like DTSTART in RFC 5545 the start is always the first occurrence and counts towards COUNT, even if it does not match the rule.
+// An error is returned if the rule produces more than maxOccurrences dates
+func (rr *RecurrenceRule) Occurrences(start time.Time, exceptionDates []time.Time, maxOccurrences int) ([]time.Time, error) {
+	start = truncateToDate(start)
+	excluded := make(map[time.Time]bool, len(exceptionDates))
+	for _, exceptionDate := range exceptionDates {
+		excluded[truncateToDate(exceptionDate)] = true
+	}
+
+	occurrences := make([]time.Time, 0)
+	generated := 0
+
+	// excluded dates count towards COUNT, they are removed from the set that the rule produces
+	generate := func(date time.Time) (bool, error) {
+		generated++
+		if !excluded[date] {
+			if len(occurrences) == maxOccurrences {
+				return false, fmt.Errorf("recurrence rule must not produce more than %d occurrences", maxOccurrences)
+			}
+			occurrences = append(occurrences, date)
+		}
+
+		return rr.Count != 0 && generated == rr.Count, nil
+	}
+
+	done, err := generate(start)
+	if err != nil {
+		return nil, err
+	}
+	if done {
+		return occurrences, nil
+	}
+
+	for period := 0; period < maxRecurrencePeriods; period++ {
+		candidates, periodStart := rr.periodCandidates(start, period)
+
+		if rr.Until != nil && periodStart.After(*rr.Until) {
+			break
+		}
+
+		for _, candidate := range candidates {
+			// the start has been generated already
+			if !candidate.After(start) {
+				continue
+			}
+
+			if rr.Until != nil && candidate.After(*rr.Until) {
+				return occurrences, nil
+			}
+
+			done, err := generate(candidate)
+			if err != nil {
+				return nil, err
+			}
+			if done {
+				return occurrences, nil
+			}
+		}
+	}
+
+	return occurrences, nil
+}
+
+// periodCandidates returns the sorted dates of the nth period of the rule and the first day of the period
+func (rr *RecurrenceRule) periodCandidates(start time.Time, period int) ([]time.Time, time.Time) {
+	candidates := make([]time.Time, 0)
+
+	switch rr.Frequency {
+	case RecurrenceDaily:
+		day := start.AddDate(0, 0, period*rr.Interval)
+		if rr.matchesDay(day) {
+			candidates = append(candidates, day)
+		}
+		return candidates, day
+
+	case RecurrenceWeekly:
+		// weeks start on monday
+		weekStart := start.AddDate(0, 0, -((int(start.Weekday())+6)%7)+period*rr.Interval*7)
+		for i := 0; i < 7; i++ {
+			day := weekStart.AddDate(0, 0, i)
+			if len(rr.ByDay) == 0 && day.Weekday() != start.Weekday() {
+				continue
+			}
+			if rr.matchesDay(day) {
+				candidates = append(candidates, day)
+			}
+		}
+		return candidates, weekStart
+
+	case RecurrenceMonthly:
+		monthStart := time.Date(start.Year(), start.Month()+time.Month(period*rr.Interval), 1, 0, 0, 0, 0, time.UTC)
+		daysInMonth := monthStart.AddDate(0, 1, -1).Day()
+		for dayOfMonth := 1; dayOfMonth <= daysInMonth; dayOfMonth++ {
+			day := monthStart.AddDate(0, 0, dayOfMonth-1)
+			if len(rr.ByDay) == 0 && len(rr.ByMonthDay) == 0 && dayOfMonth != start.Day() {
+				continue
+			}
+			if rr.matchesDay(day) {
+				candidates = append(candidates, day)
+			}
+		}
+		return candidates, monthStart
+
+	default:
+		yearStart := time.Date(start.Year()+period*rr.Interval, time.January, 1, 0, 0, 0, 0, time.UTC)
+		day := time.Date(yearStart.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		// the 29th of february only exists in leap years
+		if day.Month() == start.Month() {
+			candidates = append(candidates, day)
+		}
+		return candidates, yearStart
+	}
+}
+
+// matchesDay checks the BYDAY and BYMONTHDAY parts, both have to match if both are set
+func (rr *RecurrenceRule) matchesDay(day time.Time) bool {
+	if len(rr.ByMonthDay) > 0 {
+		daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+		matches := false
+		for _, monthDay := range rr.ByMonthDay {
+			if monthDay == day.Day() || (monthDay < 0 && daysInMonth+monthDay+1 == day.Day()) {
+				matches = true
+				break
+			}
+		}
+		if !matches {
+			return false
+		}
+	}
+
+	if len(rr.ByDay) == 0 {
+		return true
+	}
+
+	daysInMonth := time.Date(day.Year(), day.Month()+1, 0, 0, 0, 0, 0, time.UTC).Day()
+	for _, weekday := range rr.ByDay {
+		if weekday.Weekday != day.Weekday() {
+			continue
+		}
+		if weekday.Ordinal == 0 ||
+			weekday.Ordinal == (day.Day()-1)/7+1 ||
+			weekday.Ordinal == -((daysInMonth-day.Day())/7+1) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseRecurrenceWeekdays(value string) ([]RecurrenceWeekday, error) {
+	weekdays := make([]RecurrenceWeekday, 0)
+
+	for _, entry := range strings.Split(strings.ToUpper(value), ",") {
+		if len(entry) < 2 {
+			return nil, fmt.Errorf("invalid weekday %q", entry)
+		}
+
+		weekday, ok := recurrenceWeekdays[entry[len(entry)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("invalid weekday %q", entry)
+		}
+
+		ordinal := 0
+		if len(entry) > 2 {
+			var err error
+			ordinal, err = strconv.Atoi(entry[:len(entry)-2])
+			if err != nil || ordinal == 0 || ordinal < -5 || ordinal > 5 {
+				return nil, fmt.Errorf("invalid weekday %q", entry)
+			}
+		}
+
+		weekdays = append(weekdays, RecurrenceWeekday{
+			Weekday: weekday,
+			Ordinal: ordinal,
+		})
+	}
+
+	return weekdays, nil
+}
+
+func parseRecurrenceMonthDays(value string) ([]int, error) {
+	monthDays := make([]int, 0)
+
+	for _, entry := range strings.Split(value, ",") {
+		monthDay, err := strconv.Atoi(entry)
+		if err != nil || monthDay == 0 || monthDay < -31 || monthDay > 31 {
+			return nil, fmt.Errorf("invalid day of month %q", entry)
+		}
+		monthDays = append(monthDays, monthDay)
+	}
+
+	return monthDays, nil
+}
+
+// parseRecurrenceDate accepts the date and date time formats of RFC 5545, e.g. 20240131 or 20240131T180000Z
+func parseRecurrenceDate(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102", "20060102T150405Z", "20060102T150405"} {
+		date, err := time.Parse(layout, value)
+		if err == nil {
+			return truncateToDate(date), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// truncateToDate drops the time of day and the location, occurrences are compared as dates
+func truncateToDate(value time.Time) time.Time {
+	return time.Date(value.Year(), value.Month(), value.Day(), 0, 0, 0, 0, time.UTC)
+}
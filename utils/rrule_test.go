@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestRecurrenceRuleOccurrences(t *testing.T) {
+	testCases := []struct {
+		name           string
+		rule           string
+		start          time.Time
+		exceptionDates []time.Time
+		expected       []time.Time
+	}{
+		{
+			name:     "weekly on start weekday",
+			rule:     "FREQ=WEEKLY;COUNT=3",
+			start:    date(2024, time.May, 7),
+			expected: []time.Time{date(2024, time.May, 7), date(2024, time.May, 14), date(2024, time.May, 21)},
+		},
+		{
+			name:     "every second week on tuesday and thursday",
+			rule:     "RRULE:FREQ=WEEKLY;INTERVAL=2;BYDAY=TU,TH;COUNT=4",
+			start:    date(2024, time.May, 7),
+			expected: []time.Time{date(2024, time.May, 7), date(2024, time.May, 9), date(2024, time.May, 21), date(2024, time.May, 23)},
+		},
+		{
+			name:           "exception dates count towards count",
+			rule:           "FREQ=WEEKLY;COUNT=3",
+			start:          date(2024, time.May, 7),
+			exceptionDates: []time.Time{date(2024, time.May, 14)},
+			expected:       []time.Time{date(2024, time.May, 7), date(2024, time.May, 21)},
+		},
+		{
+			name:     "daily until",
+			rule:     "FREQ=DAILY;INTERVAL=2;UNTIL=20240105",
+			start:    date(2024, time.January, 1),
+			expected: []time.Time{date(2024, time.January, 1), date(2024, time.January, 3), date(2024, time.January, 5)},
+		},
+		{
+			name:     "monthly on the last friday",
+			rule:     "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			start:    date(2024, time.January, 26),
+			expected: []time.Time{date(2024, time.January, 26), date(2024, time.February, 23), date(2024, time.March, 29)},
+		},
+		{
+			name:     "start not matching the rule is the first occurrence",
+			rule:     "FREQ=MONTHLY;BYDAY=1MO;COUNT=3",
+			start:    date(2024, time.January, 10),
+			expected: []time.Time{date(2024, time.January, 10), date(2024, time.February, 5), date(2024, time.March, 4)},
+		},
+		{
+			name:     "start not matching the rule counts towards count",
+			rule:     "FREQ=WEEKLY;BYDAY=TU,TH;COUNT=3",
+			start:    date(2024, time.May, 6),
+			expected: []time.Time{date(2024, time.May, 6), date(2024, time.May, 7), date(2024, time.May, 9)},
+		},
+		{
+			name:     "monthly skips months without the day",
+			rule:     "FREQ=MONTHLY;COUNT=3",
+			start:    date(2024, time.January, 31),
+			expected: []time.Time{date(2024, time.January, 31), date(2024, time.March, 31), date(2024, time.May, 31)},
+		},
+		{
+			name:     "yearly on leap day",
+			rule:     "FREQ=YEARLY;COUNT=2",
+			start:    date(2024, time.February, 29),
+			expected: []time.Time{date(2024, time.February, 29), date(2028, time.February, 29)},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			rule, err := ParseRecurrenceRule(testCase.rule)
+			require.NoError(t, err)
+
+			occurrences, err := rule.Occurrences(testCase.start, testCase.exceptionDates, 100)
+			require.NoError(t, err)
+			assert.Equal(t, testCase.expected, occurrences)
+		})
+	}
+}
+
+func TestParseRecurrenceRuleFail(t *testing.T) {
+	rules := []string{
+		"",
+		"FREQ=HOURLY;COUNT=3",
+		"FREQ=WEEKLY",
+		"FREQ=WEEKLY;COUNT=3;UNTIL=20240101",
+		"FREQ=WEEKLY;BYDAY=1MO;COUNT=3",
+		"FREQ=WEEKLY;BYSETPOS=1;COUNT=3",
+		"FREQ=MONTHLY;BYDAY=XX;COUNT=3",
+		"FREQ=MONTHLY;BYMONTHDAY=32;COUNT=3",
+	}
+
+	for _, rule := range rules {
+		_, err := ParseRecurrenceRule(rule)
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestRecurrenceRuleOccurrencesFailTooMany(t *testing.T) {
+	rule, err := ParseRecurrenceRule("FREQ=DAILY;COUNT=400")
+	require.NoError(t, err)
+
+	_, err = rule.Occurrences(date(2024, time.January, 1), nil, 366)
+	assert.Error(t, err)
+}
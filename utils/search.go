@@ -0,0 +1,76 @@
+package utils
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// maxSearchWords limits the size of the text search query that is built from a search term
+const maxSearchWords = 10
+
+// Markers that ts_headline puts around the matches. They are characters of the private use area that do not occur in regular text,
+// so they can be replaced with html after the text has been escaped
+const (
+	SearchHighlightStart = "\ue000"
+	SearchHighlightStop  = "\ue001"
+)
+
+// searchWords splits a search term into lower case words at every character that is no letter or digit, so the words cannot contain
+// operators of to_tsquery. At most maxSearchWords words are returned
+func searchWords(search string) []string {
+	words := strings.FieldsFunc(strings.ToLower(search), func(character rune) bool {
+		return !unicode.IsLetter(character) && !unicode.IsDigit(character)
+	})
+
+	if len(words) > maxSearchWords {
+		words = words[:maxSearchWords]
+	}
+
+	return words
+}
+
+// BuildSearchQuery converts a search term into the syntax of to_tsquery, e.g. "Go meet" becomes "go & meet:*". All words have to match
+// and the last one matches as prefix, so results are found while the user is still typing. An empty string is returned if the term
+// contains no words
+func BuildSearchQuery(search string) string {
+	words := searchWords(search)
+
+	if len(words) == 0 {
+		return ""
+	}
+
+	words[len(words)-1] += ":*"
+
+	return strings.Join(words, " & ")
+}
+
+// BuildPrefixSearchQuery splits a search term into the query of all words but the last one and the prefix query of the last word,
+// e.g. "Go meet" becomes "go" and "meet:*". The prefix is meant to be matched against words that are not stemmed, because the stem of a
+// word that is still typed is often no prefix of the stem of the whole word, e.g. festiva and festiv. Empty strings are returned if the
+// term contains no words
+func BuildPrefixSearchQuery(search string) (string, string) {
+	words := searchWords(search)
+
+	if len(words) == 0 {
+		return "", ""
+	}
+
+	return strings.Join(words[:len(words)-1], " & "), words[len(words)-1] + ":*"
+}
+
+// HighlightHtml escapes a text returned by ts_headline and wraps the matches in mark elements
+func HighlightHtml(text string) string {
+	return strings.NewReplacer(
+		SearchHighlightStart, "<mark>",
+		SearchHighlightStop, "</mark>",
+	).Replace(html.EscapeString(text))
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, so user input only matches literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// EscapeLike escapes the text for use inside a LIKE pattern with the default escape character \
+func EscapeLike(text string) string {
+	return likeEscaper.Replace(text)
+}
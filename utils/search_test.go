@@ -0,0 +1,59 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSearchQuery(t *testing.T) {
+	testCases := []struct {
+		search   string
+		expected string
+	}{
+		{search: "", expected: ""},
+		{search: "  !? ", expected: ""},
+		{search: "Meet", expected: "meet:*"},
+		{search: "Go meetup Köln", expected: "go & meetup & köln:*"},
+		{search: "rock'n'roll", expected: "rock & n & roll:*"},
+		{search: "a & !b | c:* <-> (d)", expected: "a & b & c & d:*"},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, BuildSearchQuery(testCase.search), testCase.search)
+	}
+
+	assert.Equal(t, maxSearchWords, strings.Count(BuildSearchQuery(strings.Repeat("word ", 20)), "word"))
+}
+
+func TestBuildPrefixSearchQuery(t *testing.T) {
+	testCases := []struct {
+		search         string
+		expectedWords  string
+		expectedPrefix string
+	}{
+		{search: "", expectedWords: "", expectedPrefix: ""},
+		{search: "  !? ", expectedWords: "", expectedPrefix: ""},
+		{search: "Festiva", expectedWords: "", expectedPrefix: "festiva:*"},
+		{search: "Go meetup Köln", expectedWords: "go & meetup", expectedPrefix: "köln:*"},
+		{search: "a & !b | c:* <-> (d)", expectedWords: "a & b & c", expectedPrefix: "d:*"},
+	}
+
+	for _, testCase := range testCases {
+		words, prefix := BuildPrefixSearchQuery(testCase.search)
+		assert.Equal(t, testCase.expectedWords, words, testCase.search)
+		assert.Equal(t, testCase.expectedPrefix, prefix, testCase.search)
+	}
+}
+
+func TestHighlightHtml(t *testing.T) {
+	text := "<b>Go</b> " + SearchHighlightStart + "meetup" + SearchHighlightStop + " & more"
+
+	assert.Equal(t, "&lt;b&gt;Go&lt;/b&gt; <mark>meetup</mark> &amp; more", HighlightHtml(text))
+}
+
+func TestEscapeLike(t *testing.T) {
+	assert.Equal(t, "Halle", EscapeLike("Halle"))
+	assert.Equal(t, `100\% \_ C:\\Halle`, EscapeLike(`100% _ C:\Halle`))
+}
@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters as defined by RFC 6238, these are the defaults every authenticator app understands
+const TotpPeriod = 30 * time.Second
+const TotpDigits = 6
+const TotpIssuer = "eventom"
+
+// totpSkew is the number of time steps before and after the current one that are accepted to compensate clock drift
+const totpSkew = 1
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTotpSecret returns a random 160 bit secret encoded as base32 like authenticator apps expect it
+func GenerateTotpSecret() (string, error) {
+	buffer := make([]byte, 20)
+
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+
+	return totpEncoding.EncodeToString(buffer), nil
+}
+
+// BuildTotpUri builds the otpauth uri that authenticator apps import, usually shown as qr code
+func BuildTotpUri(accountName string, secret string) string {
+	query := url.Values{
+		"secret":    {secret},
+		"issuer":    {TotpIssuer},
+		"algorithm": {"SHA1"},
+		"digits":    {fmt.Sprint(TotpDigits)},
+		"period":    {fmt.Sprint(int(TotpPeriod.Seconds()))},
+	}
+
+	label := url.PathEscape(TotpIssuer + ":" + accountName)
+
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, query.Encode())
+}
+
+// ValidateTotpCode checks the code against the time steps around the given time and returns the matching time step.
+// The time step lets callers reject codes that have been used already
+func ValidateTotpCode(secret string, code string, now time.Time) (int64, bool) {
+	if len(code) != TotpDigits {
+		return 0, false
+	}
+
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+
+	if err != nil {
+		return 0, false
+	}
+
+	currentStep := now.Unix() / int64(TotpPeriod.Seconds())
+
+	for step := currentStep - totpSkew; step <= currentStep+totpSkew; step++ {
+		expectedCode := generateTotpCode(key, step)
+		if subtle.ConstantTimeCompare([]byte(expectedCode), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// GenerateTotpCode returns the code of the secret for the given time, e.g. for tests
+func GenerateTotpCode(secret string, now time.Time) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+
+	if err != nil {
+		return "", err
+	}
+
+	return generateTotpCode(key, now.Unix()/int64(TotpPeriod.Seconds())), nil
+}
+
+// generateTotpCode implements the HOTP algorithm of RFC 4226 with the time step as counter
+func generateTotpCode(key []byte, step int64) string {
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < TotpDigits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", TotpDigits, value%modulo)
+}
+
+// GenerateRecoveryCode returns a random one-time code in the form xxxxx-xxxxx
+func GenerateRecoveryCode() (string, error) {
+	buffer := make([]byte, 7)
+
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(totpEncoding.EncodeToString(buffer))[:10]
+
+	return code[:5] + "-" + code[5:], nil
+}
+
+// NormalizeRecoveryCode removes formatting, so codes can be entered with or without dash and in any case
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ReplaceAll(code, "-", "")
+	code = strings.ReplaceAll(code, " ", "")
+	return strings.ToLower(code)
+}
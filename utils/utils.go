@@ -1,23 +1,43 @@
 package utils
 
 import (
-	"crypto/rsa"
-	"crypto/x509"
-	"encoding/pem"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"eventom-backend/dtos"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type ContextUserId string
 
 const ContextUserIdKey ContextUserId = "userId"
+const ContextClaimsKey ContextUserId = "claims"
 
-var privateKey *rsa.PrivateKey
-var ProtectedRoutes map[string]bool
+// access tokens are kept short-lived, sessions are kept alive by rotating the refresh token
+const AccessTokenTTL = 15 * time.Minute
+const RefreshTokenTTL = 30 * 24 * time.Hour
+
+// a rotated refresh token that is presented again within the grace period is treated as a parallel refresh of the same client
+// instead of a stolen token, so requests that refresh at the same time do not end the session
+const RefreshTokenReuseGracePeriod = 30 * time.Second
+
+// mfa challenge tokens only prove the first login step and have to be exchanged for an access token with a second factor
+const MfaChallengeTTL = 5 * time.Minute
+const mfaChallengePurpose = "mfa_challenge"
 
 func HashPassword(password string) (string, error) {
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), 10)
@@ -29,46 +49,73 @@ func HashPassword(password string) (string, error) {
 	return string(hashedPassword), nil
 }
 
-func ReadPrivateKeyFromFile(filename string) error {
-	file, err := os.Open(filename)
+// GenerateJwt issues an access token for the session with the given id, the session id is sent as sid claim
+func GenerateJwt(userId string, sessionId string, roles []string, permissions []string) (string, error) {
+	return signJwt(jwt.MapClaims{
+		"user_id":     userId,
+		"sid":         sessionId,
+		"roles":       roles,
+		"permissions": permissions,
+		"jti":         uuid.NewString(),
+		"iat":         time.Now().Unix(),
+		"exp":         time.Now().Add(AccessTokenTTL).Unix(),
+	})
+}
 
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+// GenerateMfaChallengeJwt issues the token of the first login step for users with two-factor authentication enabled
+func GenerateMfaChallengeJwt(userId string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(MfaChallengeTTL)
+
+	signedToken, err := signJwt(jwt.MapClaims{
+		"user_id": userId,
+		"purpose": mfaChallengePurpose,
+		"jti":     uuid.NewString(),
+		"iat":     time.Now().Unix(),
+		"exp":     expiresAt.Unix(),
+	})
+
+	return signedToken, expiresAt, err
+}
 
-	fileInfo, _ := file.Stat()
-	fileSize := fileInfo.Size()
-	buffer := make([]byte, fileSize)
+// VerifyMfaChallengeJwt verifies a mfa challenge token and returns the id of the user that passed the first login step
+func VerifyMfaChallengeJwt(challengeToken string) (string, error) {
+	verifiedToken, err := VerifyJwt(challengeToken)
 
-	_, err = file.Read(buffer)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	data, _ := pem.Decode(buffer)
-	key, err := x509.ParsePKCS8PrivateKey(data.Bytes)
+	claims, ok := verifiedToken.Claims.(jwt.MapClaims)
 
-	if err != nil {
-		return err
+	if !ok {
+		return "", errors.New("could not convert jwt claims")
+	}
+
+	if purpose, _ := claims["purpose"].(string); purpose != mfaChallengePurpose {
+		return "", errors.New("token is not a mfa challenge token")
 	}
 
-	if key, ok := key.(*rsa.PrivateKey); ok {
-		privateKey = key
-		return nil
+	userId, ok := claims["user_id"].(string)
+
+	if !ok {
+		return "", errors.New("could not convert user id from jwt claims to string")
 	}
 
-	return errors.New("error while reading private key")
+	return userId, nil
 }
 
-func GenerateJwt(userId string) (string, error) {
-	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
-		"user_id": userId,
-		"iat":     time.Now().Unix(),
-		"exp":     time.Now().Add(time.Hour).Unix(),
-	})
+// signJwt signs the claims with the current signing key of the key ring and names the key in the kid header
+func signJwt(claims jwt.MapClaims) (string, error) {
+	signingKey, err := currentSigningKey()
+
+	if err != nil {
+		return "", err
+	}
 
-	return token.SignedString(privateKey)
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = signingKey.id
+
+	return token.SignedString(signingKey.privateKey)
 }
 
 func VerifyJwt(jwtToken string) (*jwt.Token, error) {
@@ -77,7 +124,20 @@ func VerifyJwt(jwtToken string) (*jwt.Token, error) {
 		if !ok {
 			return nil, errors.New("unexpected signing method")
 		}
-		return privateKey.Public(), nil
+
+		keyId, _ := token.Header["kid"].(string)
+		publicKeys := verificationKeys(keyId)
+
+		if len(publicKeys) == 0 {
+			return nil, errors.New("unknown signing key")
+		}
+
+		keySet := jwt.VerificationKeySet{}
+		for _, publicKey := range publicKeys {
+			keySet.Keys = append(keySet.Keys, publicKey)
+		}
+
+		return keySet, nil
 	})
 
 	if err != nil {
@@ -91,20 +151,147 @@ func VerifyJwt(jwtToken string) (*jwt.Token, error) {
 	return parsedToken, nil
 }
 
-func GetPrivateKey() *rsa.PrivateKey {
-	return privateKey
+// ParseAccessTokenClaims extracts the claims of a verified access token
+func ParseAccessTokenClaims(verifiedToken *jwt.Token) (*dtos.AccessTokenClaims, error) {
+	claims, ok := verifiedToken.Claims.(jwt.MapClaims)
+
+	if !ok {
+		return nil, errors.New("could not convert jwt claims")
+	}
+
+	// tokens with a purpose, e.g. mfa challenges, must never be accepted as access token
+	if _, ok := claims["purpose"]; ok {
+		return nil, errors.New("token is not an access token")
+	}
+
+	userId, ok := claims["user_id"].(string)
+
+	if !ok {
+		return nil, errors.New("could not convert user id from jwt claims to string")
+	}
+
+	tokenId, ok := claims["jti"].(string)
+
+	if !ok {
+		return nil, errors.New("could not convert token id from jwt claims to string")
+	}
+
+	// tokens issued before sessions have been tracked have no session id
+	sessionId, _ := claims["sid"].(string)
+
+	issuedAt, err := claims.GetIssuedAt()
+
+	if err != nil || issuedAt == nil {
+		return nil, errors.New("could not read issued at from jwt claims")
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+
+	if err != nil || expiresAt == nil {
+		return nil, errors.New("could not read expiration time from jwt claims")
+	}
+
+	roles, err := parseStringListClaim(claims, "roles")
+
+	if err != nil {
+		return nil, err
+	}
+
+	permissions, err := parseStringListClaim(claims, "permissions")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &dtos.AccessTokenClaims{
+		UserId:      userId,
+		TokenId:     tokenId,
+		SessionId:   sessionId,
+		Roles:       roles,
+		Permissions: permissions,
+		IssuedAt:    issuedAt.Time,
+		ExpiresAt:   expiresAt.Time,
+	}, nil
+}
+
+func parseStringListClaim(claims jwt.MapClaims, claimName string) ([]string, error) {
+	values, ok := claims[claimName].([]interface{})
+
+	if !ok {
+		return nil, fmt.Errorf("could not convert %s from jwt claims to a list", claimName)
+	}
+
+	stringValues := make([]string, 0, len(values))
+	for _, value := range values {
+		stringValue, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("could not convert %s from jwt claims to strings", claimName)
+		}
+		stringValues = append(stringValues, stringValue)
+	}
+
+	return stringValues, nil
 }
 
-func SetProtectedRoutes() {
-	ProtectedRoutes = make(map[string]bool, 10)
-	ProtectedRoutes["POST events"] = true
-	ProtectedRoutes["GET events"] = false
-	ProtectedRoutes["PUT events"] = true
-	ProtectedRoutes["DELETE events"] = true
-	ProtectedRoutes["POST signup"] = false
-	ProtectedRoutes["POST login"] = false
-	ProtectedRoutes["POST logout"] = true
-	ProtectedRoutes["POST registrations"] = true
-	ProtectedRoutes["GET registrations"] = false
-	ProtectedRoutes["DELETE registrations"] = true
+// HasPermission checks whether the authenticated user of the request context has been granted the given permission
+func HasPermission(ctx context.Context, permission string) bool {
+	claims, ok := ctx.Value(ContextClaimsKey).(*dtos.AccessTokenClaims)
+
+	if !ok {
+		return false
+	}
+
+	return slices.Contains(claims.Permissions, permission)
+}
+
+// GenerateOpaqueToken returns a random url safe token that carries no information on its own
+func GenerateOpaqueToken() (string, error) {
+	buffer := make([]byte, 32)
+
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(buffer), nil
+}
+
+// HashToken hashes opaque tokens before they are persisted, so a leaked database does not leak usable tokens
+func HashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+// BuildAppUrl builds an absolute url to the given path of the app, e.g. for links in mails
+func BuildAppUrl(path string, query url.Values) string {
+	baseUrl := os.Getenv("APP_BASE_URL")
+	if baseUrl == "" {
+		baseUrl = "http://localhost:8080"
+	}
+
+	appUrl := strings.TrimSuffix(baseUrl, "/") + path
+	if len(query) > 0 {
+		appUrl += "?" + query.Encode()
+	}
+
+	return appUrl
+}
+
+// GetClientInfo returns the ip address and the user agent of the client
+func GetClientInfo(r *http.Request) dtos.ClientInfo {
+	return dtos.ClientInfo{
+		IpAddress: GetClientIp(r),
+		UserAgent: r.UserAgent(),
+	}
+}
+
+// GetClientIp returns the ip address of the client without the port
+func GetClientIp(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+
+	if err != nil {
+		return r.RemoteAddr
+	}
+
+	return ip
 }